@@ -0,0 +1,124 @@
+package apiresources
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// DefaultFile the default path of the cached API resources file relative to the cluster repository
+var DefaultFile = filepath.Join(".jx", "gitops", v1alpha1.APIResourcesFileName)
+
+// Load loads the cached API resources file returning an empty value if it does not exist
+func Load(path string) (*v1alpha1.APIResources, error) {
+	resources := &v1alpha1.APIResources{}
+	if path == "" {
+		return resources, nil
+	}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return resources, nil
+	}
+	err = yamls.LoadFile(path, resources)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load API resources file %s", path)
+	}
+	return resources, nil
+}
+
+// ToAPIResources converts the discovered API resources into a sorted cache
+func ToAPIResources(lists []*metav1.APIResourceList) *v1alpha1.APIResources {
+	resources := &v1alpha1.APIResources{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.APIVersion,
+			Kind:       v1alpha1.KindAPIResources,
+		},
+	}
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for i := range list.APIResources {
+			r := &list.APIResources[i]
+			resources.Spec.Resources = append(resources.Spec.Resources, v1alpha1.APIResource{
+				APIVersion: list.GroupVersion,
+				Kind:       r.Kind,
+				Namespaced: r.Namespaced,
+			})
+		}
+	}
+	sort.Slice(resources.Spec.Resources, func(i, j int) bool {
+		r1 := resources.Spec.Resources[i]
+		r2 := resources.Spec.Resources[j]
+		if r1.Kind != r2.Kind {
+			return r1.Kind < r2.Kind
+		}
+		return r1.APIVersion < r2.APIVersion
+	})
+	return resources
+}
+
+// NamespacedKinds returns whether each kind in the cached API resources file is namespaced
+func NamespacedKinds(path string) (map[string]bool, error) {
+	answer := map[string]bool{}
+	cache, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range cache.Spec.Resources {
+		answer[r.Kind] = r.Namespaced
+	}
+	return answer, nil
+}
+
+// AddCustomResourceDefinitions adds whether the kinds defined by any CRDs in the given dir are namespaced
+func AddCustomResourceDefinitions(namespacedKinds map[string]bool, dir string) error {
+	exists, err := files.DirExists(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if dir exists %s", dir)
+	}
+	if !exists {
+		return nil
+	}
+	modifyFn := func(node *yaml.RNode, path string) (bool, error) {
+		kind, namespaced := CRDScope(node, path)
+		if kind != "" {
+			namespacedKinds[kind] = namespaced
+		}
+		return false, nil
+	}
+	filter := kyamls.Filter{
+		Kinds: []string{"CustomResourceDefinition"},
+	}
+	err = kyamls.ModifyFiles(dir, modifyFn, filter)
+	if err != nil {
+		return errors.Wrapf(err, "failed to walk CRDs in dir %s", dir)
+	}
+	return nil
+}
+
+// IsNamespaced returns true if the kind is namespaced using the given lookup falling back to the well known cluster kinds
+func IsNamespaced(namespacedKinds map[string]bool, kind string) bool {
+	namespaced, ok := namespacedKinds[kind]
+	if ok {
+		return namespaced
+	}
+	return !kyamls.IsClusterKind(kind)
+}
+
+// CRDScope returns the kind defined by the CRD and whether it is namespaced
+func CRDScope(node *yaml.RNode, path string) (string, bool) {
+	namespaced := kyamls.GetStringField(node, path, "spec", "scope") == "Namespaced"
+	kind := kyamls.GetStringField(node, path, "spec", "names", "kind")
+	return kind, namespaced
+}
@@ -0,0 +1,46 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIResourcesFileName default name of the cached API resources file
+	APIResourcesFileName = "api-resources.yaml"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// APIResources caches the kinds of resource supported by a cluster and whether they are namespaced
+// so that resources can be placed in the right directory without access to the cluster
+//
+// +k8s:openapi-gen=true
+type APIResources struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec holds the API resources
+	// +optional
+	Spec APIResourcesSpec `json:"spec"`
+}
+
+// APIResourcesSpec defines the API resources
+type APIResourcesSpec struct {
+	// Resources the kinds of resource sorted by kind and API version
+	Resources []APIResource `json:"resources,omitempty"`
+}
+
+// APIResource a kind of resource supported by the cluster
+type APIResource struct {
+	// APIVersion the group and version of the resource
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind the kind of the resource
+	Kind string `json:"kind" validate:"nonzero"`
+
+	// Namespaced whether the resource is namespaced or cluster scoped
+	Namespaced bool `json:"namespaced"`
+}
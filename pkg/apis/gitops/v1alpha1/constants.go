@@ -10,6 +10,27 @@ const (
 	// KindSourceConfig the kind
 	KindSourceConfig = "SourceConfig"
 
+	// KindMigrations the kind
+	KindMigrations = "Migrations"
+
+	// KindLintPolicy the kind
+	KindLintPolicy = "LintPolicy"
+
+	// KindGCPolicy the kind
+	KindGCPolicy = "GCPolicy"
+
+	// KindAPIResources the kind
+	KindAPIResources = "APIResources"
+
+	// KindImagePaths the kind
+	KindImagePaths = "ImagePaths"
+
+	// KindVersionStreamHistory the kind
+	KindVersionStreamHistory = "VersionStreamHistory"
+
+	// KindImageDigests the kind
+	KindImageDigests = "ImageDigests"
+
 	// DomainPlaceholder what is the default domain value used as a place holder until
 	// the real domain name can be discovered which is usually after the first apply
 	// of kubernetes resources as we need to discover the LoadBalancer Service in the nginx namespace
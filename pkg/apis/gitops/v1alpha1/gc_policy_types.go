@@ -0,0 +1,118 @@
+package v1alpha1
+
+import (
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/matcher"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// GCPolicyFileName default name of the garbage collection policy file
+	GCPolicyFileName = "gc-policy.yaml"
+
+	// GCKindPipelineActivity the PipelineActivity resources
+	GCKindPipelineActivity = "PipelineActivity"
+	// GCKindLighthouseJob the LighthouseJob resources
+	GCKindLighthouseJob = "LighthouseJob"
+	// GCKindPipelineRun the tekton PipelineRun resources
+	GCKindPipelineRun = "PipelineRun"
+	// GCKindJob the batch Job resources
+	GCKindJob = "Job"
+	// GCKindPod the Pod resources
+	GCKindPod = "Pod"
+)
+
+// GCKinds the kinds of resource which are garbage collected
+var GCKinds = []string{GCKindPipelineActivity, GCKindLighthouseJob, GCKindPipelineRun, GCKindJob, GCKindPod}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GCPolicy represents the rules used to garbage collect completed pipeline resources
+//
+// +k8s:openapi-gen=true
+type GCPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec holds the garbage collection policy
+	// +optional
+	Spec GCPolicySpec `json:"spec"`
+}
+
+// GCPolicyList contains a list of GCPolicy
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type GCPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GCPolicy `json:"items"`
+}
+
+// GCPolicySpec defines the rules of the garbage collection policy
+type GCPolicySpec struct {
+	// Rules the ordered rules. Each resource is garbage collected using the first rule which matches it.
+	// Resources which match no rule are kept
+	Rules []GCRule `json:"rules,omitempty"`
+}
+
+// GCRule a garbage collection rule
+type GCRule struct {
+	// Name the name of the rule used in the summary
+	Name string `json:"name" validate:"nonzero"`
+
+	// Kinds if specified only resources of these kinds match the rule
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Owners the regular expressions to match the repository owner
+	Owners GCPattern `json:"owners,omitempty"`
+
+	// Repositories the regular expressions to match the repository name
+	Repositories GCPattern `json:"repositories,omitempty"`
+
+	// Branches the regular expressions to match the branch name. Pull requests use branch names like 'PR-123'
+	Branches GCPattern `json:"branches,omitempty"`
+
+	// Namespaces if specified only resources in these namespaces match the rule
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Selector if specified only resources with matching labels match the rule
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// MaxAge the maximum age of completed resources. Older resources are deleted
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+
+	// HistoryLimit the maximum number of completed resources to keep for each repository, branch and context.
+	// Older resources are deleted
+	HistoryLimit *int `json:"historyLimit,omitempty"`
+
+	// MinKeep the minimum number of the most recent completed resources to keep for each repository, branch and context
+	// even if they are older than the MaxAge
+	MinKeep *int `json:"minKeep,omitempty"`
+}
+
+// GCPattern the regular expressions to include or exclude
+type GCPattern struct {
+	// Include if specified the text must match one of these regular expressions
+	Include []string `json:"include,omitempty"`
+	// Exclude the text must not match any of these regular expressions
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Matcher returns a matcher for the pattern
+func (p *GCPattern) Matcher() (*matcher.Matcher, error) {
+	m := &matcher.Matcher{}
+	var err error
+	m.Includes, err = m.ToRegexs(p.Include)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create include regex")
+	}
+	m.Excludes, err = m.ToRegexs(p.Exclude)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create exclude regex")
+	}
+	return m, nil
+}
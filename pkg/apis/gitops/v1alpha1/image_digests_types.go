@@ -0,0 +1,34 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ImageDigestsFileName default name of the image digest cache file in the cluster repository
+	ImageDigestsFileName = "image-digests.yaml"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageDigests caches the digests of image tags so that pinning images to digests does not
+// need to access the registries every time
+//
+// +k8s:openapi-gen=true
+type ImageDigests struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec holds the digests
+	// +optional
+	Spec ImageDigestsSpec `json:"spec"`
+}
+
+// ImageDigestsSpec defines the cached image digests
+type ImageDigestsSpec struct {
+	// Digests the digests indexed by the image of the form 'host/repository:tag'
+	Digests map[string]string `json:"digests,omitempty"`
+}
@@ -0,0 +1,48 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ImagePathsFileName default name of the image paths file in the cluster repository and version stream
+	ImagePathsFileName = "image-paths.yaml"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImagePaths configures where the container images are found in each kind of kubernetes resource
+// so that they can be updated from the version stream
+//
+// +k8s:openapi-gen=true
+type ImagePaths struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec holds the image paths
+	// +optional
+	Spec ImagePathsSpec `json:"spec"`
+}
+
+// ImagePathsSpec defines the image paths for each kind of resource
+type ImagePathsSpec struct {
+	// Kinds the image paths of each kind. An entry with the same kind and API version as a built in
+	// entry replaces its paths
+	Kinds []ImagePathsKind `json:"kinds,omitempty"`
+}
+
+// ImagePathsKind the paths to the images of a kind of resource
+type ImagePathsKind struct {
+	// Kind the kind of the resource
+	Kind string `json:"kind" validate:"nonzero"`
+
+	// APIVersion if specified only resources of this API version match. Otherwise any API version matches
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Paths the dot separated paths to the images such as 'spec.template.spec.containers.image'.
+	// Any arrays in the path are iterated over
+	Paths []string `json:"paths,omitempty"`
+}
@@ -0,0 +1,97 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LintPolicyFileName default name of the lint policy file
+	LintPolicyFileName = "lint-policy.yaml"
+
+	// LintIgnoreAnnotation the annotation on a resource listing the comma separated rule IDs to ignore or '*' for all rules.
+	// The same key can be used in a YAML comment of the form '# gitops.jenkins-x.io/lint-ignore: rule1,rule2' to ignore rules for a whole file
+	LintIgnoreAnnotation = "gitops.jenkins-x.io/lint-ignore"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LintPolicy represents the rules used to lint the kubernetes resources in the config-root directory
+//
+// +k8s:openapi-gen=true
+type LintPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec holds the lint policy
+	// +optional
+	Spec LintPolicySpec `json:"spec"`
+}
+
+// LintPolicyList contains a list of LintPolicy
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type LintPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LintPolicy `json:"items"`
+}
+
+// LintPolicySpec defines the rules of the lint policy
+type LintPolicySpec struct {
+	// Rules configures the built in rules or adds custom rules.
+	// A rule with the same ID as a built in rule overrides its severity or disables it
+	Rules []LintRule `json:"rules,omitempty"`
+
+	// Exclude the path globs relative to the cluster repository of files which are not linted
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// LintSeverity the severity of a lint finding
+type LintSeverity string
+
+const (
+	// LintSeverityError errors fail the lint
+	LintSeverityError LintSeverity = "error"
+	// LintSeverityWarning warnings are reported but do not fail the lint by default
+	LintSeverityWarning LintSeverity = "warning"
+	// LintSeverityInfo informational findings
+	LintSeverityInfo LintSeverity = "info"
+)
+
+// LintRule a rule which is evaluated on each resource
+type LintRule struct {
+	// ID the unique identifier of the rule
+	ID string `json:"id" validate:"nonzero"`
+
+	// Description the description of the rule
+	Description string `json:"description,omitempty"`
+
+	// Severity the severity of findings of this rule. Defaults to error for custom rules
+	Severity LintSeverity `json:"severity,omitempty"`
+
+	// Disabled disables the rule
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Kinds if specified only resources of these kinds are checked
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Field the dot separated path of the field to check for custom rules. Use '[*]' to match every element of an array
+	// e.g. 'spec.template.spec.containers[*].image'
+	Field string `json:"field,omitempty"`
+
+	// Required the field must exist
+	Required bool `json:"required,omitempty"`
+
+	// Pattern if specified the field values must match this regular expression
+	Pattern string `json:"pattern,omitempty"`
+
+	// NotPattern if specified the field values must not match this regular expression
+	NotPattern string `json:"notPattern,omitempty"`
+
+	// Message the message to report for findings of custom rules
+	Message string `json:"message,omitempty"`
+}
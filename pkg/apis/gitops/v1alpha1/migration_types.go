@@ -0,0 +1,117 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MigrationsFileName default name of the migrations file in the cluster repository and version stream
+	MigrationsFileName = "migrations.yaml"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Migrations represents the declarative migrations contributed by a version stream and
+// the record of which migrations have been applied to a cluster git repository
+//
+// +k8s:openapi-gen=true
+type Migrations struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec holds the migrations and the applied record
+	// +optional
+	Spec MigrationsSpec `json:"spec"`
+}
+
+// MigrationsList contains a list of Migrations
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type MigrationsList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Migrations `json:"items"`
+}
+
+// MigrationsSpec defines the declarative migrations and the applied migrations
+type MigrationsSpec struct {
+	// Migrations the declarative migrations usually contributed by the version stream
+	Migrations []DeclarativeMigration `json:"migrations,omitempty"`
+
+	// Applied the migrations which have been applied to the cluster git repository
+	Applied []AppliedMigration `json:"applied,omitempty"`
+}
+
+// DeclarativeMigration a migration expressed as YAML rather than code
+type DeclarativeMigration struct {
+	// ID the unique identifier of the migration
+	ID string `json:"id" validate:"nonzero"`
+
+	// Description the description of the migration
+	Description string `json:"description,omitempty"`
+
+	// Repositories the repository URL rewrites to perform
+	Repositories []RepositoryURLRewrite `json:"repositories,omitempty"`
+
+	// Charts the chart replacements to perform
+	Charts []ChartReplacement `json:"charts,omitempty"`
+}
+
+// RepositoryURLRewrite rewrites the URL of a helm repository
+type RepositoryURLRewrite struct {
+	// From the old URL of the repository. Any trailing '/' is ignored when comparing
+	From string `json:"from" validate:"nonzero"`
+
+	// To the new URL of the repository
+	To string `json:"to" validate:"nonzero"`
+
+	// Name if specified only repositories with this name are rewritten
+	Name string `json:"name,omitempty"`
+}
+
+// ChartReplacement replaces a chart in any release which uses it
+type ChartReplacement struct {
+	// From the old chart name including the repository prefix
+	From string `json:"from" validate:"nonzero"`
+
+	// To the new chart name including the repository prefix
+	To string `json:"to" validate:"nonzero"`
+
+	// Namespace if specified the namespace of the release is changed
+	Namespace string `json:"namespace,omitempty"`
+
+	// RepositoryURL the URL of the repository for the prefix of the new chart which is added if it is missing
+	RepositoryURL string `json:"repositoryUrl,omitempty"`
+
+	// Values if specified replaces the values files of the release. Paths are relative to the version stream
+	Values []string `json:"values,omitempty"`
+
+	// ReplaceValues replaces any values files of the form 'charts/$from/...' with 'charts/$to/...' in the version stream
+	ReplaceValues bool `json:"replaceValues,omitempty"`
+
+	// ResolveVersion if enabled the version of the new chart is resolved from the version stream
+	ResolveVersion bool `json:"resolveVersion,omitempty"`
+}
+
+// AppliedMigration records a migration being applied
+type AppliedMigration struct {
+	// ID the unique identifier of the migration
+	ID string `json:"id"`
+
+	// Timestamp when the migration was applied
+	Timestamp metav1.Time `json:"timestamp,omitempty"`
+}
+
+// IsApplied returns true if the migration with the given ID has been applied
+func (m *Migrations) IsApplied(id string) bool {
+	for i := range m.Spec.Applied {
+		if m.Spec.Applied[i].ID == id {
+			return true
+		}
+	}
+	return false
+}
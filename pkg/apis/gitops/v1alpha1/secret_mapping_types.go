@@ -47,6 +47,12 @@ type Defaults struct {
 	BackendType BackendType `json:"backendType,omitempty" validate:"nonzero"`
 	// GcpSecretsManager config
 	GcpSecretsManager GcpSecretsManager `json:"gcpSecretsManager,omitempty"`
+	// AwsSecretsManager config
+	AwsSecretsManager AwsSecretsManager `json:"awsSecretsManager,omitempty"`
+	// AzureKeyVault config
+	AzureKeyVault AzureKeyVault `json:"azureKeyVault,omitempty"`
+	// Vault config
+	Vault Vault `json:"vault,omitempty"`
 }
 
 // SecretMappingList contains a list of SecretMapping
@@ -73,6 +79,12 @@ type SecretRule struct {
 	Mandatory bool `json:"mandatory,omitempty"`
 	// GcpSecretsManager config
 	GcpSecretsManager GcpSecretsManager `json:"gcpSecretsManager,omitempty"`
+	// AwsSecretsManager config
+	AwsSecretsManager AwsSecretsManager `json:"awsSecretsManager,omitempty"`
+	// AzureKeyVault config
+	AzureKeyVault AzureKeyVault `json:"azureKeyVault,omitempty"`
+	// Vault config
+	Vault Vault `json:"vault,omitempty"`
 }
 
 // BackendType describes a secrets backend
@@ -83,6 +95,12 @@ const (
 	BackendTypeVault BackendType = "vault"
 	// BackendTypeGSM Google Secrets Manager is the Backed service
 	BackendTypeGSM BackendType = "gcpSecretsManager"
+	// BackendTypeAWS AWS Secrets Manager is the Backed service
+	BackendTypeAWS BackendType = "secretsManager"
+	// BackendTypeAzure Azure Key Vault is the Backed service
+	BackendTypeAzure BackendType = "azureKeyVault"
+	// BackendTypeLocal local kubernetes Secrets are the Backed service which is useful for testing
+	BackendTypeLocal BackendType = "local"
 	// BackendTypeNone if none is configured
 	BackendTypeNone BackendType = ""
 )
@@ -97,6 +115,30 @@ type GcpSecretsManager struct {
 	UniquePrefix string `json:"uniquePrefix,omitempty"`
 }
 
+// AwsSecretsManager the configuration for AWS Secrets Manager
+type AwsSecretsManager struct {
+	// Region the AWS region of the secrets, defaults to the cluster region
+	Region string `json:"region,omitempty"`
+	// RoleArn the optional role to assume when accessing the secrets
+	RoleArn string `json:"roleArn,omitempty"`
+}
+
+// AzureKeyVault the configuration for Azure Key Vault
+type AzureKeyVault struct {
+	// KeyVaultName the name of the key vault containing the secrets
+	KeyVaultName string `json:"keyVaultName,omitempty"`
+}
+
+// Vault the configuration for Vault
+type Vault struct {
+	// MountPoint the kubernetes auth mount point, defaults to kubernetes
+	MountPoint string `json:"mountPoint,omitempty"`
+	// Role the vault role used to access the secrets, defaults to vault-infra
+	Role string `json:"role,omitempty"`
+	// KeyPrefix the prefix of the default keys, defaults to secret/data
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+}
+
 // Mapping the predicates which must be true to invoke the associated tasks/pipelines
 type Mapping struct {
 	// Name the secret entry name which maps to the Key of the Secret.Data map
@@ -0,0 +1,52 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// VersionStreamHistoryFileName default name of the version stream history file in the cluster repository
+	VersionStreamHistoryFileName = "versionstream-history.yaml"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VersionStreamHistory records the version streams previously used by a cluster git repository
+// so that a version stream switch can be rolled back
+//
+// +k8s:openapi-gen=true
+type VersionStreamHistory struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec holds the history
+	// +optional
+	Spec VersionStreamHistorySpec `json:"spec"`
+}
+
+// VersionStreamHistorySpec defines the version stream history
+type VersionStreamHistorySpec struct {
+	// Entries the previous version streams with the most recent last
+	Entries []VersionStreamHistoryEntry `json:"entries,omitempty"`
+}
+
+// VersionStreamHistoryEntry a version stream which was used by the cluster git repository
+type VersionStreamHistoryEntry struct {
+	// URL the git URL of the version stream
+	URL string `json:"url" validate:"nonzero"`
+
+	// Ref the git ref of the version stream in the Kptfile. This is the commit SHA if the version stream was pinned
+	Ref string `json:"ref,omitempty"`
+
+	// Directory the directory in the git repository of the version stream
+	Directory string `json:"directory,omitempty"`
+
+	// SHA the git commit SHA of the version stream
+	SHA string `json:"sha,omitempty"`
+
+	// Timestamp when the version stream was replaced
+	Timestamp metav1.Time `json:"timestamp,omitempty"`
+}
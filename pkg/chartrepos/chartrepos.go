@@ -0,0 +1,295 @@
+package chartrepos
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/httphelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/chart"
+	helmrepo "helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OCIScheme the URL scheme of OCI registries
+	OCIScheme = ociregistry.OCIScheme
+)
+
+// Repository a helm chart repository or an OCI registry
+type Repository struct {
+	Name     string
+	URL      string
+	OCI      bool
+	Username string
+	Password string
+}
+
+// NewRepository creates a repository for the URL removing any oci:// scheme from the URL of OCI registries
+// so that it can be used in a helmfile
+func NewRepository(name, u string, oci bool) Repository {
+	if strings.HasPrefix(u, OCIScheme) {
+		u = strings.TrimPrefix(u, OCIScheme)
+		oci = true
+	}
+	return Repository{Name: name, URL: u, OCI: oci}
+}
+
+// FromRepositorySpec creates a repository from a helmfile repository
+func FromRepositorySpec(spec *state.RepositorySpec) Repository {
+	answer := NewRepository(spec.Name, spec.URL, spec.OCI)
+	answer.Username = spec.Username
+	answer.Password = spec.Password
+	return answer
+}
+
+// IsOCI returns true if the repository is an OCI registry
+func (r *Repository) IsOCI() bool {
+	return r.OCI || strings.HasPrefix(r.URL, OCIScheme)
+}
+
+// ChartURL returns the URL of a chart in an OCI registry or the repository URL for other repositories
+func (r *Repository) ChartURL(chartName string) string {
+	if r.IsOCI() {
+		return OCIScheme + stringhelpers.UrlJoin(strings.TrimPrefix(r.URL, OCIScheme), chartName)
+	}
+	return r.URL
+}
+
+// SplitChartName splits a helmfile chart name into the repository prefix and chart name. Charts referenced via an
+// oci:// URL have no prefix. The prefix of local charts is '.' or '..'
+func SplitChartName(chartName string) (string, string) {
+	if strings.HasPrefix(chartName, OCIScheme) {
+		i := strings.LastIndex(chartName, "/")
+		return "", chartName[i+1:]
+	}
+	prefix, name, ok := strings.Cut(chartName, "/")
+	if !ok {
+		return "", chartName
+	}
+	return prefix, name
+}
+
+// Client provides access to the charts in chart repositories
+type Client interface {
+	// Versions returns the versions of the chart in the repository
+	Versions(repo Repository, chartName string) ([]string, error)
+
+	// ChartMetadata returns the metadata of the version of the chart in the repository
+	ChartMetadata(repo Repository, chartName, version string) (*chart.Metadata, error)
+}
+
+// HTTPClient a Client which uses the index.yaml of chart repositories or the v2 API of OCI registries
+type HTTPClient struct {
+	Client   *http.Client
+	Registry *ociregistry.Client
+	lock     sync.Mutex
+	indexes  map[string]*helmrepo.IndexFile
+
+	// indexLocks the locks of each repository URL so that an index is only fetched once without blocking other repositories
+	indexLocks map[string]*sync.Mutex
+}
+
+// NewClient creates a new Client using the default http client
+func NewClient() *HTTPClient {
+	return &HTTPClient{
+		Client: httphelpers.GetClient(),
+	}
+}
+
+// NewClientWithRegistryConfig creates a new Client which uses the credentials in the docker config file for OCI registries
+func NewClientWithRegistryConfig(registryConfigFile string) (*HTTPClient, error) {
+	registry, err := ociregistry.NewClient(registryConfigFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create registry client")
+	}
+	return &HTTPClient{
+		Client:   registry.HTTPClient,
+		Registry: registry,
+	}, nil
+}
+
+// Versions returns the versions of the chart in the repository
+func (c *HTTPClient) Versions(repo Repository, chartName string) ([]string, error) {
+	if repo.IsOCI() {
+		host, path := c.ociRepository(repo, chartName)
+		return c.registry().Tags(host, path)
+	}
+	index, err := c.Index(repo.URL)
+	if err != nil {
+		return nil, err
+	}
+	var answer []string
+	for _, cv := range index.Entries[chartName] {
+		if cv != nil && cv.Metadata != nil {
+			answer = append(answer, cv.Version)
+		}
+	}
+	return answer, nil
+}
+
+// ChartMetadata returns the metadata of the version of the chart in the repository
+func (c *HTTPClient) ChartMetadata(repo Repository, chartName, version string) (*chart.Metadata, error) {
+	if repo.IsOCI() {
+		host, path := c.ociRepository(repo, chartName)
+		registry := c.registry()
+		manifest, _, err := registry.Manifest(host, path, version)
+		if err != nil {
+			return nil, err
+		}
+		data, err := registry.Blob(host, path, manifest.Config.Digest)
+		if err != nil {
+			return nil, err
+		}
+		answer := &chart.Metadata{}
+		err = json.Unmarshal(data, answer)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse chart metadata of %s version %s", repo.ChartURL(chartName), version)
+		}
+		return answer, nil
+	}
+	index, err := c.Index(repo.URL)
+	if err != nil {
+		return nil, err
+	}
+	cv, err := index.Get(chartName, version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find chart %s version %s in repository %s", chartName, version, repo.URL)
+	}
+	return cv.Metadata, nil
+}
+
+// Index returns the index.yaml of the chart repository caching the result
+func (c *HTTPClient) Index(repoURL string) (*helmrepo.IndexFile, error) {
+	urlLock := c.indexLock(repoURL)
+	urlLock.Lock()
+	defer urlLock.Unlock()
+
+	c.lock.Lock()
+	index := c.indexes[repoURL]
+	c.lock.Unlock()
+	if index != nil {
+		return index, nil
+	}
+
+	index, err := c.fetchIndex(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	c.indexes[repoURL] = index
+	c.lock.Unlock()
+	return index, nil
+}
+
+// indexLock returns the lock used to fetch the index of the given repository URL
+func (c *HTTPClient) indexLock(repoURL string) *sync.Mutex {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.indexes == nil {
+		c.indexes = map[string]*helmrepo.IndexFile{}
+	}
+	if c.indexLocks == nil {
+		c.indexLocks = map[string]*sync.Mutex{}
+	}
+	answer := c.indexLocks[repoURL]
+	if answer == nil {
+		answer = &sync.Mutex{}
+		c.indexLocks[repoURL] = answer
+	}
+	return answer
+}
+
+func (c *HTTPClient) fetchIndex(repoURL string) (*helmrepo.IndexFile, error) {
+	u := stringhelpers.UrlJoin(repoURL, "index.yaml")
+	resp, err := c.httpClient().Get(u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to GET %s", u)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, errors.Errorf("failed to GET %s with status %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read response from %s", u)
+	}
+	index := &helmrepo.IndexFile{}
+	err = yaml.Unmarshal(data, index)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", u)
+	}
+	return index, nil
+}
+
+// ociRepository returns the registry host and repository path of the chart registering any repository credentials
+func (c *HTTPClient) ociRepository(repo Repository, chartName string) (string, string) {
+	host, path := ociregistry.SplitURL(repo.URL)
+	if repo.Username != "" || repo.Password != "" {
+		c.registry().SetCredentials(host, ociregistry.Credentials{Username: repo.Username, Password: repo.Password})
+	}
+	return host, strings.TrimPrefix(path+"/"+chartName, "/")
+}
+
+func (c *HTTPClient) registry() *ociregistry.Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.Registry == nil {
+		c.Registry = &ociregistry.Client{HTTPClient: c.httpClient()}
+	}
+	return c.Registry
+}
+
+func (c *HTTPClient) httpClient() *http.Client {
+	if c.Client == nil {
+		return httphelpers.GetClient()
+	}
+	return c.Client
+}
+
+// LatestVersion returns the latest semantic version ignoring any pre-releases unless includePrerelease is true
+func LatestVersion(versions []string, includePrerelease bool) string {
+	var list []*semver.Version
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if sv.Prerelease() != "" && !includePrerelease {
+			continue
+		}
+		list = append(list, sv)
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	sort.Sort(semver.Collection(list))
+	return list[len(list)-1].Original()
+}
+
+// IsNewer returns true if the version is newer than the current version. If either version is not a semantic
+// version then any difference is assumed to be newer
+func IsNewer(current, version string) bool {
+	if version == "" || current == version {
+		return false
+	}
+	cv, err := semver.NewVersion(current)
+	if err != nil {
+		return true
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return true
+	}
+	return v.GreaterThan(cv)
+}
@@ -0,0 +1,156 @@
+package chartrepos_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/fakeregistry"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestIndexRepository(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`apiVersion: v1
+entries:
+  mychart:
+  - name: mychart
+    version: 1.1.0
+    description: my chart
+  - name: mychart
+    version: 1.0.0
+`))
+	}))
+	defer server.Close()
+
+	c := chartrepos.NewClient()
+	repo := chartrepos.NewRepository("myrepo", server.URL, false)
+	versions, err := c.Versions(repo, "mychart")
+	require.NoError(t, err, "failed to list versions")
+	assert.Equal(t, []string{"1.1.0", "1.0.0"}, versions)
+
+	metadata, err := c.ChartMetadata(repo, "mychart", "1.1.0")
+	require.NoError(t, err, "failed to get metadata")
+	assert.Equal(t, "my chart", metadata.Description)
+}
+
+func TestIndexDoesNotBlockOtherRepositories(t *testing.T) {
+	index := []byte("apiVersion: v1\nentries: {}\n")
+	release := make(chan struct{})
+	slowStarted := make(chan struct{})
+	var requests int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			close(slowStarted)
+		}
+		<-release
+		_, _ = w.Write(index)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(index)
+	}))
+	defer fast.Close()
+
+	c := chartrepos.NewClient()
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := c.Index(slow.URL)
+			errs <- err
+		}()
+	}
+	<-slowStarted
+
+	_, err := c.Index(fast.URL)
+	require.NoError(t, err, "failed to get the index of %s while %s is being fetched", fast.URL, slow.URL)
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-errs, "failed to get the index of %s", slow.URL)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "should only fetch the index once")
+}
+
+func TestOCIRepository(t *testing.T) {
+	registry := fakeregistry.New()
+	defer registry.Close()
+	registry.Credentials = ociregistry.Credentials{Username: "myuser", Password: "mypassword"}
+
+	for _, v := range []string{"1.0.0", "1.1.0", "1.2.0-beta.1"} {
+		registry.AddChart("myorg/charts/mychart", &chart.Metadata{Name: "mychart", Version: v, Description: "chart " + v, APIVersion: "v2"})
+	}
+
+	// lets use the credentials from a docker config file
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{"auths": {"`+registry.Host+`": {"auth": "bXl1c2VyOm15cGFzc3dvcmQ="}}}`), 0o600)
+	require.NoError(t, err, "failed to save %s", configFile)
+
+	c, err := chartrepos.NewClientWithRegistryConfig(configFile)
+	require.NoError(t, err, "failed to create client")
+	c.Registry.HTTPClient = registry.Server.Client()
+
+	repo := chartrepos.NewRepository("myrepo", registry.URL("myorg/charts"), false)
+	assert.True(t, repo.OCI, "should be an OCI repository")
+	assert.Equal(t, registry.Host+"/myorg/charts", repo.URL)
+	assert.Equal(t, registry.URL("myorg/charts/mychart"), repo.ChartURL("mychart"))
+
+	versions, err := c.Versions(repo, "mychart")
+	require.NoError(t, err, "failed to list versions")
+	assert.ElementsMatch(t, []string{"1.0.0", "1.1.0", "1.2.0-beta.1"}, versions)
+	assert.Equal(t, "1.1.0", chartrepos.LatestVersion(versions, false))
+
+	metadata, err := c.ChartMetadata(repo, "mychart", "1.1.0")
+	require.NoError(t, err, "failed to get metadata")
+	assert.Equal(t, "chart 1.1.0", metadata.Description)
+
+	// without credentials the registry should refuse the token
+	anonymous := &chartrepos.HTTPClient{Registry: registry.Client()}
+	_, err = anonymous.Versions(repo, "mychart")
+	require.Error(t, err, "should fail without credentials")
+
+	// credentials in the helmfile repository should be used
+	repo.Username = "myuser"
+	repo.Password = "mypassword"
+	versions, err = anonymous.Versions(repo, "mychart")
+	require.NoError(t, err, "failed to list versions with repository credentials")
+	assert.Len(t, versions, 3)
+}
+
+func TestSplitChartName(t *testing.T) {
+	testCases := []struct {
+		chart, prefix, name string
+	}{
+		{chart: "jenkins-x/lighthouse", prefix: "jenkins-x", name: "lighthouse"},
+		{chart: "oci://ghcr.io/myorg/charts/mychart", prefix: "", name: "mychart"},
+		{chart: "../charts/mychart", prefix: "..", name: "charts/mychart"},
+		{chart: "mychart", prefix: "", name: "mychart"},
+	}
+	for _, tc := range testCases {
+		prefix, name := chartrepos.SplitChartName(tc.chart)
+		assert.Equal(t, tc.prefix, prefix, "prefix for %s", tc.chart)
+		assert.Equal(t, tc.name, name, "name for %s", tc.chart)
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	versions := []string{"1.0.0", "1.10.0", "1.9.3", "2.0.0-rc.1", "latest"}
+	assert.Equal(t, "1.10.0", chartrepos.LatestVersion(versions, false))
+	assert.Equal(t, "2.0.0-rc.1", chartrepos.LatestVersion(versions, true))
+	assert.Equal(t, "", chartrepos.LatestVersion(nil, false))
+
+	assert.True(t, chartrepos.IsNewer("1.9.3", "1.10.0"))
+	assert.False(t, chartrepos.IsNewer("1.10.0", "1.9.3"))
+	assert.False(t, chartrepos.IsNewer("1.0.0", ""))
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
@@ -33,6 +34,8 @@ var (
 
 		If the last commit was a merge from a pull request the regeneration is skipped, unless the cluster is new.
 
+		When using --incremental the last regenerated commit is recorded in the .jx/gitops/last-regen-commit file which is committed along with the regenerated resources. The files changed since the commit which recorded that file (so ignoring the files modified by the regeneration itself) are mapped to the nested helmfiles so that only the affected helmfiles are regenerated leaving the other namespaces untouched. The previously generated resources of their releases are removed from config-root and then the 'regen-incremental-phase-1' Makefile target is invoked with the space separated helmfiles in the HELMFILES variable instead of 'regen-phase-1'. The target should template the helmfiles into config-root, post process the resources and commit them in the same way as 'regen-phase-1'. Any other change (e.g. to the root helmfile, jx-requirements.yml or the version stream) results in a full regeneration.
+
 		Also the process detects if an ingress has changed (or similar changes) and retriggers another regeneration which typically is only required when installing for the first time or if no explicit domain name is being used and the LoadBalancer service has been removed.
 `)
 
@@ -46,6 +49,7 @@ var (
 type Options struct {
 	Dir           string
 	PullRequest   bool
+	Incremental   bool
 	CommandRunner cmdrunner.CommandRunner
 	IsNewCluster  bool
 	repo          *git.Repository
@@ -67,6 +71,7 @@ func NewCmdApply() (*cobra.Command, *Options) {
 	}
 	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory to the git and make commands")
 	cmd.Flags().BoolVarP(&o.PullRequest, "pull-request", "", false, "specifies to apply the pull request contents into the PR branch")
+	cmd.Flags().BoolVarP(&o.Incremental, "incremental", "", false, "only regenerates the nested helmfiles affected by the files changed since the last regenerated commit. Requires the 'regen-incremental-phase-1' Makefile target")
 	return cmd, o
 }
 
@@ -114,11 +119,25 @@ func (o *Options) Run() error {
 			regen = true
 		}
 	}
+	var affected []helmfiles.Helmfile
 	if !regen {
-		if o.IsNewCluster {
+		regenCommit := ""
+		if o.Incremental {
+			regenCommit, err = LoadRegenCommit(o.Dir)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load the last regenerated commit")
+			}
+		}
+		switch {
+		case o.IsNewCluster:
 			log.Logger().Infof("applying to new cluster so performing a full regenerate")
 			regen = true
-		} else {
+		case regenCommit != "":
+			regen, affected, err = o.changesSinceRegen(regenCommit, headCommit)
+			if err != nil {
+				return err
+			}
+		default:
 			err := verifyRegenerated(headCommit, &object.Commit{})
 			if err != nil {
 				log.Logger().WithError(err).Infof("all changes may not have been regenerated")
@@ -133,9 +152,23 @@ func (o *Options) Run() error {
 		if o.PullRequest {
 			return o.pullRequest()
 		}
-		_, err := o.Regenerate(o.repo, headCommit)
-		if err != nil {
-			return errors.Wrapf(err, "failed to regenerate")
+		if o.Incremental {
+			// lets record the commit before regenerating so that the marker is committed and pushed with the regenerated resources
+			err = o.RecordRegenerated(headCommit)
+			if err != nil {
+				return errors.Wrapf(err, "failed to record the regenerated commit")
+			}
+		}
+		if len(affected) > 0 {
+			_, err = o.RegenerateHelmfiles(o.repo, headCommit, affected)
+			if err != nil {
+				return errors.Wrapf(err, "failed to regenerate helmfiles")
+			}
+		} else {
+			_, err = o.Regenerate(o.repo, headCommit)
+			if err != nil {
+				return errors.Wrapf(err, "failed to regenerate")
+			}
 		}
 
 		c := &cmdrunner.Command{
@@ -161,14 +194,43 @@ func (o *Options) Run() error {
 	return nil
 }
 
+// changesSinceRegen returns whether a regeneration is required and the affected nested helmfiles if only they need to be regenerated
+func (o *Options) changesSinceRegen(regenCommit string, headCommit *object.Commit) (bool, []helmfiles.Helmfile, error) {
+	changed, err := ChangedFiles(o.repo, regenCommit, headCommit)
+	if err != nil {
+		log.Logger().WithError(err).Infof("failed to find the changes since the last regenerated commit %s so performing a full regenerate", regenCommit)
+		return true, nil, nil
+	}
+	if len(changed) == 0 {
+		log.Logger().Infof("all changes are already regenerated at commit %s", info(regenCommit))
+		return false, nil, nil
+	}
+	affected, full, err := AffectedHelmfiles(o.Dir, changed)
+	if err != nil {
+		return false, nil, errors.Wrapf(err, "failed to find the helmfiles affected by the changes since commit %s", regenCommit)
+	}
+	if full {
+		return true, nil, nil
+	}
+	for _, hf := range affected {
+		log.Logger().Infof("regenerating helmfile %s", info(hf.Filepath))
+	}
+	return true, affected, nil
+}
+
 // Regenerate regenerates the kubernetes resources
 func (o *Options) Regenerate(repo *git.Repository, headCommit *object.Commit) (bool, error) {
+	return o.regenerate(repo, headCommit, "regen-phase-1")
+}
+
+// regenerate runs the given phase 1 make target and its arguments followed by phase 2 if phase 1 did not cancel the pipeline
+func (o *Options) regenerate(repo *git.Repository, headCommit *object.Commit, phase1 ...string) (bool, error) {
 	firstSha := headCommit.Hash.String()
 
 	c := &cmdrunner.Command{
 		Dir:  o.Dir,
 		Name: "make",
-		Args: []string{"regen-phase-1", "NEW_CLUSTER=" + strconv.FormatBool(o.IsNewCluster)},
+		Args: append(append([]string{}, phase1...), "NEW_CLUSTER="+strconv.FormatBool(o.IsNewCluster)),
 	}
 	err := o.RunCommand(c)
 	if err != nil {
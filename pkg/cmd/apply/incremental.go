@@ -0,0 +1,240 @@
+package apply
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/move"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	// RegenMarkerFile the file which records the last commit that was regenerated relative to the cluster git repository
+	RegenMarkerFile = filepath.Join(".jx", "gitops", "last-regen-commit")
+)
+
+// LoadRegenCommit loads the last regenerated commit SHA from the marker file or returns an empty string if there is none
+func LoadRegenCommit(dir string) (string, error) {
+	path := filepath.Join(dir, RegenMarkerFile)
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ChangedFiles returns the sorted files changed between the given regenerated commit SHA and the head commit ignoring
+// the generated resources in config-root and the regeneration marker file. The regeneration also modifies files
+// outside of config-root (such as resolved helmfiles, values files and reports) in the commit which records the marker
+// file so the changes are found from that commit if it exists
+func ChangedFiles(repo *git.Repository, fromSha string, headCommit *object.Commit) ([]string, error) {
+	fromCommit, err := markerCommit(repo, fromSha, headCommit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the commit recording the regenerated commit %s", fromSha)
+	}
+	if fromCommit == nil {
+		fromCommit, err = repo.CommitObject(plumbing.NewHash(fromSha))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find commit %s", fromSha)
+		}
+	}
+	fromTree, err := fromCommit.Tree()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tree of commit %s", fromCommit.Hash.String())
+	}
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tree of commit %s", headCommit.Hash.String())
+	}
+	changes, err := object.DiffTree(fromTree, headTree)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to diff commit %s with %s", fromCommit.Hash.String(), headCommit.Hash.String())
+	}
+
+	marker := filepath.ToSlash(RegenMarkerFile)
+	paths := map[string]bool{}
+	for _, c := range changes {
+		for _, name := range []string{c.From.Name, c.To.Name} {
+			if name == "" || name == marker || strings.HasPrefix(name, "config-root/") {
+				continue
+			}
+			paths[name] = true
+		}
+	}
+	var answer []string
+	for p := range paths {
+		answer = append(answer, p)
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
+
+// markerCommit returns the most recent commit from the head commit which committed the marker file recording the
+// given SHA or nil if there is no such commit
+func markerCommit(repo *git.Repository, sha string, headCommit *object.Commit) (*object.Commit, error) {
+	marker := filepath.ToSlash(RegenMarkerFile)
+	iter, err := repo.Log(&git.LogOptions{
+		From:     headCommit.Hash,
+		FileName: &marker,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the commits of %s", marker)
+	}
+	defer iter.Close()
+	for {
+		c, err := iter.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find the commits of %s", marker)
+		}
+		f, err := c.File(marker)
+		if err != nil {
+			// the marker was removed in this commit
+			continue
+		}
+		text, err := f.Contents()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s in commit %s", marker, c.Hash.String())
+		}
+		if strings.TrimSpace(text) == sha {
+			return c, nil
+		}
+	}
+}
+
+// AffectedHelmfiles returns the nested helmfiles affected by the changed files. If any changed file is not inside the
+// directory of a nested helmfile (e.g. the root helmfile, the requirements or the version stream) then a full
+// regeneration is required so true is returned
+func AffectedHelmfiles(dir string, changed []string) ([]helmfiles.Helmfile, bool, error) {
+	all, err := helmfiles.GatherHelmfiles("helmfile.yaml", dir)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to gather helmfiles in dir %s", dir)
+	}
+
+	// the first helmfile is the root helmfile which affects everything
+	nested := map[string]helmfiles.Helmfile{}
+	for _, hf := range all[1:] {
+		rel, err := filepath.Rel(dir, filepath.Dir(hf.Filepath))
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "failed to find relative path of %s", hf.Filepath)
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			continue
+		}
+		nested[rel] = hf
+	}
+
+	affected := map[string]helmfiles.Helmfile{}
+	for _, path := range changed {
+		found := false
+		for helmfileDir, hf := range nested {
+			if strings.HasPrefix(path, helmfileDir+"/") {
+				affected[hf.Filepath] = hf
+				found = true
+			}
+		}
+		if !found {
+			log.Logger().Infof("changed file %s is not part of a nested helmfile so performing a full regenerate", info(path))
+			return nil, true, nil
+		}
+	}
+
+	var answer []helmfiles.Helmfile
+	for _, hf := range affected {
+		answer = append(answer, hf)
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		return answer[i].Filepath < answer[j].Filepath
+	})
+	return answer, false, nil
+}
+
+// RegenerateHelmfiles removes the previously generated resources of the releases of the given helmfiles and then
+// regenerates them via the 'regen-incremental-phase-1' Makefile target so that the resources are post processed,
+// committed, applied and pushed in the same way as a full regeneration while other namespaces are left untouched
+func (o *Options) RegenerateHelmfiles(repo *git.Repository, headCommit *object.Commit, affected []helmfiles.Helmfile) (bool, error) {
+	outputDir := filepath.Join(o.Dir, "config-root")
+	var paths []string
+	for _, hf := range affected {
+		err := removeGeneratedReleases(hf.Filepath, outputDir)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to remove generated resources of %s", hf.Filepath)
+		}
+		rel, err := filepath.Rel(o.Dir, hf.Filepath)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to find relative path of %s", hf.Filepath)
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return o.regenerate(repo, headCommit, "regen-incremental-phase-1", "HELMFILES="+strings.Join(paths, " "))
+}
+
+// RecordRegenerated records the given commit as regenerated in the marker file. The marker file is then committed
+// by the regeneration along with the regenerated resources
+func (o *Options) RecordRegenerated(commit *object.Commit) error {
+	path := filepath.Join(o.Dir, RegenMarkerFile)
+	err := os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir for %s", path)
+	}
+	err = os.WriteFile(path, []byte(commit.Hash.String()+"\n"), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	return nil
+}
+
+// removeGeneratedReleases removes the resources previously generated for the releases of the helmfile
+func removeGeneratedReleases(helmfile, outputDir string) error {
+	helmStates, err := helmfiles.LoadHelmfile(helmfile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load helmfile %s", helmfile)
+	}
+	for _, helmState := range helmStates {
+		for i := range helmState.Releases {
+			release := &helmState.Releases[i]
+			ns := release.Namespace
+			if ns == "" {
+				ns = helmState.OverrideNamespace
+			}
+			if ns == "" || release.Name == "" {
+				continue
+			}
+			chartName := release.Chart
+			if i := strings.LastIndex(chartName, "/"); i >= 0 {
+				chartName = chartName[i+1:]
+			}
+			pathName := move.PathName(chartName, release.Name)
+			for _, dir := range []string{
+				filepath.Join(outputDir, "namespaces", ns, pathName),
+				filepath.Join(outputDir, "cluster", "resources", ns, pathName),
+				filepath.Join(outputDir, "customresourcedefinitions", ns, pathName),
+			} {
+				err = os.RemoveAll(dir)
+				if err != nil {
+					return errors.Wrapf(err, "failed to remove dir %s", dir)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalRegenerate(t *testing.T) {
+	t.Setenv("JX_NO_KUBERNETES", "true")
+
+	dir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join("testdata", "incremental"), dir)
+	require.NoError(t, err, "failed to copy testdata")
+
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err, "failed to init git repository")
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(message string) *object.Commit {
+		_, err := wt.Add(".")
+		require.NoError(t, err)
+		hash, err := wt.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err, "failed to commit %s", message)
+		c, err := repo.CommitObject(hash)
+		require.NoError(t, err)
+		return c
+	}
+	regenCommit := commit("chore: regenerated")
+
+	err = os.MkdirAll(filepath.Join(dir, ".jx", "gitops"), files.DefaultDirWritePermissions)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, RegenMarkerFile), []byte(regenCommit.Hash.String()+"\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err)
+
+	// the regeneration also modifies files outside of config-root
+	err = os.WriteFile(filepath.Join(dir, "helmfiles", "nginx", "jx-values.yaml"), []byte("jxRequirements: {}\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err)
+	err = os.MkdirAll(filepath.Join(dir, "docs"), files.DefaultDirWritePermissions)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "docs", "README.md"), []byte("# Releases\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err)
+	markerCommit := commit("chore: regenerated with the marker")
+
+	changed, err := ChangedFiles(repo, regenCommit.Hash.String(), markerCommit)
+	require.NoError(t, err)
+	assert.Empty(t, changed, "changes after recording the regenerated commit")
+
+	// now lets change the values of a nested helmfile
+	err = os.WriteFile(filepath.Join(dir, "helmfiles", "nginx", "values.yaml"), []byte("replicaCount: 2\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err)
+	headCommit := commit("fix: more replicas")
+
+	changed, err = ChangedFiles(repo, regenCommit.Hash.String(), headCommit)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"helmfiles/nginx/values.yaml"}, changed, "changed files")
+
+	affected, full, err := AffectedHelmfiles(dir, changed)
+	require.NoError(t, err)
+	assert.False(t, full, "should not need a full regenerate")
+	require.Len(t, affected, 1, "affected helmfiles")
+	assert.Equal(t, filepath.Join(dir, "helmfiles", "nginx", "helmfile.yaml"), affected[0].Filepath)
+
+	_, full, err = AffectedHelmfiles(dir, []string{"helmfiles/nginx/values.yaml", "jx-requirements.yml"})
+	require.NoError(t, err)
+	assert.True(t, full, "a change outside of the nested helmfiles should need a full regenerate")
+
+	fakeRunner := fakerunner.FakeRunner{}
+	o := Options{
+		Dir:           dir,
+		Incremental:   true,
+		CommandRunner: fakeRunner.Run,
+		repo:          repo,
+	}
+	err = o.Run()
+	require.NoError(t, err, "failed to run")
+
+	var commands []string
+	for _, c := range fakeRunner.OrderedCommands {
+		commands = append(commands, c.Name+" "+c.Args[0])
+	}
+	assert.Equal(t, []string{"make regen-incremental-phase-1", "make regen-phase-2", "make regen-phase-3"}, commands, "commands")
+	assert.Equal(t, "HELMFILES=helmfiles/nginx/helmfile.yaml", fakeRunner.OrderedCommands[0].Args[1], "regenerated helmfiles")
+
+	assert.NoDirExists(t, filepath.Join(dir, "config-root", "namespaces", "nginx", "ingress-nginx"), "should have removed the regenerated release")
+	assert.FileExists(t, filepath.Join(dir, "config-root", "namespaces", "jx", "jx-pipelines-visualizer", "jx-pipelines-visualizer-deploy.yaml"), "should not have modified other namespaces")
+
+	regenSha, err := LoadRegenCommit(dir)
+	require.NoError(t, err)
+	assert.Equal(t, headCommit.Hash.String(), regenSha, "recorded regenerated commit")
+}
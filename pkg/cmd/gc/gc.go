@@ -3,7 +3,9 @@ package gc
 import (
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/activities"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/jobs"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/orphans"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/pods"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/run"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/spf13/cobra"
@@ -24,5 +26,7 @@ func NewCmdGC() *cobra.Command {
 	command.AddCommand(cobras.SplitCommand(activities.NewCmdGCActivities()))
 	command.AddCommand(cobras.SplitCommand(pods.NewCmdGCPods()))
 	command.AddCommand(cobras.SplitCommand(jobs.NewCmdGCJobs()))
+	command.AddCommand(cobras.SplitCommand(orphans.NewCmdGCOrphans()))
+	command.AddCommand(cobras.SplitCommand(run.NewCmdGCRun()))
 	return command
 }
@@ -0,0 +1,441 @@
+package orphans
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/activities"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/run"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/gcpolicy"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/errorutil"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	lhclient "github.com/jenkins-x/lighthouse-client/pkg/client/clientset/versioned"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// KindTaskRun the tekton TaskRun resources
+	KindTaskRun = "TaskRun"
+
+	// ActivityNameLabel the lighthouse label containing the name of the PipelineActivity
+	ActivityNameLabel = "lighthouse.jenkins-x.io/activityName"
+	// BuildNumLabel the lighthouse label containing the build number
+	BuildNumLabel = "lighthouse.jenkins-x.io/buildNum"
+	// PipelineRunLabel the tekton label on a TaskRun containing the name of its PipelineRun
+	PipelineRunLabel = "tekton.dev/pipelineRun"
+	// CreatedByLighthouseLabel the label lighthouse adds to the PipelineRuns it creates
+	CreatedByLighthouseLabel = "created-by-lighthouse"
+
+	lighthouseLabelPrefix = "lighthouse.jenkins-x.io/"
+)
+
+var (
+	// TaskRunResource the tekton TaskRun resource
+	TaskRunResource = schema.GroupVersionResource{
+		Group:    "tekton.dev",
+		Version:  "v1",
+		Resource: "taskruns",
+	}
+
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Garbage collects orphaned PipelineRuns, TaskRuns and LighthouseJobs
+
+		A PipelineRun or LighthouseJob is orphaned if it is not linked to any PipelineActivity or its owner no longer exists. A TaskRun is orphaned if its PipelineRun no longer exists.
+
+		Only PipelineRuns and TaskRuns created by lighthouse are considered, i.e. those with the 'created-by-lighthouse' label or a 'lighthouse.jenkins-x.io/' label, so that resources created by other tools are left alone. The command fails if the PipelineActivities cannot be listed rather than treating every resource as orphaned.
+
+		Completed orphans older than the age are deleted. Orphans which have not completed are only deleted if the --incomplete-age option is specified and they are older than it
+`)
+
+	cmdExample = templates.Examples(`
+		# garbage collect orphaned resources
+		jx gitops gc orphans
+
+		# dry run mode
+		jx gitops gc orphans --dry-run
+
+		# also delete orphans which have been running for more than a day
+		jx gitops gc orphans --incomplete-age 24h
+`)
+)
+
+// Options the options for the command
+type Options struct {
+	Namespace     string
+	DryRun        bool
+	Age           time.Duration
+	IncompleteAge time.Duration
+	Orphans       []*Orphan
+	Summary       []*SummaryRow
+	Out           io.Writer
+	JXClient      jxc.Interface
+	LHClient      lhclient.Interface
+	DynamicClient dynamic.Interface
+}
+
+// Orphan an orphaned resource
+type Orphan struct {
+	*gcpolicy.Resource
+	Reason string
+	Delete bool
+}
+
+// SummaryRow the counts of resources of a kind
+type SummaryRow struct {
+	Kind     string
+	Total    int
+	Orphaned int
+	Deleted  int
+}
+
+// NewCmdGCOrphans creates the command object
+func NewCmdGCOrphans() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "orphans",
+		Aliases: []string{"orphan"},
+		Short:   "Garbage collects PipelineRuns, TaskRuns and LighthouseJobs which are not linked to any PipelineActivity",
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "The namespace to garbage collect. Defaults to the current namespace")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "d", false, "Dry run mode. If enabled just list the resources that would be removed")
+	cmd.Flags().DurationVarP(&o.Age, "age", "a", time.Hour, "The minimum age of completed orphans to garbage collect")
+	cmd.Flags().DurationVarP(&o.IncompleteAge, "incomplete-age", "", 0, "If specified the minimum age of orphans which have not completed to garbage collect")
+	return cmd, o
+}
+
+// Validate validates the options and creates any missing clients
+func (o *Options) Validate() error {
+	var err error
+	o.JXClient, o.Namespace, err = jxclient.LazyCreateJXClientAndNamespace(o.JXClient, o.Namespace)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create jx client")
+	}
+	o.LHClient, err = activities.LazyCreateLHClient(o.LHClient)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the lighthouse client")
+	}
+	o.DynamicClient, err = kube.LazyCreateDynamicClient(o.DynamicClient)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the dynamic client")
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	return nil
+}
+
+// Run implements this command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+	ctx := context.TODO()
+	ns := o.Namespace
+
+	// lets not treat every resource as orphaned if the activities cannot be listed
+	paList, err := o.JXClient.JenkinsV1().PipelineActivities(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list PipelineActivities in namespace %s", ns)
+	}
+	links := newActivityLinks()
+	for i := range paList.Items {
+		links.add(&paList.Items[i])
+	}
+
+	// if lighthouse is not installed we cannot tell if the LighthouseJob owners of PipelineRuns exist
+	lhList, err := o.LHClient.LighthouseV1alpha1().LighthouseJobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to list LighthouseJobs in namespace %s", ns)
+		}
+		log.Logger().Warnf("cannot list LighthouseJobs in namespace %s so not checking the LighthouseJob owners of PipelineRuns: %s", ns, err.Error())
+		lhList = nil
+	}
+	lighthouseJobs := map[string]bool{}
+	if lhList != nil {
+		for i := range lhList.Items {
+			lighthouseJobs[lhList.Items[i].Name] = true
+		}
+	}
+
+	pipelineRuns, err := o.listRuns(ctx, run.PipelineRunResource)
+	if err != nil {
+		return err
+	}
+	taskRuns, err := o.listRuns(ctx, TaskRunResource)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]*SummaryRow{}
+	count := func(kind string) *SummaryRow {
+		row := counts[kind]
+		if row == nil {
+			row = &SummaryRow{Kind: kind}
+			counts[kind] = row
+		}
+		row.Total++
+		return row
+	}
+	o.Orphans = nil
+	now := time.Now()
+	addOrphan := func(r *gcpolicy.Resource, reason string) {
+		row := count(r.Kind)
+		if reason == "" {
+			return
+		}
+		row.Orphaned++
+		orphan := &Orphan{Resource: r, Reason: reason, Delete: o.shouldDelete(r, now)}
+		if orphan.Delete {
+			row.Deleted++
+		}
+		o.Orphans = append(o.Orphans, orphan)
+	}
+
+	if lhList != nil {
+		for i := range lhList.Items {
+			j := &lhList.Items[i]
+			r := gcpolicy.FromLighthouseJob(j)
+			addOrphan(r, links.orphanReason(firstString(j.Status.ActivityName, r.Labels[ActivityNameLabel]), r))
+		}
+	}
+
+	existingPipelineRuns := map[string]bool{}
+	for i := range pipelineRuns {
+		existingPipelineRuns[pipelineRuns[i].GetName()] = true
+	}
+	for i := range pipelineRuns {
+		u := &pipelineRuns[i]
+		r := gcpolicy.FromPipelineRun(v1alpha1.GCKindPipelineRun, u)
+		reason := ""
+		owner := ""
+		if lhList != nil {
+			owner = missingOwner(u, v1alpha1.GCKindLighthouseJob, lighthouseJobs)
+		}
+		if owner != "" {
+			reason = "owner LighthouseJob " + owner + " not found"
+		} else if !links.hasPipelineRun(u.GetName()) {
+			reason = links.orphanReason(r.Labels[ActivityNameLabel], r)
+		}
+		addOrphan(r, reason)
+	}
+
+	for i := range taskRuns {
+		u := &taskRuns[i]
+		r := gcpolicy.FromPipelineRun(KindTaskRun, u)
+		reason := ""
+		prName := r.Labels[PipelineRunLabel]
+		if owner := missingOwner(u, v1alpha1.GCKindPipelineRun, existingPipelineRuns); owner != "" {
+			reason = "owner PipelineRun " + owner + " not found"
+		} else if prName == "" && len(u.GetOwnerReferences()) == 0 {
+			reason = "no PipelineRun"
+		} else if prName != "" && !existingPipelineRuns[prName] {
+			reason = "PipelineRun " + prName + " not found"
+		}
+		addOrphan(r, reason)
+	}
+
+	var errs []error
+	for _, orphan := range o.Orphans {
+		if !orphan.Delete {
+			log.Logger().Debugf("keeping orphaned %s %s: %s", orphan.Kind, orphan.Name, orphan.Reason)
+			continue
+		}
+		if o.DryRun {
+			log.Logger().Infof("not deleting orphaned %s %s: %s", orphan.Kind, info(orphan.Name), orphan.Reason)
+			continue
+		}
+		err = o.deleteResource(ctx, orphan.Resource)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Logger().Warnf("failed to delete %s %s in namespace %s: %s", orphan.Kind, orphan.Name, ns, err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		log.Logger().Infof("deleted orphaned %s %s: %s", orphan.Kind, info(orphan.Name), orphan.Reason)
+	}
+
+	o.Summary = nil
+	for _, kind := range []string{v1alpha1.GCKindLighthouseJob, v1alpha1.GCKindPipelineRun, KindTaskRun} {
+		if row := counts[kind]; row != nil {
+			o.Summary = append(o.Summary, row)
+		}
+	}
+	o.renderSummary()
+	return errorutil.CombineErrors(errs...)
+}
+
+func (o *Options) shouldDelete(r *gcpolicy.Resource, now time.Time) bool {
+	age := now.Sub(r.Timestamp)
+	if r.Completed {
+		return age > o.Age
+	}
+	return o.IncompleteAge > 0 && age > o.IncompleteAge
+}
+
+func (o *Options) renderSummary() {
+	if len(o.Summary) == 0 {
+		log.Logger().Infof("no PipelineRuns, TaskRuns or LighthouseJobs found in namespace %s", info(o.Namespace))
+		return
+	}
+	deletedTitle := "DELETED"
+	if o.DryRun {
+		deletedTitle = "WOULD DELETE"
+	}
+	t := table.CreateTable(o.Out)
+	t.AddRow("KIND", "TOTAL", "ORPHANED", deletedTitle)
+	for _, row := range o.Summary {
+		t.AddRow(row.Kind, strconv.Itoa(row.Total), strconv.Itoa(row.Orphaned), strconv.Itoa(row.Deleted))
+	}
+	t.Render()
+}
+
+func (o *Options) listRuns(ctx context.Context, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	list, err := o.DynamicClient.Resource(resource).Namespace(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to list %s in namespace %s", resource.Resource, o.Namespace)
+	}
+	var answer []unstructured.Unstructured
+	for i := range list.Items {
+		if IsCreatedByLighthouse(list.Items[i].GetLabels()) {
+			answer = append(answer, list.Items[i])
+		}
+	}
+	return answer, nil
+}
+
+// IsCreatedByLighthouse returns true if the labels of a PipelineRun or TaskRun show it was created by lighthouse
+func IsCreatedByLighthouse(labels map[string]string) bool {
+	if labels[CreatedByLighthouseLabel] != "" {
+		return true
+	}
+	for k := range labels {
+		if strings.HasPrefix(k, lighthouseLabelPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Options) deleteResource(ctx context.Context, r *gcpolicy.Resource) error {
+	propagation := metav1.DeletePropagationBackground
+	deleteOptions := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	switch r.Kind {
+	case v1alpha1.GCKindLighthouseJob:
+		return o.LHClient.LighthouseV1alpha1().LighthouseJobs(r.Namespace).Delete(ctx, r.Name, deleteOptions)
+	case v1alpha1.GCKindPipelineRun:
+		return o.DynamicClient.Resource(run.PipelineRunResource).Namespace(r.Namespace).Delete(ctx, r.Name, deleteOptions)
+	case KindTaskRun:
+		return o.DynamicClient.Resource(TaskRunResource).Namespace(r.Namespace).Delete(ctx, r.Name, deleteOptions)
+	default:
+		return errors.Errorf("unsupported kind %s", r.Kind)
+	}
+}
+
+// missingOwner returns the name of the first owner of the given kind which does not exist
+func missingOwner(u *unstructured.Unstructured, kind string, existing map[string]bool) string {
+	for _, ref := range u.GetOwnerReferences() {
+		if ref.Kind == kind && !existing[ref.Name] {
+			return ref.Name
+		}
+	}
+	return ""
+}
+
+// activityLinks indexes the PipelineActivities so that resources can be linked to them
+type activityLinks struct {
+	names        map[string]bool
+	pipelineRuns map[string]bool
+	builds       map[string]bool
+}
+
+func newActivityLinks() *activityLinks {
+	return &activityLinks{
+		names:        map[string]bool{},
+		pipelineRuns: map[string]bool{},
+		builds:       map[string]bool{},
+	}
+}
+
+func (l *activityLinks) add(a *v1.PipelineActivity) {
+	l.names[a.Name] = true
+	if a.Labels == nil {
+		return
+	}
+	if prName := a.Labels[activities.PrLabel]; prName != "" {
+		l.pipelineRuns[prName] = true
+	}
+	if key := buildKey(a.Labels); key != "" {
+		l.builds[key] = true
+	}
+}
+
+func (l *activityLinks) hasPipelineRun(name string) bool {
+	return l.pipelineRuns[name]
+}
+
+// orphanReason returns the reason the resource is orphaned or an empty string if it is linked to an activity
+func (l *activityLinks) orphanReason(activityName string, r *gcpolicy.Resource) string {
+	if activityName != "" {
+		if l.names[activityName] {
+			return ""
+		}
+		return "PipelineActivity " + activityName + " not found"
+	}
+	key := buildKey(r.Labels)
+	if key != "" && l.builds[key] {
+		return ""
+	}
+	return "no PipelineActivity"
+}
+
+// buildKey returns the key of the lighthouse build labels or an empty string if there are none
+func buildKey(labels map[string]string) string {
+	values := []string{labels[gcpolicy.OrgLabel], labels[gcpolicy.RepoLabel], labels[gcpolicy.BranchLabel], labels[gcpolicy.ContextLabel], labels[BuildNumLabel]}
+	for _, v := range values {
+		if v == "" {
+			return ""
+		}
+	}
+	return strings.Join(values, "/")
+}
+
+func firstString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
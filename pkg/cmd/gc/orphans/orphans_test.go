@@ -0,0 +1,199 @@
+package orphans_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/activities"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/orphans"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/run"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	jxfake "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	"github.com/jenkins-x/lighthouse-client/pkg/apis/lighthouse/v1alpha1"
+	fakelh "github.com/jenkins-x/lighthouse-client/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedyn "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const ns = "jx"
+
+func TestGCOrphans(t *testing.T) {
+	ctx := context.TODO()
+	old := metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	recent := metav1.Time{Time: time.Now().Add(-time.Minute)}
+
+	jxClient := jxfake.NewSimpleClientset(&v1.PipelineActivity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-repo-main-1",
+			Namespace: ns,
+			Labels:    map[string]string{activities.PrLabel: "linked-run"},
+		},
+	})
+
+	lhClient := fakelh.NewSimpleClientset(
+		&v1alpha1.LighthouseJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "linked-job", Namespace: ns},
+			Status:     v1alpha1.LighthouseJobStatus{ActivityName: "org-repo-main-1", CompletionTime: &old},
+		},
+		&v1alpha1.LighthouseJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "orphan-job", Namespace: ns},
+			Status:     v1alpha1.LighthouseJobStatus{ActivityName: "org-repo-main-2", CompletionTime: &old},
+		},
+		&v1alpha1.LighthouseJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "running-orphan-job", Namespace: ns},
+			Status:     v1alpha1.LighthouseJobStatus{StartTime: old},
+		},
+	)
+
+	dynClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		run.PipelineRunResource: "PipelineRunList",
+		orphans.TaskRunResource: "TaskRunList",
+	})
+	lighthouseLabels := map[string]string{orphans.CreatedByLighthouseLabel: "true"}
+	runs := []struct {
+		resource       schema.GroupVersionResource
+		kind           string
+		name           string
+		completionTime metav1.Time
+		labels         map[string]string
+		owner          string
+	}{
+		{resource: run.PipelineRunResource, kind: "PipelineRun", name: "linked-run", completionTime: old, labels: lighthouseLabels},
+		{resource: run.PipelineRunResource, kind: "PipelineRun", name: "orphan-run", completionTime: old, labels: lighthouseLabels},
+		{resource: run.PipelineRunResource, kind: "PipelineRun", name: "recent-orphan-run", completionTime: recent, labels: lighthouseLabels},
+		{resource: run.PipelineRunResource, kind: "PipelineRun", name: "other-tool-run", completionTime: old},
+		{resource: orphans.TaskRunResource, kind: "TaskRun", name: "linked-task", completionTime: old, labels: map[string]string{orphans.PipelineRunLabel: "linked-run", orphans.CreatedByLighthouseLabel: "true"}},
+		{resource: orphans.TaskRunResource, kind: "TaskRun", name: "orphan-task", completionTime: old, owner: "deleted-run", labels: map[string]string{"lighthouse.jenkins-x.io/job": "pr-build"}},
+		{resource: orphans.TaskRunResource, kind: "TaskRun", name: "other-tool-task", completionTime: old, owner: "deleted-run"},
+	}
+	for _, r := range runs {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(schema.GroupVersionKind{Group: r.resource.Group, Version: r.resource.Version, Kind: r.kind})
+		u.SetName(r.name)
+		u.SetNamespace(ns)
+		u.SetLabels(r.labels)
+		if r.owner != "" {
+			u.SetOwnerReferences([]metav1.OwnerReference{{Kind: "PipelineRun", Name: r.owner}})
+		}
+		err := unstructured.SetNestedField(u.Object, r.completionTime.UTC().Format(time.RFC3339), "status", "completionTime")
+		require.NoError(t, err)
+		err = dynClient.Tracker().Create(r.resource, u, ns)
+		require.NoError(t, err)
+	}
+
+	out := &bytes.Buffer{}
+	_, o := orphans.NewCmdGCOrphans()
+	o.Namespace = ns
+	o.Out = out
+	o.JXClient = jxClient
+	o.LHClient = lhClient
+	o.DynamicClient = dynClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run")
+
+	jobs, err := lhClient.LighthouseV1alpha1().LighthouseJobs(ns).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for i := range jobs.Items {
+		names = append(names, jobs.Items[i].Name)
+	}
+	assert.ElementsMatch(t, []string{"linked-job", "running-orphan-job"}, names)
+
+	assertRemaining(t, dynClient, run.PipelineRunResource, "linked-run", "recent-orphan-run", "other-tool-run")
+	assertRemaining(t, dynClient, orphans.TaskRunResource, "linked-task", "other-tool-task")
+
+	var summary []orphans.SummaryRow
+	for _, row := range o.Summary {
+		summary = append(summary, *row)
+	}
+	assert.Equal(t, []orphans.SummaryRow{
+		{Kind: "LighthouseJob", Total: 3, Orphaned: 2, Deleted: 1},
+		{Kind: "PipelineRun", Total: 3, Orphaned: 2, Deleted: 1},
+		{Kind: "TaskRun", Total: 2, Orphaned: 1, Deleted: 1},
+	}, summary)
+	assert.Contains(t, out.String(), "ORPHANED")
+}
+
+func TestGCOrphansFailsWithoutActivities(t *testing.T) {
+	jxClient := jxfake.NewSimpleClientset()
+	jxClient.PrependReactor("list", "pipelineactivities", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "jenkins.io", Resource: "pipelineactivities"}, "")
+	})
+	dynClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		run.PipelineRunResource: "PipelineRunList",
+		orphans.TaskRunResource: "TaskRunList",
+	})
+
+	_, o := orphans.NewCmdGCOrphans()
+	o.Namespace = ns
+	o.Out = &bytes.Buffer{}
+	o.JXClient = jxClient
+	o.LHClient = fakelh.NewSimpleClientset()
+	o.DynamicClient = dynClient
+
+	err := o.Run()
+	require.Error(t, err, "should fail if the PipelineActivities cannot be listed")
+	t.Logf("got expected error %s\n", err.Error())
+}
+
+func TestGCOrphansWithoutLighthouseJobs(t *testing.T) {
+	old := metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	jxClient := jxfake.NewSimpleClientset(&v1.PipelineActivity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org-repo-main-1",
+			Namespace: ns,
+			Labels:    map[string]string{activities.PrLabel: "owned-run"},
+		},
+	})
+	lhClient := fakelh.NewSimpleClientset()
+	lhClient.PrependReactor("list", "lighthousejobs", func(_ k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "lighthouse.jenkins.io", Resource: "lighthousejobs"}, "")
+	})
+	dynClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		run.PipelineRunResource: "PipelineRunList",
+		orphans.TaskRunResource: "TaskRunList",
+	})
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: run.PipelineRunResource.Group, Version: run.PipelineRunResource.Version, Kind: "PipelineRun"})
+	u.SetName("owned-run")
+	u.SetNamespace(ns)
+	u.SetLabels(map[string]string{orphans.CreatedByLighthouseLabel: "true"})
+	u.SetOwnerReferences([]metav1.OwnerReference{{Kind: "LighthouseJob", Name: "some-job"}})
+	err := unstructured.SetNestedField(u.Object, old.UTC().Format(time.RFC3339), "status", "completionTime")
+	require.NoError(t, err)
+	err = dynClient.Tracker().Create(run.PipelineRunResource, u, ns)
+	require.NoError(t, err)
+
+	_, o := orphans.NewCmdGCOrphans()
+	o.Namespace = ns
+	o.Out = &bytes.Buffer{}
+	o.JXClient = jxClient
+	o.LHClient = lhClient
+	o.DynamicClient = dynClient
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run")
+
+	assert.Empty(t, o.Orphans, "should not report PipelineRuns as orphaned when the LighthouseJobs cannot be listed")
+	assertRemaining(t, dynClient, run.PipelineRunResource, "owned-run")
+}
+
+func assertRemaining(t *testing.T, dynClient *fakedyn.FakeDynamicClient, resource schema.GroupVersionResource, expected ...string) {
+	list, err := dynClient.Resource(resource).Namespace(ns).List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for i := range list.Items {
+		names = append(names, list.Items[i].GetName())
+	}
+	assert.ElementsMatch(t, expected, names, "remaining %s", resource.Resource)
+}
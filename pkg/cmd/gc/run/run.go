@@ -0,0 +1,269 @@
+package run
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/activities"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/gcpolicy"
+	jxc "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/errorutil"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jxclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	lhclient "github.com/jenkins-x/lighthouse-client/pkg/client/clientset/versioned"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	// PipelineRunResource the tekton PipelineRun resource
+	PipelineRunResource = schema.GroupVersionResource{
+		Group:    "tekton.dev",
+		Version:  "v1",
+		Resource: "pipelineruns",
+	}
+
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Garbage collects PipelineActivities, LighthouseJobs, PipelineRuns, Jobs and Pods using the rules of the garbage collection policy
+
+		The policy is loaded from .jx/gitops/gc-policy.yaml in the cluster repository. Each completed resource is garbage collected using the first rule which matches its kind, repository owner, repository name, branch, namespace and labels. Resources older than the maximum age of the rule are deleted along with resources which exceed the history limit of the rule for their repository, branch and context. The most recent resources up to the minimum keep of the rule are never deleted even if they are older than the maximum age.
+
+		If there is no policy file a default policy equivalent to the defaults of the gc activities, gc jobs and gc pods commands is used
+`)
+
+	cmdExample = templates.Examples(`
+		# garbage collect using the policy in the current directory
+		jx gitops gc run
+
+		# dry run mode
+		jx gitops gc run --dry-run
+`)
+)
+
+// Options the options for the command
+type Options struct {
+	Dir           string
+	PolicyFile    string
+	Namespace     string
+	DryRun        bool
+	Policy        *v1alpha1.GCPolicy
+	Decisions     []*gcpolicy.Decision
+	Summary       []*gcpolicy.SummaryRow
+	Out           io.Writer
+	KubeClient    kubernetes.Interface
+	JXClient      jxc.Interface
+	LHClient      lhclient.Interface
+	DynamicClient dynamic.Interface
+}
+
+// NewCmdGCRun creates the command object
+func NewCmdGCRun() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "run",
+		Short:   "Garbage collects pipeline resources using the rules of the garbage collection policy",
+		Long:    cmdLong,
+		Example: cmdExample,
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "", ".", "the directory of the cluster repository")
+	cmd.Flags().StringVarP(&o.PolicyFile, "policy", "", "", "the policy file. Defaults to .jx/gitops/gc-policy.yaml in the dir")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "The namespace to garbage collect. Defaults to the current namespace. Any namespaces used in the rules are also garbage collected")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "Dry run mode. If enabled just list the resources that would be removed")
+	return cmd, o
+}
+
+// Validate validates the options and creates any missing clients
+func (o *Options) Validate() error {
+	if o.PolicyFile == "" {
+		o.PolicyFile = filepath.Join(o.Dir, gcpolicy.PolicyPath)
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	var err error
+	if o.Policy == nil {
+		o.Policy, err = gcpolicy.LoadPolicy(o.PolicyFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load gc policy")
+		}
+	}
+	o.KubeClient, o.Namespace, err = kube.LazyCreateKubeClientAndNamespace(o.KubeClient, o.Namespace)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create kube client")
+	}
+	o.JXClient, err = jxclient.LazyCreateJXClient(o.JXClient)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create jx client")
+	}
+	o.LHClient, err = activities.LazyCreateLHClient(o.LHClient)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the lighthouse client")
+	}
+	o.DynamicClient, err = kube.LazyCreateDynamicClient(o.DynamicClient)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the dynamic client")
+	}
+	return nil
+}
+
+// Run implements this command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+	engine, err := gcpolicy.NewEngine(o.Policy)
+	if err != nil {
+		return errors.Wrapf(err, "invalid gc policy %s", o.PolicyFile)
+	}
+
+	ctx := context.TODO()
+	namespaces := []string{o.Namespace}
+	for _, ns := range engine.Namespaces() {
+		if ns != o.Namespace {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	var resources []*gcpolicy.Resource
+	for _, ns := range namespaces {
+		list, err := o.listResources(ctx, ns)
+		if err != nil {
+			return errors.Wrapf(err, "failed to list resources in namespace %s", ns)
+		}
+		resources = append(resources, list...)
+	}
+
+	o.Decisions = engine.Evaluate(resources, time.Now())
+
+	var errs []error
+	for _, d := range o.Decisions {
+		if !d.Delete {
+			continue
+		}
+		r := d.Resource
+		if o.DryRun {
+			log.Logger().Infof("not deleting %s %s in namespace %s as rule %s: %s", r.Kind, info(r.Name), r.Namespace, info(d.Rule), d.Reason)
+			continue
+		}
+		err = o.deleteResource(ctx, r)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Logger().Warnf("failed to delete %s %s in namespace %s: %s", r.Kind, r.Name, r.Namespace, err.Error())
+			errs = append(errs, err)
+			continue
+		}
+		log.Logger().Infof("deleted %s %s in namespace %s as rule %s: %s", r.Kind, info(r.Name), r.Namespace, info(d.Rule), d.Reason)
+	}
+
+	o.Summary = gcpolicy.Summarize(o.Decisions)
+	o.renderSummary()
+	return errorutil.CombineErrors(errs...)
+}
+
+func (o *Options) renderSummary() {
+	if len(o.Summary) == 0 {
+		log.Logger().Infof("no resources found to garbage collect")
+		return
+	}
+	deletedTitle := "DELETED"
+	if o.DryRun {
+		deletedTitle = "WOULD DELETE"
+	}
+	t := table.CreateTable(o.Out)
+	t.AddRow("RULE", "KIND", "KEPT", deletedTitle)
+	for _, row := range o.Summary {
+		t.AddRow(row.Rule, row.Kind, strconv.Itoa(row.Kept), strconv.Itoa(row.Deleted))
+	}
+	t.Render()
+}
+
+func (o *Options) listResources(ctx context.Context, ns string) ([]*gcpolicy.Resource, error) {
+	var answer []*gcpolicy.Resource
+
+	paList, err := o.JXClient.JenkinsV1().PipelineActivities(ns).List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to list PipelineActivities")
+	}
+	if paList != nil {
+		for i := range paList.Items {
+			answer = append(answer, gcpolicy.FromPipelineActivity(&paList.Items[i]))
+		}
+	}
+
+	lhList, err := o.LHClient.LighthouseV1alpha1().LighthouseJobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to list LighthouseJobs")
+	}
+	if lhList != nil {
+		for i := range lhList.Items {
+			answer = append(answer, gcpolicy.FromLighthouseJob(&lhList.Items[i]))
+		}
+	}
+
+	prList, err := o.DynamicClient.Resource(PipelineRunResource).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to list PipelineRuns")
+	}
+	if prList != nil {
+		for i := range prList.Items {
+			answer = append(answer, gcpolicy.FromPipelineRun(v1alpha1.GCKindPipelineRun, &prList.Items[i]))
+		}
+	}
+
+	jobList, err := o.KubeClient.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list Jobs")
+	}
+	for i := range jobList.Items {
+		answer = append(answer, gcpolicy.FromJob(&jobList.Items[i]))
+	}
+
+	podList, err := o.KubeClient.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list Pods")
+	}
+	for i := range podList.Items {
+		answer = append(answer, gcpolicy.FromPod(&podList.Items[i]))
+	}
+	return answer, nil
+}
+
+func (o *Options) deleteResource(ctx context.Context, r *gcpolicy.Resource) error {
+	switch r.Kind {
+	case v1alpha1.GCKindPipelineActivity:
+		return o.JXClient.JenkinsV1().PipelineActivities(r.Namespace).Delete(ctx, r.Name, *metav1.NewDeleteOptions(0))
+	case v1alpha1.GCKindLighthouseJob:
+		return o.LHClient.LighthouseV1alpha1().LighthouseJobs(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
+	case v1alpha1.GCKindPipelineRun:
+		return o.DynamicClient.Resource(PipelineRunResource).Namespace(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
+	case v1alpha1.GCKindJob:
+		propagation := metav1.DeletePropagationBackground
+		return o.KubeClient.BatchV1().Jobs(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	case v1alpha1.GCKindPod:
+		return o.KubeClient.CoreV1().Pods(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
+	default:
+		return errors.Errorf("unsupported kind %s", r.Kind)
+	}
+}
@@ -0,0 +1,117 @@
+package run_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/gc/run"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/gcpolicy"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	jxfake "github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned/fake"
+	fakelh "github.com/jenkins-x/lighthouse-client/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedyn "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGCRun(t *testing.T) {
+	ctx := context.TODO()
+	ns := "jx"
+	now := time.Now()
+
+	var activities []runtime.Object
+	addActivity := func(name, owner, branch string, age time.Duration, status v1.ActivityStatusType) {
+		activities = append(activities, &v1.PipelineActivity{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+				Labels: map[string]string{
+					gcpolicy.OrgLabel:    owner,
+					gcpolicy.RepoLabel:   "myrepo",
+					gcpolicy.BranchLabel: branch,
+				},
+			},
+			Spec: v1.PipelineActivitySpec{
+				GitOwner:           owner,
+				GitRepository:      "myrepo",
+				GitBranch:          branch,
+				CompletedTimestamp: &metav1.Time{Time: now.Add(-age)},
+				Status:             status,
+			},
+		})
+	}
+	addActivity("team-a-pr-old", "team-a", "PR-1", 48*time.Hour, v1.ActivityStatusTypeSucceeded)
+	addActivity("team-a-pr-new", "team-a", "PR-1", time.Hour, v1.ActivityStatusTypeSucceeded)
+	addActivity("team-a-pr-newer", "team-a", "PR-1", time.Minute, v1.ActivityStatusTypeFailed)
+	addActivity("team-a-pr-running", "team-a", "PR-1", 0, v1.ActivityStatusTypeRunning)
+	addActivity("team-b-pr", "team-b", "PR-1", 48*time.Hour, v1.ActivityStatusTypeSucceeded)
+	for i := 1; i <= 4; i++ {
+		addActivity(fmt.Sprintf("team-b-main-%d", i), "team-b", "main", time.Duration(i)*time.Hour, v1.ActivityStatusTypeSucceeded)
+	}
+
+	pods := []runtime.Object{
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "old-pod", Namespace: ns},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodSucceeded,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.Time{Time: now.Add(-2 * time.Hour)}}}},
+				},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "running-pod", Namespace: ns},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	}
+
+	jxClient := jxfake.NewSimpleClientset(activities...)
+	kubeClient := fake.NewSimpleClientset(pods...)
+	dynClient := fakedyn.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{run.PipelineRunResource: "PipelineRunList"})
+
+	out := &bytes.Buffer{}
+	_, o := run.NewCmdGCRun()
+	o.Dir = "testdata"
+	o.Namespace = ns
+	o.Out = out
+	o.KubeClient = kubeClient
+	o.JXClient = jxClient
+	o.LHClient = fakelh.NewSimpleClientset()
+	o.DynamicClient = dynClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run")
+
+	paList, err := jxClient.JenkinsV1().PipelineActivities(ns).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for i := range paList.Items {
+		names = append(names, paList.Items[i].Name)
+	}
+	assert.ElementsMatch(t, []string{"team-a-pr-newer", "team-a-pr-running", "team-b-pr", "team-b-main-1", "team-b-main-2"}, names)
+
+	podList, err := kubeClient.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, podList.Items, 1)
+	assert.Equal(t, "running-pod", podList.Items[0].Name)
+
+	summary := map[string][2]int{}
+	for _, row := range o.Summary {
+		summary[row.Rule+"/"+row.Kind] = [2]int{row.Kept, row.Deleted}
+	}
+	assert.Equal(t, map[string][2]int{
+		"pods/Pod":                    {1, 1},
+		"team-a-prs/PipelineActivity": {2, 2},
+		"releases/PipelineActivity":   {2, 2},
+		"<none>/PipelineActivity":     {1, 0},
+	}, summary)
+	assert.Contains(t, out.String(), "team-a-prs")
+}
@@ -0,0 +1,267 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/hash"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/resourcediff"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FormatText the plain text output format
+	FormatText = "text"
+	// FormatJSON the JSON output format
+	FormatJSON = "json"
+	// FormatMarkdown the markdown output format which is suitable for a pull request comment
+	FormatMarkdown = "markdown"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Displays the semantic differences between the kubernetes resources rendered into config-root at two git revisions.
+
+		Resources are matched by apiVersion, kind, namespace and name rather than by file path so renamed files are detected and annotations such as the hash annotations are ignored.
+`)
+
+	cmdExample = templates.Examples(`
+		# displays the changes between the pull request base and HEAD
+		%s helmfile diff
+
+		# generates a markdown pull request comment of the changes between 2 revisions
+		%s helmfile diff --from v1.2.3 --to HEAD --format markdown --output-file diff.md
+		%s pr comment --file diff.md
+	`)
+
+	formats = []string{FormatText, FormatJSON, FormatMarkdown}
+)
+
+// Options the options for the command
+type Options struct {
+	Dir               string
+	From              string
+	To                string
+	Format            string
+	OutputFile        string
+	Paths             []string
+	IgnoreAnnotations []string
+	Out               io.Writer
+	Changes           []resourcediff.ResourceChange
+}
+
+// NewCmdHelmfileDiff creates a command object for the command
+func NewCmdHelmfileDiff() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "diff",
+		Short:   "Displays the semantic differences between the rendered kubernetes resources at two git revisions",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName, rootcmd.BinaryName, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory of the cluster git repository")
+	cmd.Flags().StringVarP(&o.From, "from", "", "", "the git revision to compare from. If not specified uses $PULL_BASE_SHA or $PULL_BASE_REF and then HEAD~1")
+	cmd.Flags().StringVarP(&o.To, "to", "", "HEAD", "the git revision to compare to")
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatText, fmt.Sprintf("the output format. One of: %s", strings.Join(formats, ", ")))
+	cmd.Flags().StringVarP(&o.OutputFile, "output-file", "o", "", "the file to write the diff to. If not specified the diff is written to the console")
+	cmd.Flags().StringArrayVarP(&o.Paths, "path", "p", []string{"config-root/cluster", "config-root/namespaces"}, "the directories in the git repository to compare")
+	cmd.Flags().StringArrayVarP(&o.IgnoreAnnotations, "ignore-annotation", "", []string{hash.DefaultAnnotation}, "the annotations to ignore when comparing resources")
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	if o.From == "" {
+		o.From = os.Getenv("PULL_BASE_SHA")
+	}
+	if o.From == "" {
+		ref := os.Getenv("PULL_BASE_REF")
+		if ref != "" {
+			o.From = "origin/" + ref
+		}
+	}
+	if o.From == "" {
+		o.From = "HEAD~1"
+	}
+	if o.To == "" {
+		o.To = "HEAD"
+	}
+	if o.Format == "" {
+		o.Format = FormatText
+	}
+	found := false
+	for _, f := range formats {
+		if f == o.Format {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.Errorf("unknown format %s. Supported formats: %s", o.Format, strings.Join(formats, ", "))
+	}
+	if len(o.Paths) == 0 {
+		o.Paths = []string{"config-root/cluster", "config-root/namespaces"}
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+
+	repo, err := git.PlainOpenWithOptions(o.Dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return errors.Wrapf(err, "failed to open git repository %s", o.Dir)
+	}
+	from, err := resourcediff.LoadResourcesAtRef(repo, o.From, o.Paths)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load resources at %s", o.From)
+	}
+	to, err := resourcediff.LoadResourcesAtRef(repo, o.To, o.Paths)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load resources at %s", o.To)
+	}
+	o.Changes = resourcediff.Diff(from, to, o.IgnoreAnnotations)
+
+	var text string
+	switch o.Format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(o.Changes, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal changes to JSON")
+		}
+		text = string(data) + "\n"
+	case FormatMarkdown:
+		text = ToMarkdown(o.Changes)
+	default:
+		text = ToText(o.Changes, o.From, o.To)
+	}
+
+	if o.OutputFile == "" {
+		_, err = io.WriteString(o.Out, text)
+		return err
+	}
+	err = os.WriteFile(o.OutputFile, []byte(text), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", o.OutputFile)
+	}
+	log.Logger().Infof("saved diff of %d resources to %s", len(o.Changes), info(o.OutputFile))
+	return nil
+}
+
+// ToText converts the changes to plain text
+func ToText(changes []resourcediff.ResourceChange, from, to string) string {
+	w := &strings.Builder{}
+	fmt.Fprintf(w, "%s between %s and %s\n", summary(changes), from, to)
+	for i := range changes {
+		c := &changes[i]
+		w.WriteString("\n")
+		switch c.Status {
+		case resourcediff.StatusAdded:
+			fmt.Fprintf(w, "+ %s\n    %s\n", c.Key.String(), c.ToPath)
+		case resourcediff.StatusRemoved:
+			fmt.Fprintf(w, "- %s\n    %s\n", c.Key.String(), c.FromPath)
+		default:
+			fmt.Fprintf(w, "~ %s\n", c.Key.String())
+			if c.Renamed() {
+				fmt.Fprintf(w, "    renamed %s => %s\n", c.FromPath, c.ToPath)
+			}
+			for _, f := range c.Fields {
+				fmt.Fprintf(w, "    %s: %s => %s\n", f.Path, formatValue(f.From), formatValue(f.To))
+			}
+		}
+	}
+	return w.String()
+}
+
+// ToMarkdown converts the changes to markdown suitable for a pull request comment
+func ToMarkdown(changes []resourcediff.ResourceChange) string {
+	w := &strings.Builder{}
+	w.WriteString("### Kubernetes resource changes\n\n")
+	if len(changes) == 0 {
+		w.WriteString("No changes to kubernetes resources\n")
+		return w.String()
+	}
+	fmt.Fprintf(w, "%s\n\n", summary(changes))
+	w.WriteString("| Status | Kind | Namespace | Name | File |\n")
+	w.WriteString("| --- | --- | --- | --- | --- |\n")
+	for i := range changes {
+		c := &changes[i]
+		path := c.ToPath
+		if path == "" {
+			path = c.FromPath
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %s | `%s` |\n", c.Status, c.Key.Kind, c.Key.Namespace, c.Key.Name, path)
+	}
+
+	for i := range changes {
+		c := &changes[i]
+		if len(c.Fields) == 0 && !c.Renamed() {
+			continue
+		}
+		fmt.Fprintf(w, "\n<details>\n<summary>%s</summary>\n\n", c.Key.String())
+		if c.Renamed() {
+			fmt.Fprintf(w, "renamed `%s` to `%s`\n\n", c.FromPath, c.ToPath)
+		}
+		if len(c.Fields) > 0 {
+			w.WriteString("| Field | From | To |\n")
+			w.WriteString("| --- | --- | --- |\n")
+			for _, f := range c.Fields {
+				fmt.Fprintf(w, "| `%s` | %s | %s |\n", f.Path, markdownValue(f.From), markdownValue(f.To))
+			}
+		}
+		w.WriteString("\n</details>\n")
+	}
+	return w.String()
+}
+
+func summary(changes []resourcediff.ResourceChange) string {
+	counts := map[resourcediff.Status]int{}
+	for i := range changes {
+		counts[changes[i].Status]++
+	}
+	return fmt.Sprintf("%d added, %d removed, %d changed, %d renamed", counts[resourcediff.StatusAdded], counts[resourcediff.StatusRemoved], counts[resourcediff.StatusChanged], counts[resourcediff.StatusRenamed])
+}
+
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func markdownValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	text := strings.ReplaceAll(formatValue(value), "|", "\\|")
+	return "`" + text + "`"
+}
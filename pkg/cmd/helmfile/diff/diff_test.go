@@ -0,0 +1,100 @@
+package diff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/diff"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/resourcediff"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelmfileDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repo, err := git.PlainInit(tmpDir, false)
+	require.NoError(t, err, "failed to init git repository")
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(srcDir, message string) {
+		err := os.RemoveAll(filepath.Join(tmpDir, "config-root"))
+		require.NoError(t, err)
+		err = files.CopyDirOverwrite(srcDir, filepath.Join(tmpDir, "config-root"))
+		require.NoError(t, err, "failed to copy %s", srcDir)
+		_, err = wt.Add(".")
+		require.NoError(t, err)
+		// lets make sure removed files are staged too
+		status, err := wt.Status()
+		require.NoError(t, err)
+		for path, s := range status {
+			if s.Worktree == git.Deleted {
+				_, err = wt.Remove(path)
+				require.NoError(t, err)
+			}
+		}
+		_, err = wt.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err, "failed to commit %s", message)
+	}
+	commit(filepath.Join("testdata", "before"), "before")
+	commit(filepath.Join("testdata", "after"), "after")
+
+	_, o := diff.NewCmdHelmfileDiff()
+	o.Dir = tmpDir
+	o.Format = diff.FormatJSON
+	buf := &bytes.Buffer{}
+	o.Out = buf
+	err = o.Run()
+	require.NoError(t, err, "failed to run diff")
+
+	t.Logf("generated %s\n", buf.String())
+
+	var changes []resourcediff.ResourceChange
+	err = json.Unmarshal(buf.Bytes(), &changes)
+	require.NoError(t, err, "failed to parse JSON output")
+
+	statuses := map[string]resourcediff.Status{}
+	for i := range changes {
+		statuses[changes[i].Key.Name] = changes[i].Status
+	}
+	assert.Equal(t, map[string]resourcediff.Status{
+		"cheese":  resourcediff.StatusAdded,
+		"old":     resourcediff.StatusRemoved,
+		"myapp":   resourcediff.StatusChanged,
+		"renamed": resourcediff.StatusRenamed,
+	}, statuses, "resource changes and no change for the hash annotation only change")
+
+	for i := range changes {
+		c := &changes[i]
+		if c.Key.Name == "myapp" {
+			assert.Equal(t, []resourcediff.FieldChange{
+				{
+					Path: "spec.replicas",
+					From: float64(1),
+					To:   float64(2),
+				},
+				{
+					Path: "spec.template.spec.containers[0].image",
+					From: "myapp:1.0.0",
+					To:   "myapp:1.1.0",
+				},
+			}, c.Fields, "field changes for %s", c.Key.String())
+		}
+	}
+
+	o.Format = diff.FormatMarkdown
+	buf.Reset()
+	err = o.Run()
+	require.NoError(t, err, "failed to run diff")
+	assert.Contains(t, buf.String(), "| `spec.replicas` | `1` | `2` |", "markdown output")
+}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/move"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// CRDNeed a release which creates custom resources defined by the CRD of another release which it does not need
+type CRDNeed struct {
+	// Release the namespace/name of the release which creates the custom resources
+	Release string
+
+	// Provider the namespace/name of the release which contains the CRD
+	Provider string
+
+	// Kind the group/kind of the custom resource
+	Kind string
+}
+
+// crdConsumer a release which creates a resource of a kind which may be defined by a CRD
+type crdConsumer struct {
+	release string
+	kind    string
+}
+
+// findMissingCRDNeeds finds the releases in the config-root directory which create custom resources defined by the CRDs
+// of another release which they do not need either directly or transitively
+func (o *Options) findMissingCRDNeeds(g *helmfiles.ReleaseGraph) ([]CRDNeed, error) {
+	dir := filepath.Join(o.Dir, o.ConfigRootPath)
+	exists, err := files.DirExists(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if dir exists %s", dir)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	pathNames := map[string][]string{}
+	for _, key := range g.Keys() {
+		n := g.Nodes[key]
+		if n.Missing {
+			continue
+		}
+		_, chartName := chartrepos.SplitChartName(n.Chart)
+		chartName = path.Base(chartName)
+		pathNames[n.Name] = append(pathNames[n.Name], key)
+		pathName := move.PathName(chartName, n.Name)
+		if pathName != n.Name {
+			pathNames[pathName] = append(pathNames[pathName], key)
+		}
+	}
+
+	providers := map[string]string{}
+	var consumers []crdConsumer
+	modifyFn := func(node *yaml.RNode, p string) (bool, error) {
+		release := releaseOf(g, pathNames, node, dir, p)
+		if release == "" {
+			return false, nil
+		}
+		kind := kyamls.GetKind(node, p)
+		if kyamls.IsCustomResourceDefinition(kind) {
+			group := kyamls.GetStringField(node, p, "spec", "group")
+			crdKind := kyamls.GetStringField(node, p, "spec", "names", "kind")
+			key := group + "/" + crdKind
+			if providers[key] == "" {
+				providers[key] = release
+			}
+			return false, nil
+		}
+		group, _, found := strings.Cut(kyamls.GetAPIVersion(node, p), "/")
+		if found {
+			consumers = append(consumers, crdConsumer{release: release, kind: group + "/" + kind})
+		}
+		return false, nil
+	}
+	err = kyamls.ModifyFiles(dir, modifyFn, kyamls.Filter{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to walk the resources in dir %s", dir)
+	}
+
+	var answer []CRDNeed
+	found := map[string]bool{}
+	for _, c := range consumers {
+		provider := providers[c.kind]
+		if provider == "" || provider == c.release || found[c.release+" "+provider] {
+			continue
+		}
+		if g.DependsOn(c.release, provider) {
+			continue
+		}
+		found[c.release+" "+provider] = true
+		answer = append(answer, CRDNeed{Release: c.release, Provider: provider, Kind: c.kind})
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		if answer[i].Release != answer[j].Release {
+			return answer[i].Release < answer[j].Release
+		}
+		return answer[i].Provider < answer[j].Provider
+	})
+	return answer, nil
+}
+
+// releaseOf returns the key of the release which generated the resource using the helm release annotations if they are
+// present or the directory the resource was moved into by 'helmfile move'
+func releaseOf(g *helmfiles.ReleaseGraph, pathNames map[string][]string, node *yaml.RNode, dir, p string) string {
+	annotations := node.GetAnnotations()
+	name := annotations[move.HelmReleaseNameAnnotation]
+	ns := annotations[move.HelmReleaseNameSpaceAnnotation]
+	if name != "" && ns != "" && g.Nodes[ns+"/"+name] != nil {
+		return ns + "/" + name
+	}
+
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	pathName := ""
+	switch {
+	case len(parts) >= 4 && (parts[0] == "customresourcedefinitions" || parts[0] == "namespaces"):
+		ns, pathName = parts[1], parts[2]
+	case len(parts) >= 5 && parts[0] == "cluster" && parts[1] == "resources":
+		ns, pathName = parts[2], parts[3]
+	default:
+		return ""
+	}
+	keys := pathNames[pathName]
+	for _, key := range keys {
+		if g.Nodes[key].Namespace == ns {
+			return key
+		}
+	}
+	if len(keys) == 1 {
+		return keys[0]
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FormatDOT outputs the graph in the graphviz DOT format
+	FormatDOT = "dot"
+	// FormatMermaid outputs the graph as a mermaid flowchart
+	FormatMermaid = "mermaid"
+	// FormatMarkdown outputs the graph as a mermaid flowchart in a markdown code block
+	FormatMarkdown = "markdown"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Generates the dependency graph of the releases in all the nested helmfiles
+
+		The graph is built from the 'needs' of each release. Any needs which do not refer to a known release or which form a cycle are reported.
+
+		If the kubernetes resources have been generated into the config-root directory then releases which create custom resources
+		defined by the CRDs of another release without needing that release are also reported and shown in the graph.
+`)
+
+	cmdExample = templates.Examples(`
+		# displays the release graph as a mermaid flowchart
+		%s helmfile graph
+
+		# generates the release graph in the DOT format
+		%[1]s helmfile graph --format dot --out docs/releases.dot
+
+		# fail if there are any cycles, unknown needs or missing needs of CRDs
+		%[1]s helmfile graph --fail
+	`)
+
+	formats = []string{FormatMermaid, FormatDOT, FormatMarkdown}
+)
+
+// Options the options for the command
+type Options struct {
+	options.BaseOptions
+	Dir            string
+	Helmfile       string
+	Namespace      string
+	ConfigRootPath string
+	Format         string
+	OutFile        string
+	Fail           bool
+	Helmfiles      []helmfiles.Helmfile
+	Out            io.Writer
+
+	// Graph the generated release graph
+	Graph *helmfiles.ReleaseGraph
+
+	// Cycles the cycles of needs between releases
+	Cycles [][]string
+
+	// MissingCRDNeeds the releases which consume custom resources of releases they do not need
+	MissingCRDNeeds []CRDNeed
+}
+
+// NewCmdHelmfileGraph creates a command object for the command
+func NewCmdHelmfileGraph() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "graph",
+		Short:   "Generates the dependency graph of the releases in the helmfiles",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.BaseOptions.AddBaseFlags(cmd)
+
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory that contains the helmfile.yaml and helmfiles directory")
+	cmd.Flags().StringVarP(&o.Helmfile, "helmfile", "", "", "the helmfile to graph. If not specified defaults to 'helmfile.yaml' in the dir")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "", "jx", "the default namespace of releases which are not in a nested helmfile folder")
+	cmd.Flags().StringVarP(&o.ConfigRootPath, "config-root", "", "config-root", "the folder name containing the generated kubernetes resources used to find the consumers of CRDs")
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatMermaid, "the output format. Supported values: "+strings.Join(formats, ", "))
+	cmd.Flags().StringVarP(&o.OutFile, "out", "o", "", "the file to write the graph to. If not specified the graph is written to the terminal")
+	cmd.Flags().BoolVarP(&o.Fail, "fail", "", false, "returns a non zero exit code if there are any cycles, unknown needs or missing needs of CRDs")
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	err := o.BaseOptions.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+	if stringhelpers.StringArrayIndex(formats, o.Format) < 0 {
+		return options.InvalidOption("format", o.Format, formats)
+	}
+	if o.Dir == "" {
+		o.Dir = "."
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.Helmfiles == nil {
+		o.Helmfiles, err = helmfiles.GatherHelmfiles(o.Helmfile, o.Dir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to gather helmfiles")
+		}
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	o.Graph, err = helmfiles.NewReleaseGraph(o.Helmfiles, o.Namespace)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the release graph")
+	}
+	o.Cycles = o.Graph.Cycles()
+	o.MissingCRDNeeds, err = o.findMissingCRDNeeds(o.Graph)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the consumers of CRDs")
+	}
+	for _, c := range o.MissingCRDNeeds {
+		o.Graph.AddEdge(c.Release, c.Provider, helmfiles.EdgeCRD)
+	}
+
+	err = o.writeGraph()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range o.Graph.Dangling {
+		log.Logger().Warnf("release %s needs %s which is not a known release", info(d.Release), info(d.Need))
+	}
+	for _, cycle := range o.Cycles {
+		log.Logger().Warnf("cyclic needs: %s", info(strings.Join(cycle, " -> ")))
+	}
+	for _, c := range o.MissingCRDNeeds {
+		log.Logger().Warnf("release %s creates %s resources defined by the CRD of release %s but does not need it", info(c.Release), info(c.Kind), info(c.Provider))
+	}
+
+	count := len(o.Graph.Dangling) + len(o.Cycles) + len(o.MissingCRDNeeds)
+	if count > 0 && o.Fail {
+		return errors.Errorf("found %d problems with the needs of the releases", count)
+	}
+	return nil
+}
+
+func (o *Options) writeGraph() error {
+	buf := &bytes.Buffer{}
+	var err error
+	switch o.Format {
+	case FormatDOT:
+		err = o.Graph.WriteDOT(buf)
+	case FormatMarkdown:
+		buf.WriteString("```mermaid\n")
+		err = o.Graph.WriteMermaid(buf)
+		buf.WriteString("```\n")
+	default:
+		err = o.Graph.WriteMermaid(buf)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate the %s graph", o.Format)
+	}
+
+	if o.OutFile == "" {
+		_, err = o.Out.Write(buf.Bytes())
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(o.OutFile), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir for %s", o.OutFile)
+	}
+	err = os.WriteFile(o.OutFile, buf.Bytes(), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", o.OutFile)
+	}
+	log.Logger().Infof("saved the release graph to %s", info(o.OutFile))
+	return nil
+}
@@ -0,0 +1,48 @@
+package graph_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/graph"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelmfileGraph(t *testing.T) {
+	for _, format := range []string{graph.FormatMermaid, graph.FormatDOT} {
+		_, o := graph.NewCmdHelmfileGraph()
+		o.Dir = filepath.Join("testdata", "sample")
+		o.Format = format
+		o.OutFile = filepath.Join(t.TempDir(), "graph."+format)
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run for format %s", format)
+
+		testhelpers.AssertTextFilesEqual(t, filepath.Join("testdata", "sample", "expected."+format), o.OutFile, "generated "+format+" graph")
+
+		assert.Equal(t, []helmfiles.DanglingNeed{{Release: "jx/lighthouse", Need: "jx/jx-pipelines-visualizer"}}, o.Graph.Dangling, "dangling needs")
+		assert.Equal(t, [][]string{{"jx/jx-build-controller", "jx/jx-preview", "jx/jx-build-controller"}}, o.Cycles, "cycles")
+		assert.Equal(t, []graph.CRDNeed{
+			{
+				Release:  "jx/jx-ingress",
+				Provider: "cert-manager/cert-manager",
+				Kind:     "cert-manager.io/Certificate",
+			},
+		}, o.MissingCRDNeeds, "missing CRD needs")
+	}
+}
+
+func TestHelmfileGraphFail(t *testing.T) {
+	_, o := graph.NewCmdHelmfileGraph()
+	o.Dir = filepath.Join("testdata", "sample")
+	o.Out = &bytes.Buffer{}
+	o.Fail = true
+
+	err := o.Run()
+	require.Error(t, err, "should fail due to the problems with the needs")
+	assert.Contains(t, err.Error(), "found 3 problems")
+}
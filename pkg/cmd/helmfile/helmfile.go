@@ -3,7 +3,12 @@ package helmfile
 import (
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/add"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/deletecmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/diff"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/graph"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/migrate"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/move"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/outdated"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/promote"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/report"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/resolve"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/status"
@@ -29,7 +34,12 @@ func NewCmdHelmfile() *cobra.Command {
 	}
 	command.AddCommand(cobras.SplitCommand(add.NewCmdHelmfileAdd()))
 	command.AddCommand(cobras.SplitCommand(deletecmd.NewCmdHelmfileDelete()))
+	command.AddCommand(cobras.SplitCommand(diff.NewCmdHelmfileDiff()))
+	command.AddCommand(cobras.SplitCommand(graph.NewCmdHelmfileGraph()))
+	command.AddCommand(cobras.SplitCommand(migrate.NewCmdHelmfileMigrate()))
 	command.AddCommand(cobras.SplitCommand(move.NewCmdHelmfileMove()))
+	command.AddCommand(cobras.SplitCommand(outdated.NewCmdHelmfileOutdated()))
+	command.AddCommand(cobras.SplitCommand(promote.NewCmdHelmfilePromote()))
 	command.AddCommand(cobras.SplitCommand(report.NewCmdHelmfileReport()))
 	command.AddCommand(cobras.SplitCommand(resolve.NewCmdHelmfileResolve()))
 	command.AddCommand(cobras.SplitCommand(status.NewCmdHelmfileStatus()))
@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/migrations"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Lists or applies the pending migrations of the helmfiles in the cluster git repository.
+
+		Applied migrations are recorded in .jx/gitops/migrations.yaml so that each migration only runs once.
+		Version streams can contribute their own declarative migrations via a migrations.yaml file in the version stream.
+`)
+
+	cmdExample = templates.Examples(`
+		# lists the migrations and whether they have been applied
+		%s helmfile migrate --list
+
+		# shows which migrations would be applied to which helmfiles
+		%s helmfile migrate --dry-run
+
+		# applies any pending migrations
+		%s helmfile migrate
+	`)
+)
+
+// Options the options for the command
+type Options struct {
+	versionstreamer.Options
+	Helmfile                string
+	KptBinary               string
+	List                    bool
+	DryRun                  bool
+	AddEnvironmentPipelines bool
+	Gitter                  gitclient.Interface
+	Runner                  *migrations.Runner
+}
+
+// NewCmdHelmfileMigrate creates a command object for the command
+func NewCmdHelmfileMigrate() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "migrate",
+		Short:   "Lists or applies the pending migrations of the helmfiles",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName, rootcmd.BinaryName, rootcmd.BinaryName),
+		Aliases: []string{"migration", "migrations"},
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.Options.AddFlags(cmd)
+	cmd.Flags().StringVarP(&o.Helmfile, "helmfile", "", "", "the helmfile to migrate. If not specified defaults to 'helmfile.yaml' in the dir")
+	cmd.Flags().BoolVarP(&o.List, "list", "l", false, "lists the migrations and whether they have been applied")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "if enabled just log which migrations would be applied to which helmfiles without modifying any files")
+	cmd.Flags().BoolVarP(&o.AddEnvironmentPipelines, "add-environment-pipelines", "", false, "adds the environment pipelines to the .lighthouse folder if they are missing")
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	err := o.Options.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate version stream options")
+	}
+	if o.Helmfile == "" {
+		o.Helmfile = "helmfile.yaml"
+	}
+	if o.Gitter == nil {
+		o.Gitter = cli.NewCLIClient("", o.CommandRunner)
+	}
+	if o.Runner == nil {
+		o.Runner, err = migrations.NewRunner(&migrations.Context{
+			Dir:                     o.Dir,
+			Requirements:            o.Requirements,
+			Resolver:                o.Resolver,
+			CommandRunner:           o.CommandRunner,
+			Gitter:                  o.Gitter,
+			KptBinary:               o.KptBinary,
+			AddEnvironmentPipelines: o.AddEnvironmentPipelines,
+		}, o.VersionStreamDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load migrations")
+		}
+	}
+	o.Runner.DryRun = o.DryRun
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+
+	if o.List {
+		return o.listMigrations()
+	}
+
+	helmfileList, err := helmfiles.GatherHelmfiles(o.Helmfile, o.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to gather helmfiles")
+	}
+
+	for _, helmfile := range helmfileList {
+		path := helmfile.Filepath
+		helmStates, err := helmfiles.LoadHelmfile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load helmfile %s", path)
+		}
+
+		var ids []string
+		for _, helmState := range helmStates {
+			applied, err := o.Runner.Run(helmState)
+			if err != nil {
+				return errors.Wrapf(err, "failed to migrate helmfile %s", path)
+			}
+			ids = append(ids, applied...)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if o.DryRun {
+			log.Logger().Infof("would apply migrations %s to %s", info(strings.Join(ids, ", ")), info(path))
+			continue
+		}
+		err = helmfiles.SaveHelmfile(path, helmStates)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save helmfile %s", path)
+		}
+		log.Logger().Infof("applied migrations %s to %s", info(strings.Join(ids, ", ")), info(path))
+	}
+	return o.Runner.Save()
+}
+
+func (o *Options) listMigrations() error {
+	t := table.CreateTable(os.Stdout)
+	t.AddRow("ID", "SOURCE", "STATUS", "DESCRIPTION")
+	for i := range o.Runner.Migrations {
+		m := &o.Runner.Migrations[i]
+		status := "pending"
+		if m.Repeatable {
+			status = "repeatable"
+		} else if o.Runner.Record.IsApplied(m.ID) {
+			status = "applied"
+		}
+		t.AddRow(m.ID, m.Source, status, m.Description)
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,79 @@
+package migrate_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/migrate"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/migrations"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelmfileMigrate(t *testing.T) {
+	srcDir := "testdata"
+	require.DirExists(t, srcDir)
+
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite(srcDir, tmpDir)
+	require.NoError(t, err, "failed to copy test files at %s to %s", srcDir, tmpDir)
+
+	helmfilePath := filepath.Join(tmpDir, "helmfiles", "jx", "helmfile.yaml")
+	recordFile := filepath.Join(tmpDir, migrations.MigrationsFile)
+
+	newOptions := func() *migrate.Options {
+		_, o := migrate.NewCmdHelmfileMigrate()
+		runner := &fakerunner.FakeRunner{}
+		o.Dir = tmpDir
+		o.CommandRunner = runner.Run
+		o.Gitter = cli.NewCLIClient("", runner.Run)
+		return o
+	}
+
+	// dry run should not modify anything
+	o := newOptions()
+	o.DryRun = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run dry run")
+
+	testhelpers.AssertTextFilesEqual(t, filepath.Join(srcDir, "helmfiles", "jx", "helmfile.yaml"), helmfilePath, "dry run should not modify the helmfile")
+	assert.NoFileExists(t, recordFile, "dry run should not record migrations")
+
+	o = newOptions()
+	err = o.Run()
+	require.NoError(t, err, "failed to run migrations")
+
+	testhelpers.AssertTextFilesEqual(t, filepath.Join(tmpDir, "expected-jx-helmfile.yaml"), helmfilePath, "migrated helmfile")
+	require.FileExists(t, recordFile, "should have recorded the applied migrations")
+
+	record, err := migrations.LoadMigrations(recordFile)
+	require.NoError(t, err, "failed to load %s", recordFile)
+	assert.True(t, record.IsApplied("acme-charts"), "should have applied the version stream migration")
+	assert.True(t, record.IsApplied("jenkins-x-charts"), "should have applied the built in migration")
+	assert.False(t, record.IsApplied("jx-build-controller"), "should not record repeatable migrations")
+	assert.False(t, record.IsApplied("chartmuseum-chart"), "should not record migrations which did not match")
+
+	// lets reintroduce an old chart and verify the applied migration is not run again
+	err = os.WriteFile(helmfilePath, []byte(`namespace: jx
+releases:
+- chart: oldacme/cheese
+  name: cheese
+`), 0o600)
+	require.NoError(t, err, "failed to save %s", helmfilePath)
+
+	o = newOptions()
+	err = o.Run()
+	require.NoError(t, err, "failed to rerun migrations")
+
+	helmStates, err := helmfiles.LoadHelmfile(helmfilePath)
+	require.NoError(t, err, "failed to load %s", helmfilePath)
+	require.Len(t, helmStates, 1)
+	require.Len(t, helmStates[0].Releases, 1)
+	assert.Equal(t, "oldacme/cheese", helmStates[0].Releases[0].Chart, "should not have reapplied the migration")
+}
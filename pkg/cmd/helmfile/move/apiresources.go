@@ -0,0 +1,77 @@
+package move
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apiresources"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/discovery"
+)
+
+// APIResourcesFile the default path of the cached API resources file relative to the cluster repository
+var APIResourcesFile = apiresources.DefaultFile
+
+// discoverNamespacedKinds populates the namespaced kinds from the cached API resources file, the cluster if available
+// and any CRDs already in the output directory
+func (o *Options) discoverNamespacedKinds() error {
+	var err error
+	o.NamespacedKind, err = apiresources.NamespacedKinds(o.APIResourcesFile)
+	if err != nil {
+		return err
+	}
+
+	if !kube.IsNoKubernetes() {
+		err = o.discoverClusterResources()
+		if err != nil {
+			return err
+		}
+	} else if o.RefreshAPIResources {
+		return errors.Errorf("cannot refresh the API resources file %s without access to a cluster", o.APIResourcesFile)
+	}
+
+	return apiresources.AddCustomResourceDefinitions(o.NamespacedKind, o.CustomResourceDefinitionsDir)
+}
+
+func (o *Options) discoverClusterResources() error {
+	var err error
+	o.KubeClient, err = kube.LazyCreateKubeClient(o.KubeClient)
+	if err != nil {
+		if o.RefreshAPIResources {
+			return errors.Wrapf(err, "failed to create k8s client to refresh the API resources file %s", o.APIResourcesFile)
+		}
+		log.Logger().Errorf("Failed to create k8s client: %v", err)
+		return nil
+	}
+	apiResourceLists, err := discovery.ServerPreferredResources(o.KubeClient.Discovery())
+	if err != nil {
+		log.Logger().Errorf("Failed to fetch api resources: %v", err)
+	}
+	for i := range apiResourceLists {
+		resources := apiResourceLists[i].APIResources
+		for j := range resources {
+			o.NamespacedKind[resources[j].Kind] = resources[j].Namespaced
+		}
+	}
+	if !o.RefreshAPIResources {
+		return nil
+	}
+	if len(apiResourceLists) == 0 {
+		return errors.Errorf("no API resources discovered so cannot refresh the API resources file %s", o.APIResourcesFile)
+	}
+	err = os.MkdirAll(filepath.Dir(o.APIResourcesFile), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir for %s", o.APIResourcesFile)
+	}
+	err = yamls.SaveFile(apiresources.ToAPIResources(apiResourceLists), o.APIResourcesFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save API resources file %s", o.APIResourcesFile)
+	}
+	log.Logger().Infof("refreshed the API resources file %s", termcolor.ColorInfo(o.APIResourcesFile))
+	return nil
+}
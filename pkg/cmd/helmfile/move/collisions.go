@@ -0,0 +1,131 @@
+package move
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CollisionPolicyWarn logs a warning for each collision and writes all the resources
+	CollisionPolicyWarn = "warn"
+
+	// CollisionPolicyFail fails the command if any resources collide
+	CollisionPolicyFail = "fail"
+
+	// CollisionPolicyKeepFirst only writes the resource from the first release which generated it
+	CollisionPolicyKeepFirst = "keep-first"
+
+	// CollisionPolicyDedupeIdentical only writes the first resource if the colliding resources are byte-identical
+	// and fails otherwise
+	CollisionPolicyDedupeIdentical = "dedupe-identical"
+)
+
+var (
+	// CollisionPolicies the possible collision policies
+	CollisionPolicies = []string{CollisionPolicyWarn, CollisionPolicyFail, CollisionPolicyKeepFirst, CollisionPolicyDedupeIdentical}
+)
+
+// Collision represents a resource generated by more than one release
+type Collision struct {
+	// Key the apiVersion/kind/namespace/name of the resource
+	Key string
+
+	// Release the namespace/name of the first release which generated the resource
+	Release string
+
+	// Path the file the first release generated the resource in
+	Path string
+
+	// OtherRelease the namespace/name of the release which generated the duplicate resource
+	OtherRelease string
+
+	// OtherPath the file the other release generated the duplicate resource in
+	OtherPath string
+
+	// Identical whether the generated resources are byte-identical
+	Identical bool
+}
+
+// String returns a description of the collision
+func (c *Collision) String() string {
+	return c.Key + " is generated by release " + c.Release + " (" + c.Path + ") and release " + c.OtherRelease + " (" + c.OtherPath + ")"
+}
+
+// ResourceKey returns the apiVersion/kind/namespace/name key used to detect collisions
+func ResourceKey(apiVersion, kind, namespace, name string) string {
+	return strings.Join([]string{apiVersion, kind, namespace, name}, "/")
+}
+
+func (r *ResourceToMove) releaseName() string {
+	return r.namespace + "/" + r.release
+}
+
+// indexResource indexes the resource so that any collision with a previously indexed resource is recorded
+// and the resource is skipped if the collision policy says so
+func (o *Options) indexResource(index map[string]*ResourceToMove, res *ResourceToMove, resourceNamespace string) {
+	if res.name == "" {
+		return
+	}
+	key := ResourceKey(res.apiVersion, res.kind, resourceNamespace, res.name)
+	first := index[key]
+	if first == nil {
+		index[key] = res
+		return
+	}
+	c := Collision{
+		Key:          key,
+		Release:      first.releaseName(),
+		Path:         o.relPath(first.path),
+		OtherRelease: res.releaseName(),
+		OtherPath:    o.relPath(res.path),
+		Identical:    bytes.Equal(first.data, res.data),
+	}
+	o.Collisions = append(o.Collisions, c)
+
+	switch o.CollisionPolicy {
+	case CollisionPolicyKeepFirst:
+		res.skip = true
+		log.Logger().Warnf("resource %s so ignoring the resource from release %s", c.String(), termcolor.ColorInfo(c.OtherRelease))
+	case CollisionPolicyDedupeIdentical:
+		if c.Identical {
+			res.skip = true
+			log.Logger().Infof("ignoring resource %s from release %s as it is identical to the one from release %s", termcolor.ColorInfo(key), termcolor.ColorInfo(c.OtherRelease), termcolor.ColorInfo(c.Release))
+		}
+	case CollisionPolicyWarn:
+		log.Logger().Warnf("resource %s so whichever is applied last will win", c.String())
+	}
+}
+
+// checkCollisions returns an error if the collision policy does not allow the collisions found
+func (o *Options) checkCollisions() error {
+	var messages []string
+	for i := range o.Collisions {
+		c := &o.Collisions[i]
+		switch o.CollisionPolicy {
+		case CollisionPolicyFail:
+			messages = append(messages, c.String())
+		case CollisionPolicyDedupeIdentical:
+			if !c.Identical {
+				messages = append(messages, c.String()+" and the resources differ")
+			}
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.Errorf("found %d resource collisions with --collision-policy %s:\n%s", len(messages), o.CollisionPolicy, strings.Join(messages, "\n"))
+}
+
+// relPath returns the path relative to the source dir for nicer messages
+func (o *Options) relPath(path string) string {
+	rel, err := filepath.Rel(o.Dir, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apiresources"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmhelpers"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
@@ -13,6 +14,8 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
@@ -20,6 +23,7 @@ import (
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
@@ -45,11 +49,26 @@ var (
 		If supplied with --dir-includes-release-name then by default we will annotate the resources with the annotations "app.kubernetes.io/instance" to preserve the helm release name.
 
 		The annotation "meta.helm.sh/release-namespace" will be added by default and contain the namespace specified in the release.
+
+		Whether a kind is namespaced is found from the cached API resources file (.jx/gitops/api-resources.yaml by default), the cluster if there is access to one,
+		the CRDs already in 'config-root/customresourcedefinitions' and the CRDs being moved. So the results are the same with or without access to a cluster
+		as long as the cached API resources file is kept up to date via --refresh-api-resources.
+
+		Every resource is indexed by its apiVersion, kind, namespace and name so that resources rendered by more than one release are reported along with both source releases.
+		The --collision-policy flag controls what happens on a collision: 'warn' (the default) logs the collision and writes both resources,
+		'fail' fails the command, 'keep-first' only writes the resource from the first release and 'dedupe-identical' only writes the first resource
+		if the colliding resources are byte-identical and fails otherwise.
 `)
 
 	namespaceExample = templates.Examples(`
 		# moves the generated files in 'tmp' to the config root dir
 		%s helmfile move --dir config-root --from tmp
+
+		# refreshes the cached API resources file from the cluster
+		%s helmfile move --dir config-root --from tmp --refresh-api-resources
+
+		# fails if two releases generate the same resource
+		%s helmfile move --dir config-root --from tmp --collision-policy fail
 	`)
 )
 
@@ -67,8 +86,13 @@ type Options struct {
 	OverrideNamespace            bool
 	AnnotateReleaseNames         bool
 	AnnotateReleaseNameSpace     bool
+	CollisionPolicy              string
+	APIResourcesFile             string
+	RefreshAPIResources          bool
+	KubeClient                   kubernetes.Interface
 	NamespacedKind               map[string]bool
 	ResourcesToMove              []ResourceToMove
+	Collisions                   []Collision
 }
 
 // NewCmdHelmfileMove creates a command object for the command
@@ -80,7 +104,7 @@ func NewCmdHelmfileMove() (*cobra.Command, *Options) {
 		Aliases: []string{"mv"},
 		Short:   "Moves the generated template files from 'helmfile template' into the right gitops directory",
 		Long:    namespaceLong,
-		Example: fmt.Sprintf(namespaceExample, rootcmd.BinaryName),
+		Example: fmt.Sprintf(namespaceExample, rootcmd.BinaryName, rootcmd.BinaryName, rootcmd.BinaryName),
 		Run: func(_ *cobra.Command, _ []string) {
 			err := o.Run()
 			helper.CheckErr(err)
@@ -92,6 +116,9 @@ func NewCmdHelmfileMove() (*cobra.Command, *Options) {
 	cmd.Flags().BoolVarP(&o.AnnotateReleaseNames, "annotate-release-name", "", true, "if using --dir-includes-release-name layout then lets add the 'meta.helm.sh/release-name' annotation to record the helm release name")
 	cmd.Flags().BoolVarP(&o.AnnotateReleaseNameSpace, "annotate-release-namespace", "", true, "add the 'meta.helm.sh/release-namespace' annotation to record the helm release namespace")
 	cmd.Flags().BoolVarP(&o.OverrideNamespace, "override-namespace", "", true, "applies the namespace specified in helmfile to all the generated resources")
+	cmd.Flags().StringVarP(&o.APIResourcesFile, "api-resources-file", "", APIResourcesFile, "the cached API resources file used to find which kinds are namespaced when there is no access to a cluster")
+	cmd.Flags().BoolVarP(&o.RefreshAPIResources, "refresh-api-resources", "", false, "refreshes the cached API resources file from the cluster")
+	cmd.Flags().StringVarP(&o.CollisionPolicy, "collision-policy", "", CollisionPolicyWarn, fmt.Sprintf("what to do when two releases generate the same resource. Possible values: %s", strings.Join(CollisionPolicies, ", ")))
 
 	o.Filter.AddFlags(cmd)
 	return cmd, o
@@ -99,6 +126,12 @@ func NewCmdHelmfileMove() (*cobra.Command, *Options) {
 
 // Run implements the command
 func (o *Options) Run() error {
+	if o.CollisionPolicy == "" {
+		o.CollisionPolicy = CollisionPolicyWarn
+	}
+	if stringhelpers.StringArrayIndex(CollisionPolicies, o.CollisionPolicy) < 0 {
+		return options.InvalidOption("collision-policy", o.CollisionPolicy, CollisionPolicies)
+	}
 	if o.ClusterDir == "" {
 		o.ClusterDir = filepath.Join(o.OutputDir, "cluster")
 	}
@@ -134,25 +167,10 @@ func (o *Options) Run() error {
 	}
 
 	var namespaces []string
-	o.NamespacedKind = make(map[string]bool)
-	if !kube.IsNoKubernetes() {
-		client, err := kube.LazyCreateKubeClient(nil)
-		if err != nil {
-			log.Logger().Errorf("Failed to create k8s client: %v", err)
-			goto noKube
-		}
-		apiResourceLists, err := client.Discovery().ServerPreferredResources()
-		if err != nil {
-			log.Logger().Errorf("Failed to fetch api resources: %v", err)
-		}
-		for i := range apiResourceLists {
-			resources := apiResourceLists[i].APIResources
-			for j := range resources {
-				o.NamespacedKind[resources[j].Kind] = resources[j].Namespaced
-			}
-		}
+	err = o.discoverNamespacedKinds()
+	if err != nil {
+		return errors.Wrapf(err, "failed to discover the namespaced kinds")
 	}
-noKube:
 	o.ResourcesToMove = make([]ResourceToMove, 0, len(fileNames))
 	for _, dir := range fileNames {
 		log.Logger().Debugf("processing chart dir %s", dir)
@@ -185,7 +203,15 @@ noKube:
 			return errors.Wrapf(err, "failed to ")
 		}
 	}
-	for _, res := range o.ResourcesToMove {
+	o.Collisions = nil
+	index := map[string]*ResourceToMove{}
+	for i := range o.ResourcesToMove {
+		res := &o.ResourcesToMove[i]
+		if res.crd {
+			res.outDir = filepath.Join(o.CustomResourceDefinitionsDir, res.namespace, res.pathname)
+			o.indexResource(index, res, "")
+			continue
+		}
 		isNamespaced, ok := o.NamespacedKind[res.kind]
 		if !ok {
 			isNamespaced = !kyamls.IsClusterKind(res.kind)
@@ -198,10 +224,11 @@ noKube:
 				log.Logger().Errorf("the server doesn't have resource of kind %s. Assuming it is%s namespaced.", res.kind, not)
 			}
 		}
-		outDir := filepath.Join(o.ClusterResourcesDir, res.namespace, res.pathname)
+		res.outDir = filepath.Join(o.ClusterResourcesDir, res.namespace, res.pathname)
+		resourceNamespace := ""
 
 		if isNamespaced {
-			resourceNamespace := res.namespace
+			resourceNamespace = res.namespace
 			setNS := true
 			if !o.OverrideNamespace {
 				nsNodeText := kyamls.GetNamespace(res.node, res.path)
@@ -218,14 +245,25 @@ noKube:
 				}
 			}
 
-			outDir = filepath.Join(o.NamespacesDir, resourceNamespace, res.pathname)
+			res.outDir = filepath.Join(o.NamespacesDir, resourceNamespace, res.pathname)
 		} else {
 			err := res.node.PipeE(yaml.Lookup("metadata"), yaml.FieldClearer{Name: "namespace"})
 			if err != nil {
 				return errors.Wrapf(err, "failed to remove metadata.namespace for path %s", res.path)
 			}
 		}
-		err = o.writeNodeToDir(outDir, res.rel, res.node)
+		o.indexResource(index, res, resourceNamespace)
+	}
+	err = o.checkCollisions()
+	if err != nil {
+		return err
+	}
+	for i := range o.ResourcesToMove {
+		res := &o.ResourcesToMove[i]
+		if res.skip {
+			continue
+		}
+		err = o.writeNodeToDir(res.outDir, res.rel, res.node)
 		if err != nil {
 			return err
 		}
@@ -346,15 +384,7 @@ func (o *Options) moveFilesToClusterOrNamespacesFolder(dir, ns, releaseName, cha
 		if !match {
 			return nil
 		}
-		// pathName is always prefixed with chartName but lets also remove any duplication
-		var pathName string
-		if chartName == releaseName {
-			pathName = chartName
-		} else if strings.HasPrefix(releaseName, chartName) {
-			pathName = releaseName
-		} else {
-			pathName = fmt.Sprintf("%s-%s", chartName, releaseName)
-		}
+		pathName := PathName(chartName, releaseName)
 
 		var setAnnotations []yaml.AnnotationSetter
 		if o.AnnotateReleaseNames {
@@ -388,19 +418,22 @@ func (o *Options) moveFilesToClusterOrNamespacesFolder(dir, ns, releaseName, cha
 		}
 
 		if kyamls.IsCustomResourceDefinition(kind) {
-			namespaced := kyamls.GetStringField(node, path, "spec", "scope") == "Namespaced"
-			name := kyamls.GetStringField(node, path, "spec", "names", "kind")
+			name, namespaced := apiresources.CRDScope(node, path)
 			log.Logger().Debugf("CRD %s: namespaced = %v", name, namespaced)
 			o.NamespacedKind[name] = namespaced
-			return o.writeNodeToDir(filepath.Join(o.CustomResourceDefinitionsDir, ns, pathName), rel, node)
 		}
 		o.ResourcesToMove = append(o.ResourcesToMove, ResourceToMove{
-			kind:      kind,
-			node:      node,
-			path:      path,
-			pathname:  pathName,
-			rel:       rel,
-			namespace: ns,
+			apiVersion: kyamls.GetAPIVersion(node, path),
+			kind:       kind,
+			name:       kyamls.GetName(node, path),
+			node:       node,
+			path:       path,
+			pathname:   pathName,
+			rel:        rel,
+			namespace:  ns,
+			release:    releaseName,
+			data:       data,
+			crd:        kyamls.IsCustomResourceDefinition(kind),
 		})
 		return nil
 	})
@@ -411,12 +444,19 @@ func (o *Options) moveFilesToClusterOrNamespacesFolder(dir, ns, releaseName, cha
 }
 
 type ResourceToMove struct {
-	kind      string
-	path      string
-	rel       string
-	node      *yaml.RNode
-	pathname  string
-	namespace string
+	apiVersion string
+	kind       string
+	name       string
+	path       string
+	rel        string
+	node       *yaml.RNode
+	pathname   string
+	namespace  string
+	release    string
+	data       []byte
+	crd        bool
+	outDir     string
+	skip       bool
 }
 
 func (o *Options) writeNodeToDir(outDir, rel string, node *yaml.RNode) error {
@@ -433,3 +473,15 @@ func (o *Options) writeNodeToDir(outDir, rel string, node *yaml.RNode) error {
 	}
 	return nil
 }
+
+// PathName returns the name of the directory the resources of the release are moved into.
+// It is always prefixed with the chart name but lets also remove any duplication
+func PathName(chartName, releaseName string) string {
+	if chartName == releaseName {
+		return chartName
+	}
+	if strings.HasPrefix(releaseName, chartName) {
+		return releaseName
+	}
+	return fmt.Sprintf("%s-%s", chartName, releaseName)
+}
@@ -1,16 +1,24 @@
 package move_test
 
 import (
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apiresources"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/move"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 type test struct {
@@ -169,3 +177,217 @@ func testMove(t *testing.T, test *test, overrideNamespace bool) {
 		}
 	}
 }
+
+func TestMoveCollisions(t *testing.T) {
+	clusterRoleKey := move.ResourceKey("rbac.authorization.k8s.io/v1", "ClusterRole", "", "shared-reader")
+	configMapKey := move.ResourceKey("v1", "ConfigMap", "jx", "shared-config")
+
+	firstClusterRole := "cluster/resources/jx/shared-first/shared-clusterrole.yaml"
+	secondClusterRole := "cluster/resources/jx/shared-second/shared-clusterrole.yaml"
+	firstConfigMap := "namespaces/jx/shared-first/shared-config-cm.yaml"
+	secondConfigMap := "namespaces/jx/shared-second/shared-config-cm.yaml"
+
+	testCases := []struct {
+		policy        string
+		expectError   bool
+		expectedFiles []string
+		missingFiles  []string
+	}{
+		{
+			policy:        move.CollisionPolicyWarn,
+			expectedFiles: []string{firstClusterRole, secondClusterRole, firstConfigMap, secondConfigMap},
+		},
+		{
+			policy:      move.CollisionPolicyFail,
+			expectError: true,
+		},
+		{
+			policy:        move.CollisionPolicyKeepFirst,
+			expectedFiles: []string{firstClusterRole, firstConfigMap},
+			missingFiles:  []string{secondClusterRole, secondConfigMap},
+		},
+		{
+			policy:      move.CollisionPolicyDedupeIdentical,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, o := move.NewCmdHelmfileMove()
+
+		tmpDir := t.TempDir()
+		o.Dir = filepath.Join("testdata", "collisions")
+		o.DirIncludesReleaseName = true
+		o.OutputDir = tmpDir
+		o.CollisionPolicy = tc.policy
+
+		err := o.Run()
+		if tc.expectError {
+			require.Error(t, err, "expected error for policy %s", tc.policy)
+			t.Logf("policy %s got expected error: %s\n", tc.policy, err.Error())
+		} else {
+			require.NoError(t, err, "failed to run helmfile move for policy %s", tc.policy)
+		}
+
+		require.Len(t, o.Collisions, 2, "collisions for policy %s", tc.policy)
+		c := o.Collisions[0]
+		assert.Equal(t, clusterRoleKey, c.Key, "key for policy %s", tc.policy)
+		assert.Equal(t, "jx/first", c.Release, "release for policy %s", tc.policy)
+		assert.Equal(t, "jx/second", c.OtherRelease, "other release for policy %s", tc.policy)
+		assert.True(t, c.Identical, "cluster roles should be identical for policy %s", tc.policy)
+
+		c = o.Collisions[1]
+		assert.Equal(t, configMapKey, c.Key, "key for policy %s", tc.policy)
+		assert.False(t, c.Identical, "config maps should differ for policy %s", tc.policy)
+
+		for _, efn := range tc.expectedFiles {
+			assert.FileExists(t, filepath.Join(tmpDir, efn), "for policy %s", tc.policy)
+		}
+		for _, efn := range tc.missingFiles {
+			assert.NoFileExists(t, filepath.Join(tmpDir, efn), "for policy %s", tc.policy)
+		}
+	}
+}
+
+func TestMoveCollisionsDedupeIdentical(t *testing.T) {
+	_, o := move.NewCmdHelmfileMove()
+
+	// lets remove the differing ConfigMap so only the identical ClusterRoles collide
+	srcDir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join("testdata", "collisions"), srcDir)
+	require.NoError(t, err, "failed to copy testdata")
+	err = os.Remove(filepath.Join(srcDir, "jx", "second", "shared", "templates", "shared-config-cm.yaml"))
+	require.NoError(t, err, "failed to remove ConfigMap")
+
+	tmpDir := t.TempDir()
+	o.Dir = srcDir
+	o.DirIncludesReleaseName = true
+	o.OutputDir = tmpDir
+	o.CollisionPolicy = move.CollisionPolicyDedupeIdentical
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run helmfile move")
+
+	require.Len(t, o.Collisions, 1, "collisions")
+	assert.FileExists(t, filepath.Join(tmpDir, "cluster", "resources", "jx", "shared-first", "shared-clusterrole.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "cluster", "resources", "jx", "shared-second", "shared-clusterrole.yaml"))
+}
+
+func TestMoveOfflineAPIResources(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "false")
+
+	clusterResources := []string{
+		"cluster/resources/jx/tenants/tenant.yaml",
+		"cluster/resources/jx/tenants/gadget.yaml",
+		"cluster/resources/jx/tenants/widget.yaml",
+		"namespaces/jx/tenants/tenants-cm.yaml",
+		"customresourcedefinitions/jx/tenants/gadgets.example.com-crd.yaml",
+	}
+
+	t.Setenv("JX_NO_KUBERNETES", "true")
+	offlineDir := runOfflineMove(t, nil)
+	for _, efn := range clusterResources {
+		assert.FileExists(t, filepath.Join(offlineDir, efn))
+	}
+
+	t.Setenv("JX_NO_KUBERNETES", "false")
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: false},
+			},
+		},
+	}
+	clusterDir := runOfflineMove(t, kubeClient)
+
+	assert.Equal(t, outputFiles(t, offlineDir), outputFiles(t, clusterDir), "the output should be the same with and without a cluster")
+}
+
+func TestMoveRefreshAPIResources(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "false")
+	t.Setenv("JX_NO_KUBERNETES", "false")
+
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			},
+		},
+	}
+
+	_, o := move.NewCmdHelmfileMove()
+	o.Dir = filepath.Join("testdata", "output")
+	o.OutputDir = t.TempDir()
+	o.APIResourcesFile = filepath.Join(t.TempDir(), ".jx", "gitops", "api-resources.yaml")
+	o.RefreshAPIResources = true
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run helmfile move")
+
+	resources, err := apiresources.Load(o.APIResourcesFile)
+	require.NoError(t, err, "failed to load %s", o.APIResourcesFile)
+	assert.Equal(t, v1alpha1.KindAPIResources, resources.Kind)
+	assert.Equal(t, []v1alpha1.APIResource{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespaced: true},
+		{APIVersion: "v1", Kind: "Namespace", Namespaced: false},
+	}, resources.Spec.Resources)
+
+	t.Setenv("JX_NO_KUBERNETES", "true")
+	_, o = move.NewCmdHelmfileMove()
+	o.Dir = filepath.Join("testdata", "output")
+	o.OutputDir = t.TempDir()
+	o.RefreshAPIResources = true
+
+	err = o.Run()
+	require.Error(t, err, "should not be able to refresh without a cluster")
+}
+
+func runOfflineMove(t *testing.T, kubeClient kubernetes.Interface) string {
+	srcDir := filepath.Join("testdata", "offline")
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join(srcDir, "config-root"), tmpDir)
+	require.NoError(t, err, "failed to copy config-root")
+
+	_, o := move.NewCmdHelmfileMove()
+	o.Dir = filepath.Join(srcDir, "source")
+	o.OutputDir = tmpDir
+	o.APIResourcesFile = filepath.Join(srcDir, ".jx", "gitops", "api-resources.yaml")
+	o.KubeClient = kubeClient
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run helmfile move")
+	return tmpDir
+}
+
+func outputFiles(t *testing.T, dir string) map[string]string {
+	answer := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		answer[rel] = string(data)
+		return nil
+	})
+	require.NoError(t, err, "failed to walk %s", dir)
+	return answer
+}
@@ -0,0 +1,333 @@
+package outdated
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FormatTable displays the results as a table
+	FormatTable = "table"
+	// FormatJSON displays the results as JSON
+	FormatJSON = "json"
+	// FormatMarkdown displays the results as a markdown table
+	FormatMarkdown = "markdown"
+
+	// StatusUpToDate the release uses the latest version
+	StatusUpToDate = "up-to-date"
+	// StatusBehindStream the version stream has a different version to the release
+	StatusBehindStream = "behind-stream"
+	// StatusBehindUpstream the chart repository has a newer version than the release and the version stream
+	StatusBehindUpstream = "behind-upstream"
+	// StatusUnknown the latest version could not be found
+	StatusUnknown = "unknown"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Reports the releases in the helmfiles which are older than the version stream or the chart repository
+
+		For each release the pinned version is compared with the version in the version stream and the latest version
+		in the index.yaml of the chart repository or the tags of the OCI registry.
+`)
+
+	cmdExample = templates.Examples(`
+		# reports outdated releases in the helmfiles
+		%s helmfile outdated
+
+		# reports outdated releases as markdown
+		%s helmfile outdated --format markdown
+
+		# fail if any releases are outdated
+		%s helmfile outdated --fail
+	`)
+
+	formats = []string{FormatTable, FormatJSON, FormatMarkdown}
+)
+
+// Options the options for the command
+type Options struct {
+	versionstreamer.Options
+	Helmfile           string
+	Format             string
+	Fail               bool
+	IncludePrerelease  bool
+	RegistryConfigFile string
+	Helmfiles          []helmfiles.Helmfile
+	ChartClient        chartrepos.Client
+	Results            []*Release
+	prefixes           *versionstream.RepositoryPrefixes
+}
+
+// Release the result of comparing a release with the version stream and its chart repository
+type Release struct {
+	Helmfile      string `json:"helmfile"`
+	Namespace     string `json:"namespace,omitempty"`
+	Release       string `json:"release"`
+	Chart         string `json:"chart"`
+	Repository    string `json:"repository,omitempty"`
+	Version       string `json:"version,omitempty"`
+	StreamVersion string `json:"streamVersion,omitempty"`
+	LatestVersion string `json:"latestVersion,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Outdated returns true if the release is older than the version stream or the chart repository
+func (r *Release) Outdated() bool {
+	return r.Status == StatusBehindStream || r.Status == StatusBehindUpstream
+}
+
+// NewCmdHelmfileOutdated creates a command object for the command
+func NewCmdHelmfileOutdated() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "outdated",
+		Short:   "Reports the releases which are older than the version stream or the chart repository",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName, rootcmd.BinaryName, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.Options.AddFlags(cmd)
+	cmd.Flags().StringVarP(&o.Helmfile, "helmfile", "", "", "the helmfile to check. Defaults to 'helmfile.yaml' in the directory")
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatTable, "the output format. Supported values: "+strings.Join(formats, ", "))
+	cmd.Flags().BoolVarP(&o.Fail, "fail", "", false, "returns a non zero exit code if any releases are outdated")
+	cmd.Flags().StringVarP(&o.RegistryConfigFile, "registry-config", "", ociregistry.DefaultRegistryConfigFile, "the path to the docker config file with the credentials of OCI registries")
+	cmd.Flags().BoolVarP(&o.IncludePrerelease, "prerelease", "", false, "include pre-release versions when finding the latest version in the chart repository")
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	if stringhelpers.StringArrayIndex(formats, o.Format) < 0 {
+		return options.InvalidOption("format", o.Format, formats)
+	}
+	err := o.Options.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.ChartClient == nil {
+		o.ChartClient, err = chartrepos.NewClientWithRegistryConfig(o.RegistryConfigFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create chart repository client")
+		}
+	}
+	if o.Helmfiles == nil {
+		o.Helmfiles, err = helmfiles.GatherHelmfiles(o.Helmfile, o.Dir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to gather helmfiles")
+		}
+	}
+	o.prefixes, err = o.Options.Resolver.GetRepositoryPrefixes()
+	if err != nil {
+		return errors.Wrapf(err, "failed to load repository prefixes at %s", o.VersionStreamDir)
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	o.Results = nil
+	for _, hf := range o.Helmfiles {
+		err = o.checkHelmfile(hf)
+		if err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(o.Results, func(i, j int) bool {
+		r1 := o.Results[i]
+		r2 := o.Results[j]
+		if r1.Namespace != r2.Namespace {
+			return r1.Namespace < r2.Namespace
+		}
+		return r1.Release < r2.Release
+	})
+
+	err = o.display()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, r := range o.Results {
+		if r.Outdated() {
+			count++
+		}
+	}
+	if count > 0 && o.Fail {
+		return errors.Errorf("%d releases are outdated", count)
+	}
+	return nil
+}
+
+func (o *Options) checkHelmfile(hf helmfiles.Helmfile) error {
+	helmStates, err := helmfiles.LoadHelmfile(hf.Filepath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load helmfile %s", hf.Filepath)
+	}
+	path, err := filepath.Rel(o.Dir, hf.Filepath)
+	if err != nil {
+		path = hf.Filepath
+	}
+	for _, helmState := range helmStates {
+		for i := range helmState.Releases {
+			release := &helmState.Releases[i]
+			r := o.checkRelease(helmState, release)
+			if r != nil {
+				r.Helmfile = path
+				o.Results = append(o.Results, r)
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Options) checkRelease(helmState *state.HelmState, release *state.ReleaseSpec) *Release {
+	fullChartName := release.Chart
+	// ignore local and remote charts
+	if strings.Contains(fullChartName, "::") || strings.HasPrefix(fullChartName, ".") {
+		return nil
+	}
+	prefix, chartName := chartrepos.SplitChartName(fullChartName)
+	oci := strings.HasPrefix(fullChartName, chartrepos.OCIScheme)
+	if prefix == "" && !oci {
+		return nil
+	}
+	releaseName := release.Name
+	if releaseName == "" {
+		releaseName = chartName
+	}
+	r := &Release{
+		Namespace: release.Namespace,
+		Release:   releaseName,
+		Chart:     fullChartName,
+		Version:   release.Version,
+	}
+	if r.Namespace == "" {
+		r.Namespace = helmState.OverrideNamespace
+	}
+
+	versionProperties, err := o.Options.Resolver.StableVersion(versionstream.KindChart, prefix+"/"+releaseName)
+	if err == nil && versionProperties.Missing() {
+		versionProperties, err = o.Options.Resolver.StableVersion(versionstream.KindChart, fullChartName)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		r.StreamVersion = versionProperties.Version
+	}
+
+	var repo chartrepos.Repository
+	if oci {
+		repo = chartrepos.NewRepository("", strings.TrimSuffix(fullChartName, "/"+chartName), true)
+	} else {
+		repo, err = o.findRepository(helmState, prefix)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		r.Repository = repo.URL
+		versions, err := o.ChartClient.Versions(repo, chartName)
+		if err != nil {
+			log.Logger().Warnf("failed to find versions of chart %s: %s", info(fullChartName), err.Error())
+			r.Error = err.Error()
+		} else {
+			r.LatestVersion = chartrepos.LatestVersion(versions, o.IncludePrerelease)
+		}
+	}
+
+	switch {
+	case chartrepos.IsNewer(r.Version, r.StreamVersion):
+		r.Status = StatusBehindStream
+	case r.LatestVersion != "" && chartrepos.IsNewer(r.Version, r.LatestVersion):
+		r.Status = StatusBehindUpstream
+	case r.LatestVersion == "" && r.StreamVersion == "":
+		r.Status = StatusUnknown
+	default:
+		r.Status = StatusUpToDate
+	}
+	return r
+}
+
+func (o *Options) findRepository(helmState *state.HelmState, prefix string) (chartrepos.Repository, error) {
+	for i := range helmState.Repositories {
+		repo := helmState.Repositories[i]
+		if repo.Name == prefix {
+			return chartrepos.FromRepositorySpec(&repo), nil
+		}
+	}
+	u, err := versionstreamer.MatchRepositoryPrefix(o.prefixes, prefix)
+	if err != nil {
+		return chartrepos.Repository{}, err
+	}
+	return chartrepos.NewRepository(prefix, u, false), nil
+}
+
+func (o *Options) display() error {
+	switch o.Format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(o.Results, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal results to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	case FormatMarkdown:
+		_, err := fmt.Fprint(o.Out, ToMarkdown(o.Results))
+		return err
+	default:
+		t := table.CreateTable(o.Out)
+		t.AddRow("NAMESPACE", "RELEASE", "CHART", "VERSION", "STREAM", "LATEST", "STATUS")
+		for _, r := range o.Results {
+			t.AddRow(r.Namespace, r.Release, r.Chart, r.Version, r.StreamVersion, r.LatestVersion, r.Status)
+		}
+		t.Render()
+		return nil
+	}
+}
+
+// ToMarkdown converts the results to a markdown table
+func ToMarkdown(results []*Release) string {
+	sb := strings.Builder{}
+	sb.WriteString("| Namespace | Release | Chart | Version | Stream | Latest | Status |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, r := range results {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %s |\n", r.Namespace, r.Release, r.Chart, r.Version, r.StreamVersion, r.LatestVersion, r.Status))
+	}
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+package outdated_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/outdated"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const indexYAML = `apiVersion: v1
+entries:
+  lighthouse:
+  - name: lighthouse
+    version: 1.1.0
+  - name: lighthouse
+    version: 1.0.0
+  jx-pipelines-visualizer:
+  - name: jx-pipelines-visualizer
+    version: 1.3.0
+  - name: jx-pipelines-visualizer
+    version: 1.2.0
+  jx-preview:
+  - name: jx-preview
+    version: 2.0.0
+  - name: jx-preview
+    version: 1.9.0
+  tekton:
+  - name: tekton
+    version: 2.1.0-rc.1
+  - name: tekton
+    version: 2.0.0
+`
+
+func TestHelmfileOutdated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(indexYAML))
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite("testdata", tmpDir)
+	require.NoError(t, err, "failed to copy testdata")
+
+	path := filepath.Join(tmpDir, "helmfiles", "jx", "helmfile.yaml")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to load %s", path)
+	err = os.WriteFile(path, []byte(strings.ReplaceAll(string(data), "CHART_REPO_URL", server.URL)), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save %s", path)
+
+	_, o := outdated.NewCmdHelmfileOutdated()
+	o.Dir = tmpDir
+	o.Format = outdated.FormatJSON
+	buf := &bytes.Buffer{}
+	o.Out = buf
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run")
+
+	var results []*outdated.Release
+	err = json.Unmarshal(buf.Bytes(), &results)
+	require.NoError(t, err, "failed to parse output %s", buf.String())
+	require.Len(t, results, 4)
+
+	statuses := map[string]string{}
+	latest := map[string]string{}
+	for _, r := range results {
+		statuses[r.Release] = r.Status
+		latest[r.Release] = r.LatestVersion
+		assert.Equal(t, "jx", r.Namespace, "namespace for release %s", r.Release)
+	}
+	assert.Equal(t, outdated.StatusBehindStream, statuses["lighthouse"])
+	assert.Equal(t, outdated.StatusBehindUpstream, statuses["jx-pipelines-visualizer"])
+	assert.Equal(t, outdated.StatusUpToDate, statuses["tekton"])
+	assert.Equal(t, "2.0.0", latest["tekton"], "should ignore pre-releases")
+	assert.Equal(t, outdated.StatusUpToDate, statuses["jx-preview"], "a release ahead of the version stream is not outdated")
+
+	o.Format = outdated.FormatMarkdown
+	o.Fail = true
+	buf.Reset()
+	err = o.Run()
+	require.Error(t, err, "should fail as releases are outdated")
+	assert.Contains(t, buf.String(), "| jx | lighthouse | jenkins-x/lighthouse | 1.0.0 | 1.1.0 | 1.1.0 | behind-stream |")
+	t.Logf("%s\n", buf.String())
+}
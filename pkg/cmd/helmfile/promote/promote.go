@@ -0,0 +1,304 @@
+package promote
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/jxtmpl/reqvalues"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Promotes a release from the helmfile of one namespace to the helmfile of another namespace
+
+		The chart, version and labels of the release are copied to the target namespace along with any namespace specific values files.
+		A values file is namespace specific if its path contains a directory named after the source namespace such as 'helmfiles/jx-staging/values/myapp/values.yaml'
+		or 'values/jx-staging/myapp/values.yaml'. These files are copied to the equivalent directory for the target namespace along with any other files in a directory named after the release and the values paths are rewritten.
+
+		A release is not promoted if it would downgrade the version in the target namespace unless --force is specified.
+`)
+
+	cmdExample = templates.Examples(`
+		# promotes the release from the staging namespace to the production namespace
+		%s helmfile promote --name myapp --from jx-staging --to jx-production
+
+		# promotes and commits the changes to git
+		%[1]s helmfile promote --name myapp --from jx-staging --to jx-production --git-commit
+	`)
+)
+
+// Options the options for the command
+type Options struct {
+	options.BaseOptions
+
+	Dir              string
+	Helmfile         string
+	ReleaseName      string
+	From             string
+	To               string
+	Force            bool
+	GitCommitMessage string
+	DoGitCommit      bool
+	Gitter           gitclient.Interface
+	CommandRunner    cmdrunner.CommandRunner
+
+	// PreviousVersion the version of the release in the target namespace before it was promoted
+	PreviousVersion string
+
+	// Version the version of the release which was promoted
+	Version string
+}
+
+// NewCmdHelmfilePromote creates a command object for the command
+func NewCmdHelmfilePromote() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "promote",
+		Short:   "Promotes a release from the helmfile of one namespace to the helmfile of another namespace",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.BaseOptions.AddBaseFlags(cmd)
+
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory that contains the helmfile.yaml and helmfiles directory")
+	cmd.Flags().StringVarP(&o.Helmfile, "helmfile", "", "", "the helmfile to resolve. If not specified defaults to 'helmfile.yaml' in the dir")
+	cmd.Flags().StringVarP(&o.ReleaseName, "name", "", "", "the name of the helm release to promote")
+	cmd.Flags().StringVarP(&o.From, "from", "", "", "the namespace to promote the release from")
+	cmd.Flags().StringVarP(&o.To, "to", "", "", "the namespace to promote the release to")
+	cmd.Flags().BoolVarP(&o.Force, "force", "", false, "promotes the release even if it downgrades the version in the target namespace")
+	cmd.Flags().StringVarP(&o.GitCommitMessage, "commit-message", "", "", "the git commit message used")
+	cmd.Flags().BoolVarP(&o.DoGitCommit, "git-commit", "", false, "if set then the command will git commit the modified helmfile.yaml and values files")
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	err := o.BaseOptions.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+	if o.ReleaseName == "" {
+		return options.MissingOption("name")
+	}
+	if o.From == "" {
+		return options.MissingOption("from")
+	}
+	if o.To == "" {
+		return options.MissingOption("to")
+	}
+	if o.From == o.To {
+		return errors.Errorf("the --from and --to namespaces must be different")
+	}
+	if o.Helmfile == "" {
+		o.Helmfile = "helmfile.yaml"
+	}
+	if o.GitCommitMessage == "" {
+		o.GitCommitMessage = fmt.Sprintf("chore: promote %s from %s to %s", o.ReleaseName, o.From, o.To)
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	hfNames, err := helmfiles.GatherHelmfiles(o.Helmfile, o.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to gather target helmfiles from %s", o.Dir)
+	}
+
+	editor, err := helmfiles.NewEditor(o.Dir, hfNames)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create helmfile editor")
+	}
+
+	helmState, release := editor.FindRelease(o.From, o.ReleaseName)
+	if release == nil {
+		return errors.Errorf("could not find release %s in the helmfile for namespace %s", o.ReleaseName, o.From)
+	}
+	if release.Chart == "" {
+		return errors.Errorf("release %s in namespace %s has no chart", o.ReleaseName, o.From)
+	}
+
+	details := helmfiles.NewChartDetails(helmState, release, nil)
+	details.Namespace = o.To
+	details.ReleaseName = o.ReleaseName
+	o.Version = release.Version
+
+	o.PreviousVersion = ""
+	_, targetRelease := editor.FindRelease(o.To, o.ReleaseName)
+	if targetRelease != nil {
+		if targetRelease.Chart != release.Chart {
+			return errors.Errorf("release %s in namespace %s uses chart %s rather than %s", o.ReleaseName, o.To, targetRelease.Chart, release.Chart)
+		}
+		o.PreviousVersion = targetRelease.Version
+		if release.Version != "" && targetRelease.Version != "" && chartrepos.IsNewer(release.Version, targetRelease.Version) {
+			if !o.Force {
+				return errors.Errorf("cannot promote release %s from %s to %s as it would downgrade the version from %s to %s. Use --force to downgrade", o.ReleaseName, o.From, o.To, targetRelease.Version, release.Version)
+			}
+			log.Logger().Warnf("downgrading release %s in namespace %s from version %s to %s", info(o.ReleaseName), info(o.To), targetRelease.Version, release.Version)
+		}
+	}
+
+	sourceDir := filepath.Dir(editor.HelmfilePath(o.From))
+	targetDir := filepath.Dir(editor.HelmfilePath(o.To))
+	for _, v := range release.Values {
+		path, ok := v.(string)
+		if !ok {
+			log.Logger().Warnf("ignoring inline values of release %s as only values files are promoted", info(o.ReleaseName))
+			continue
+		}
+		path, err = o.promoteValuesFile(sourceDir, targetDir, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to promote values file %s", path)
+		}
+		details.Values = append(details.Values, path)
+	}
+
+	err = editor.AddChart(details)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add release %s to namespace %s", o.ReleaseName, o.To)
+	}
+
+	err = editor.Save()
+	if err != nil {
+		return errors.Wrapf(err, "failed to save modified files")
+	}
+
+	previous := o.PreviousVersion
+	if targetRelease == nil {
+		previous = "<none>"
+	}
+	log.Logger().Infof("promoted release %s chart %s from %s to %s: %s -> %s", info(o.ReleaseName), info(release.Chart), info(o.From), info(o.To), info(previous), info(o.Version))
+
+	if !o.DoGitCommit {
+		return nil
+	}
+	log.Logger().Infof("committing changes: %s", o.GitCommitMessage)
+	err = o.GitCommit(o.Dir, o.GitCommitMessage)
+	if err != nil {
+		return errors.Wrapf(err, "failed to commit changes")
+	}
+	return nil
+}
+
+// promoteValuesFile copies the values file if it is specific to the source namespace returning the values path to use
+// in the target helmfile
+func (o *Options) promoteValuesFile(sourceDir, targetDir, path string) (string, error) {
+	if filepath.IsAbs(path) || path == reqvalues.RequirementsValuesFileName {
+		return path, nil
+	}
+	sourcePath := filepath.Join(sourceDir, path)
+	rel, err := filepath.Rel(o.Dir, sourcePath)
+	if err != nil {
+		return path, errors.Wrapf(err, "failed to find relative path of %s", sourcePath)
+	}
+	parts := strings.Split(rel, string(os.PathSeparator))
+	idx := -1
+	for i := range parts {
+		if parts[i] == o.From {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		// lets preserve the path of shared values files relative to the target helmfile
+		return relativePath(targetDir, sourcePath, path)
+	}
+	targetParts := append([]string{}, parts...)
+	targetParts[idx] = o.To
+	targetPath := filepath.Join(o.Dir, filepath.Join(targetParts...))
+
+	// lets copy the directory of the values file if it is a directory for the release below the namespace directory
+	src, dest := sourcePath, targetPath
+	if idx < len(parts)-2 && parts[len(parts)-2] == o.ReleaseName {
+		src, dest = filepath.Dir(sourcePath), filepath.Dir(targetPath)
+	}
+	exists, err := files.DirExists(src)
+	if err != nil {
+		return path, errors.Wrapf(err, "failed to check if dir exists %s", src)
+	}
+	if exists {
+		err = files.CopyDirOverwrite(src, dest)
+		if err != nil {
+			return path, errors.Wrapf(err, "failed to copy dir %s to %s", src, dest)
+		}
+		log.Logger().Infof("copied values dir %s to %s", info(src), info(dest))
+		return relativePath(targetDir, targetPath, path)
+	}
+
+	exists, err = files.FileExists(src)
+	if err != nil {
+		return path, errors.Wrapf(err, "failed to check if file exists %s", src)
+	}
+	if !exists {
+		log.Logger().Warnf("values file %s of release %s does not exist", info(sourcePath), info(o.ReleaseName))
+		return relativePath(targetDir, targetPath, path)
+	}
+	err = os.MkdirAll(filepath.Dir(dest), files.DefaultDirWritePermissions)
+	if err != nil {
+		return path, errors.Wrapf(err, "failed to create dir for %s", dest)
+	}
+	err = files.CopyFile(src, dest)
+	if err != nil {
+		return path, errors.Wrapf(err, "failed to copy file %s to %s", src, dest)
+	}
+	log.Logger().Infof("copied values file %s to %s", info(src), info(dest))
+	return relativePath(targetDir, targetPath, path)
+}
+
+func relativePath(dir, path, defaultPath string) (string, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return defaultPath, errors.Wrapf(err, "failed to find path of %s relative to %s", path, dir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Git returns the gitter - lazily creating one if required
+func (o *Options) Git() gitclient.Interface {
+	if o.Gitter == nil {
+		o.Gitter = cli.NewCLIClient("", o.CommandRunner)
+	}
+	return o.Gitter
+}
+
+// GitCommit adds and commits any changes in the directory
+func (o *Options) GitCommit(outDir, commitMessage string) error {
+	gitter := o.Git()
+	_, err := gitter.Command(outDir, "add", "-A")
+	if err != nil {
+		return errors.Wrapf(err, "failed to add changes to git in dir %s", outDir)
+	}
+	err = gitclient.CommitIfChanges(gitter, outDir, commitMessage)
+	if err != nil {
+		return errors.Wrapf(err, "failed to commit changes to git in dir %s", outDir)
+	}
+	return nil
+}
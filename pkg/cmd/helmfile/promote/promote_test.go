@@ -0,0 +1,100 @@
+package promote_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/promote"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelmfilePromote(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite("testdata", tmpDir)
+	require.NoError(t, err, "failed to copy testdata to %s", tmpDir)
+
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			t.Logf("running command %s in dir %s\n", c.CLI(), c.Dir)
+			if c.Name == "git" && len(c.Args) > 0 && c.Args[0] == "status" {
+				return "M helmfiles/jx-production/helmfile.yaml", nil
+			}
+			return "", nil
+		},
+	}
+
+	_, o := promote.NewCmdHelmfilePromote()
+	o.Dir = tmpDir
+	o.ReleaseName = "myapp"
+	o.From = "jx-staging"
+	o.To = "jx-production"
+	o.DoGitCommit = true
+	o.CommandRunner = runner.Run
+
+	err = o.Run()
+	require.NoError(t, err, "failed to promote")
+	assert.Equal(t, "", o.PreviousVersion)
+	assert.Equal(t, "1.2.0", o.Version)
+
+	release := loadRelease(t, tmpDir, "jx-production", "myapp")
+	assert.Equal(t, "dev/myapp", release.Chart)
+	assert.Equal(t, "1.2.0", release.Version)
+	assert.Equal(t, map[string]string{"team": "cheese"}, release.Labels)
+	assert.Equal(t, []interface{}{"jx-values.yaml", "values/myapp/values.yaml", "values/myapp-overrides.yaml", "../../values/shared/values.yaml"}, release.Values)
+
+	assert.FileExists(t, filepath.Join(tmpDir, "helmfiles", "jx-production", "values", "myapp", "values.yaml"))
+	assert.FileExists(t, filepath.Join(tmpDir, "helmfiles", "jx-production", "values", "myapp", "secrets.yaml.gotmpl"))
+	assert.FileExists(t, filepath.Join(tmpDir, "helmfiles", "jx-production", "values", "myapp-overrides.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "helmfiles", "jx-production", "values", "oldapp.yaml"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "helmfiles", "jx-production", "jx-values.yaml"))
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{CLI: "git add -A"},
+		fakerunner.FakeResult{CLI: "git status -s"},
+		fakerunner.FakeResult{CLI: "git commit -m chore: promote myapp from jx-staging to jx-production"},
+	)
+}
+
+func TestHelmfilePromoteDowngrade(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite("testdata", tmpDir)
+	require.NoError(t, err, "failed to copy testdata to %s", tmpDir)
+
+	_, o := promote.NewCmdHelmfilePromote()
+	o.Dir = tmpDir
+	o.ReleaseName = "oldapp"
+	o.From = "jx-staging"
+	o.To = "jx-production"
+
+	err = o.Run()
+	require.Error(t, err, "should not downgrade")
+	t.Logf("got expected error: %s\n", err.Error())
+	assert.Equal(t, "2.0.0", loadRelease(t, tmpDir, "jx-production", "oldapp").Version)
+
+	o.Force = true
+	err = o.Run()
+	require.NoError(t, err, "failed to promote with force")
+	assert.Equal(t, "2.0.0", o.PreviousVersion)
+	assert.Equal(t, "1.0.0", loadRelease(t, tmpDir, "jx-production", "oldapp").Version)
+}
+
+func loadRelease(t *testing.T, dir, ns, name string) *state.ReleaseSpec {
+	path := filepath.Join(dir, "helmfiles", ns, "helmfile.yaml")
+	helmStates, err := helmfiles.LoadHelmfile(path)
+	require.NoError(t, err, "failed to load %s", path)
+	for _, helmState := range helmStates {
+		for i := range helmState.Releases {
+			if helmState.Releases[i].Name == name {
+				return &helmState.Releases[i]
+			}
+		}
+	}
+	require.Fail(t, "missing release", "no release %s in %s", name, path)
+	return nil
+}
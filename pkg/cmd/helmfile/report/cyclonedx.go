@@ -0,0 +1,154 @@
+package report
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+)
+
+const (
+	// CycloneDXFileName the name of the generated SBOM file
+	CycloneDXFileName = "sbom.cdx.json"
+
+	cycloneDXSpecVersion = "1.5"
+)
+
+// CycloneDXBOM a minimal CycloneDX bill of materials
+type CycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     CycloneDXMetadata     `json:"metadata"`
+	Components   []CycloneDXComponent  `json:"components"`
+	Dependencies []CycloneDXDependency `json:"dependencies,omitempty"`
+}
+
+// CycloneDXMetadata the metadata of the BOM. There is no timestamp so that regenerating the same releases
+// does not modify the BOM
+type CycloneDXMetadata struct {
+	Timestamp string          `json:"timestamp,omitempty"`
+	Tools     []CycloneDXTool `json:"tools,omitempty"`
+}
+
+// CycloneDXTool the tool which generated the BOM
+type CycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+// CycloneDXComponent a chart or container image
+type CycloneDXComponent struct {
+	BOMRef      string              `json:"bom-ref"`
+	Type        string              `json:"type"`
+	Group       string              `json:"group,omitempty"`
+	Name        string              `json:"name"`
+	Version     string              `json:"version,omitempty"`
+	Description string              `json:"description,omitempty"`
+	PURL        string              `json:"purl,omitempty"`
+	Properties  []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+// CycloneDXProperty a name value property
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDXDependency the dependencies of a component
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// ToCycloneDX creates a CycloneDX SBOM of the charts and the container images they use
+func ToCycloneDX(charts []*releasereport.NamespaceReleases) *CycloneDXBOM {
+	bom := &CycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: CycloneDXMetadata{
+			Tools: []CycloneDXTool{{Name: rootcmd.BinaryName}},
+		},
+		Components: []CycloneDXComponent{},
+	}
+	images := map[string]bool{}
+	for _, ns := range charts {
+		for _, r := range ns.Releases {
+			ref := "chart:" + ns.Namespace + "/" + r.ReleaseName
+			c := CycloneDXComponent{
+				BOMRef:      ref,
+				Type:        "application",
+				Group:       r.RepositoryName,
+				Name:        r.Name,
+				Version:     r.Version,
+				Description: r.Description,
+				Properties: []CycloneDXProperty{
+					{Name: "helm:namespace", Value: ns.Namespace},
+					{Name: "helm:release", Value: r.ReleaseName},
+				},
+			}
+			if r.RepositoryURL != "" {
+				c.Properties = append(c.Properties, CycloneDXProperty{Name: "helm:repository", Value: r.RepositoryURL})
+			}
+			bom.Components = append(bom.Components, c)
+
+			dep := CycloneDXDependency{Ref: ref}
+			for _, image := range r.Images {
+				imageRef := "image:" + image
+				dep.DependsOn = append(dep.DependsOn, imageRef)
+				images[image] = true
+			}
+			bom.Dependencies = append(bom.Dependencies, dep)
+		}
+	}
+
+	var imageNames []string
+	for image := range images {
+		imageNames = append(imageNames, image)
+	}
+	sort.Strings(imageNames)
+	for _, image := range imageNames {
+		name, version := splitImage(image)
+		bom.Components = append(bom.Components, CycloneDXComponent{
+			BOMRef:  "image:" + image,
+			Type:    "container",
+			Name:    name,
+			Version: version,
+			PURL:    imagePURL(name, version),
+		})
+	}
+	return bom
+}
+
+func writeCycloneDX(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error) {
+	path := filepath.Join(outDir, CycloneDXFileName)
+	err := saveJSON(path, ToCycloneDX(charts))
+	if err != nil {
+		return nil, err
+	}
+	return []string{path}, nil
+}
+
+// splitImage splits the image into the name and the digest or tag. The digest is used in preference to the tag
+func splitImage(image string) (string, string) {
+	name, digest, _ := strings.Cut(image, "@")
+	version := digest
+	i := strings.LastIndex(name, ":")
+	if i > strings.LastIndex(name, "/") {
+		if version == "" {
+			version = name[i+1:]
+		}
+		name = name[:i]
+	}
+	return name, version
+}
+
+func imagePURL(name, version string) string {
+	answer := "pkg:docker/" + name
+	if version != "" {
+		answer += "@" + strings.ReplaceAll(version, ":", "%3A")
+	}
+	return answer
+}
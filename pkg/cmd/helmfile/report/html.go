@@ -0,0 +1,181 @@
+package report
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SiteDir the directory in the output dir of the static HTML site
+	SiteDir = "site"
+
+	htmlHeader = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{ .Title }}</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; }
+    table { border-collapse: collapse; }
+    th, td { border: 1px solid #ddd; padding: 0.4em 0.8em; text-align: left; }
+  </style>
+</head>
+<body>
+`
+	htmlFooter = `</body>
+</html>
+`
+)
+
+var (
+	siteIndexTemplate = template.Must(template.New("index").Parse(htmlHeader + `<h1>Releases</h1>
+<table>
+  <thead><tr><th>Namespace</th><th>Releases</th></tr></thead>
+  <tbody>
+{{- range .Namespaces }}
+    <tr><td><a href="{{ .Namespace }}/index.html">{{ .Namespace }}</a></td><td>{{ len .Releases }}</td></tr>
+{{- end }}
+  </tbody>
+</table>
+` + htmlFooter))
+
+	siteNamespaceTemplate = template.Must(template.New("namespace").Parse(htmlHeader + `<p><a href="../index.html">Releases</a></p>
+<h1>Namespace {{ .Namespace.Namespace }}</h1>
+<table>
+  <thead><tr><th>Release</th><th>Chart</th><th>Version</th><th>Open</th></tr></thead>
+  <tbody>
+{{- range .Namespace.Releases }}
+    <tr>
+      <td><a href="{{ .ReleaseName }}.html">{{ .ReleaseName }}</a></td>
+      <td title="{{ .Description }}">{{ .Name }}</td>
+      <td>{{ .Version }}</td>
+      <td>{{ if .ApplicationURL }}<a href="{{ .ApplicationURL }}">view</a>{{ end }}</td>
+    </tr>
+{{- end }}
+  </tbody>
+</table>
+` + htmlFooter))
+
+	siteReleaseTemplate = template.Must(template.New("release").Parse(htmlHeader + `<p><a href="../index.html">Releases</a> / <a href="index.html">{{ .Namespace }}</a></p>
+<h1>{{ .Release.ReleaseName }}</h1>
+<p>{{ .Release.Description }}</p>
+<table>
+  <tbody>
+    <tr><th>Chart</th><td>{{ if .Release.Home }}<a href="{{ .Release.Home }}">{{ .Release.Name }}</a>{{ else }}{{ .Release.Name }}{{ end }}</td></tr>
+    <tr><th>Version</th><td>{{ .Release.Version }}</td></tr>
+    <tr><th>App Version</th><td>{{ .Release.AppVersion }}</td></tr>
+    <tr><th>Repository</th><td>{{ .Release.RepositoryName }} {{ .Release.RepositoryURL }}</td></tr>
+    <tr><th>Logs</th><td>{{ if .Release.LogsURL }}<a href="{{ .Release.LogsURL }}">logs</a>{{ end }}</td></tr>
+    <tr><th>Metrics</th><td>{{ if .Release.MetricsURL }}<a href="{{ .Release.MetricsURL }}">metrics</a>{{ end }}</td></tr>
+    <tr><th>Resources</th><td>{{ .Release.ResourcesPath }}</td></tr>
+  </tbody>
+</table>
+{{- if .Release.Ingresses }}
+<h2>Ingresses</h2>
+<ul>
+{{- range .Release.Ingresses }}
+  <li><a href="{{ .URL }}">{{ .Name }}</a></li>
+{{- end }}
+</ul>
+{{- end }}
+{{- if .Release.Sources }}
+<h2>Sources</h2>
+<ul>
+{{- range .Release.Sources }}
+  <li><a href="{{ . }}">{{ . }}</a></li>
+{{- end }}
+</ul>
+{{- end }}
+{{- if .Release.History }}
+<h2>History</h2>
+<table>
+  <thead><tr><th>Version</th><th>Chart</th><th>Deployed</th><th>Git SHA</th></tr></thead>
+  <tbody>
+{{- range .Release.History }}
+    <tr><td>{{ .Version }}</td><td>{{ .Chart }}</td><td>{{ if .Deployed }}{{ .Deployed.Format "2006-01-02 15:04:05" }}{{ end }}</td><td>{{ .GitSHA }}</td></tr>
+{{- end }}
+  </tbody>
+</table>
+{{- end }}
+{{- if .Release.Changelog }}
+<h2>Changelog</h2>
+<pre>{{ .Release.Changelog }}</pre>
+{{- end }}
+{{- if .Release.Images }}
+<h2>Images</h2>
+<ul>
+{{- range .Release.Images }}
+  <li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+` + htmlFooter))
+)
+
+// WriteHTMLSite writes a self contained static HTML site with an index page, a page per namespace and a page per release
+func WriteHTMLSite(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error) {
+	siteDir := filepath.Join(outDir, SiteDir)
+	var answer []string
+
+	path := filepath.Join(siteDir, "index.html")
+	err := writeTemplate(path, siteIndexTemplate, map[string]interface{}{
+		"Title":      "Releases",
+		"Namespaces": charts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	answer = append(answer, path)
+
+	for _, ns := range charts {
+		nsDir := filepath.Join(siteDir, ns.Namespace)
+		path = filepath.Join(nsDir, "index.html")
+		err = writeTemplate(path, siteNamespaceTemplate, map[string]interface{}{
+			"Title":     "Namespace " + ns.Namespace,
+			"Namespace": ns,
+		})
+		if err != nil {
+			return nil, err
+		}
+		answer = append(answer, path)
+
+		for _, r := range ns.Releases {
+			if r.ReleaseName == "" {
+				continue
+			}
+			path = filepath.Join(nsDir, r.ReleaseName+".html")
+			err = writeTemplate(path, siteReleaseTemplate, map[string]interface{}{
+				"Title":     r.ReleaseName,
+				"Namespace": ns.Namespace,
+				"Release":   r,
+			})
+			if err != nil {
+				return nil, err
+			}
+			answer = append(answer, path)
+		}
+	}
+	return answer, nil
+}
+
+func writeTemplate(path string, t *template.Template, data interface{}) error {
+	err := os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create dir for %s", path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create file %s", path)
+	}
+	defer f.Close()
+	err = t.Execute(f, data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to generate %s", path)
+	}
+	return nil
+}
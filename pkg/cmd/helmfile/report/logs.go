@@ -2,42 +2,176 @@ package report
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
 	"text/template"
 
 	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
 	"github.com/jenkins-x/jx-api/v4/pkg/cloud"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 )
 
-func getLogURL(requirements *jxcore.RequirementsConfig, ns, containerName string) string {
-	c := &requirements.Cluster
-	logsURL, envExists := os.LookupEnv("JX_LOGS_URL")
-	if envExists {
-		t, err := template.New("logsURL").Parse(logsURL)
-		if err != nil {
-			log.Logger().Warnf("failed to parse environment variable JX_LOGS_URL (%s) as template: %v", logsURL, err)
-		} else {
-			data := map[string]interface{}{
-				"requirements": requirements,
-				"ns":           ns,
-				"container":    containerName,
-			}
-			var tpl bytes.Buffer
-			err := t.Execute(&tpl, data)
-			if err != nil {
-				log.Logger().Warnf("failed to execute template %s with values: %v\nerror: %v", logsURL, data, err)
-			} else {
-				return tpl.String()
-			}
-		}
+const (
+	// LogsProviderGCP uses Google Cloud Logging and the GKE console
+	LogsProviderGCP = "gcp"
+	// LogsProviderCloudWatch uses CloudWatch Logs Insights and Container Insights
+	LogsProviderCloudWatch = "cloudwatch"
+	// LogsProviderAzure uses Azure Log Analytics and Container Insights
+	LogsProviderAzure = "azure"
+	// LogsProviderLoki uses Grafana Explore with Loki for logs and Prometheus for metrics
+	LogsProviderLoki = "loki"
+	// LogsProviderOpenSearch uses Kibana or OpenSearch Dashboards
+	LogsProviderOpenSearch = "opensearch"
+
+	// EnvLogsURL the go template of the logs URL which overrides the provider
+	EnvLogsURL = "JX_LOGS_URL"
+	// EnvMetricsURL the go template of the metrics URL which overrides the provider
+	EnvMetricsURL = "JX_METRICS_URL"
+	// EnvLogsProvider the name of the logs provider to use instead of the one for the cluster provider
+	EnvLogsProvider = "JX_LOGS_PROVIDER"
+	// EnvCloudWatchLogGroup the CloudWatch log group of the container logs
+	EnvCloudWatchLogGroup = "JX_CLOUDWATCH_LOG_GROUP"
+	// EnvAzureResourceID the Azure resource ID of the AKS cluster or Log Analytics workspace
+	EnvAzureResourceID = "JX_AZURE_RESOURCE_ID"
+	// EnvGrafanaURL the URL of Grafana
+	EnvGrafanaURL = "JX_GRAFANA_URL"
+	// EnvGrafanaLokiDatasource the name of the Loki datasource in Grafana
+	EnvGrafanaLokiDatasource = "JX_GRAFANA_LOKI_DATASOURCE"
+	// EnvGrafanaPrometheusDatasource the name of the Prometheus datasource in Grafana
+	EnvGrafanaPrometheusDatasource = "JX_GRAFANA_PROMETHEUS_DATASOURCE"
+	// EnvOpenSearchURL the URL of Kibana or OpenSearch Dashboards
+	EnvOpenSearchURL = "JX_OPENSEARCH_URL"
+	// EnvOpenSearchDashboard the ID of the dashboard used for the metrics URL
+	EnvOpenSearchDashboard = "JX_OPENSEARCH_DASHBOARD"
+)
+
+// LogsContext the release container to create the logs and metrics URLs for
+type LogsContext struct {
+	Requirements *jxcore.RequirementsConfig
+	Namespace    string
+	Container    string
+}
+
+// LogsProvider creates the URLs to browse the logs and metrics of a release
+type LogsProvider interface {
+	// LogsURL returns the URL to browse the logs or an empty string if there is none
+	LogsURL(c *LogsContext) string
+	// MetricsURL returns the URL of the metrics dashboard or an empty string if there is none
+	MetricsURL(c *LogsContext) string
+}
+
+// LogsProviders the logs providers indexed by name
+var LogsProviders = map[string]func() LogsProvider{
+	LogsProviderGCP:        func() LogsProvider { return &GCPLogsProvider{} },
+	LogsProviderCloudWatch: func() LogsProvider { return NewCloudWatchLogsProvider() },
+	LogsProviderAzure:      func() LogsProvider { return NewAzureLogsProvider() },
+	LogsProviderLoki:       func() LogsProvider { return NewLokiLogsProvider() },
+	LogsProviderOpenSearch: func() LogsProvider { return NewOpenSearchLogsProvider() },
+}
+
+// LogsProviderNames returns the sorted names of the logs providers
+func LogsProviderNames() []string {
+	var answer []string
+	for k := range LogsProviders {
+		answer = append(answer, k)
 	}
-	if c.Provider == cloud.GKE {
-		return logsURLForGCP(c.ProjectID, c.ClusterName, ns, containerName)
+	sort.Strings(answer)
+	return answer
+}
+
+// LogsProviderName returns the name of the logs provider for the requirements. The $JX_LOGS_PROVIDER
+// environment variable takes precedence, then the cluster provider, then any configured Grafana or OpenSearch URL
+func LogsProviderName(requirements *jxcore.RequirementsConfig) string {
+	if name := os.Getenv(EnvLogsProvider); name != "" {
+		return name
+	}
+	switch requirements.Cluster.Provider {
+	case cloud.GKE:
+		return LogsProviderGCP
+	case cloud.EKS, cloud.AWS:
+		return LogsProviderCloudWatch
+	case cloud.AKS:
+		return LogsProviderAzure
+	}
+	if os.Getenv(EnvGrafanaURL) != "" {
+		return LogsProviderLoki
+	}
+	if os.Getenv(EnvOpenSearchURL) != "" {
+		return LogsProviderOpenSearch
 	}
 	return ""
 }
 
+// getLogsAndMetricsURLs returns the logs and metrics URLs for the container using the provider.
+// The $JX_LOGS_URL and $JX_METRICS_URL go templates take precedence if they are specified
+func getLogsAndMetricsURLs(provider LogsProvider, requirements *jxcore.RequirementsConfig, ns, containerName string) (string, string) {
+	c := &LogsContext{
+		Requirements: requirements,
+		Namespace:    ns,
+		Container:    containerName,
+	}
+	logsURL, ok := templateURL(EnvLogsURL, c)
+	if !ok && provider != nil {
+		logsURL = provider.LogsURL(c)
+	}
+	metricsURL, ok := templateURL(EnvMetricsURL, c)
+	if !ok && provider != nil {
+		metricsURL = provider.MetricsURL(c)
+	}
+	return logsURL, metricsURL
+}
+
+func templateURL(envVar string, c *LogsContext) (string, bool) {
+	text, envExists := os.LookupEnv(envVar)
+	if !envExists {
+		return "", false
+	}
+	t, err := template.New(envVar).Parse(text)
+	if err != nil {
+		log.Logger().Warnf("failed to parse environment variable %s (%s) as template: %v", envVar, text, err)
+		return "", false
+	}
+	data := map[string]interface{}{
+		"requirements": c.Requirements,
+		"ns":           c.Namespace,
+		"container":    c.Container,
+	}
+	var tpl bytes.Buffer
+	err = t.Execute(&tpl, data)
+	if err != nil {
+		log.Logger().Warnf("failed to execute template %s with values: %v\nerror: %v", text, data, err)
+		return "", false
+	}
+	return tpl.String(), true
+}
+
+// GCPLogsProvider creates Google Cloud Logging and GKE console URLs
+type GCPLogsProvider struct{}
+
+// LogsURL returns the Cloud Logging URL of the container
+func (p *GCPLogsProvider) LogsURL(c *LogsContext) string {
+	cluster := &c.Requirements.Cluster
+	return logsURLForGCP(cluster.ProjectID, cluster.ClusterName, c.Namespace, c.Container)
+}
+
+// MetricsURL returns the GKE console workload URL of the container
+func (p *GCPLogsProvider) MetricsURL(c *LogsContext) string {
+	cluster := &c.Requirements.Cluster
+	location := cluster.Zone
+	if location == "" {
+		location = cluster.Region
+	}
+	if cluster.ProjectID == "" || cluster.ClusterName == "" || location == "" || c.Container == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://console.cloud.google.com/kubernetes/deployment/%s/%s/%s/%s/overview?project=%s", location, cluster.ClusterName, c.Namespace, c.Container, cluster.ProjectID)
+}
+
 // logsURLForGCP generates the URL for a container logs URL
 func logsURLForGCP(projectName, clusterName, ns, containerName string) string {
 	if projectName != "" && clusterName != "" && containerName != "" {
@@ -45,3 +179,184 @@ func logsURLForGCP(projectName, clusterName, ns, containerName string) string {
 	}
 	return ""
 }
+
+// CloudWatchLogsProvider creates CloudWatch Logs Insights and Container Insights URLs
+type CloudWatchLogsProvider struct {
+	// LogGroup the log group of the container logs. Defaults to the Container Insights application log group of the cluster
+	LogGroup string
+}
+
+// NewCloudWatchLogsProvider creates a CloudWatch provider configured from the environment
+func NewCloudWatchLogsProvider() *CloudWatchLogsProvider {
+	return &CloudWatchLogsProvider{LogGroup: os.Getenv(EnvCloudWatchLogGroup)}
+}
+
+// LogsURL returns the Logs Insights query URL of the container
+func (p *CloudWatchLogsProvider) LogsURL(c *LogsContext) string {
+	cluster := &c.Requirements.Cluster
+	if cluster.Region == "" || c.Container == "" {
+		return ""
+	}
+	logGroup := p.LogGroup
+	if logGroup == "" {
+		if cluster.ClusterName == "" {
+			return ""
+		}
+		logGroup = fmt.Sprintf("/aws/containerinsights/%s/application", cluster.ClusterName)
+	}
+	query := fmt.Sprintf("fields @timestamp, log\n| filter kubernetes.namespace_name = \"%s\" and kubernetes.container_name = \"%s\"\n| sort @timestamp desc\n| limit 200", c.Namespace, c.Container)
+	queryDetail := "~(end~0~start~-3600~timeType~'RELATIVE~unit~'seconds~editorString~'" + jsURLEscape(query) + "~source~(~'" + jsURLEscape(logGroup) + "))"
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/cloudwatch/home?region=%s#logsV2:logs-insights$3FqueryDetail$3D%s", cluster.Region, cluster.Region, queryDetail)
+}
+
+// MetricsURL returns the Container Insights performance URL of the namespace
+func (p *CloudWatchLogsProvider) MetricsURL(c *LogsContext) string {
+	cluster := &c.Requirements.Cluster
+	if cluster.Region == "" || cluster.ClusterName == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/cloudwatch/home?region=%s#container-insights:performance/EKS:Namespace?~(query~(controls~(CW*3a*3aEKS.cluster~(~'%s)~CW*3a*3aEKS.namespace~(~'%s))))",
+		cluster.Region, cluster.Region, jsURLEscape(cluster.ClusterName), jsURLEscape(c.Namespace))
+}
+
+// jsURLEscape escapes a string for the JSURL encoding used by the AWS console
+func jsURLEscape(text string) string {
+	buf := strings.Builder{}
+	for _, b := range []byte(text) {
+		if (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' {
+			buf.WriteByte(b)
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("*%02x", b))
+	}
+	return buf.String()
+}
+
+// AzureLogsProvider creates Azure Log Analytics and Container Insights URLs
+type AzureLogsProvider struct {
+	// ResourceID the resource ID of the AKS cluster or Log Analytics workspace
+	ResourceID string
+}
+
+// NewAzureLogsProvider creates an Azure provider configured from the environment
+func NewAzureLogsProvider() *AzureLogsProvider {
+	return &AzureLogsProvider{ResourceID: os.Getenv(EnvAzureResourceID)}
+}
+
+// LogsURL returns the Log Analytics query URL of the container
+func (p *AzureLogsProvider) LogsURL(c *LogsContext) string {
+	if p.ResourceID == "" || c.Container == "" {
+		return ""
+	}
+	query := fmt.Sprintf("ContainerLogV2\n| where PodNamespace == \"%s\" and ContainerName == \"%s\"\n| order by TimeGenerated desc", c.Namespace, c.Container)
+	return "https://portal.azure.com/#blade/Microsoft_Azure_Monitoring_Logs/LogsBlade/resourceId/" + url.PathEscape(p.ResourceID) +
+		"/source/LogsBlade.AnalyticsShareLinkToQuery/query/" + url.PathEscape(query) + "/timespan/PT1H"
+}
+
+// MetricsURL returns the Container Insights URL of the resource
+func (p *AzureLogsProvider) MetricsURL(_ *LogsContext) string {
+	if p.ResourceID == "" {
+		return ""
+	}
+	return "https://portal.azure.com/#resource" + p.ResourceID + "/insights"
+}
+
+// LokiLogsProvider creates Grafana Explore URLs using Loki for logs and Prometheus for metrics
+type LokiLogsProvider struct {
+	GrafanaURL           string
+	LokiDatasource       string
+	PrometheusDatasource string
+}
+
+// NewLokiLogsProvider creates a Grafana Loki provider configured from the environment
+func NewLokiLogsProvider() *LokiLogsProvider {
+	p := &LokiLogsProvider{
+		GrafanaURL:           os.Getenv(EnvGrafanaURL),
+		LokiDatasource:       os.Getenv(EnvGrafanaLokiDatasource),
+		PrometheusDatasource: os.Getenv(EnvGrafanaPrometheusDatasource),
+	}
+	if p.LokiDatasource == "" {
+		p.LokiDatasource = "loki"
+	}
+	if p.PrometheusDatasource == "" {
+		p.PrometheusDatasource = "prometheus"
+	}
+	return p
+}
+
+// LogsURL returns the Grafana Explore URL of the container logs
+func (p *LokiLogsProvider) LogsURL(c *LogsContext) string {
+	if c.Container == "" {
+		return ""
+	}
+	return p.exploreURL(p.LokiDatasource, fmt.Sprintf(`{namespace="%s", container="%s"}`, c.Namespace, c.Container))
+}
+
+// MetricsURL returns the Grafana Explore URL of the container CPU usage
+func (p *LokiLogsProvider) MetricsURL(c *LogsContext) string {
+	if c.Container == "" {
+		return ""
+	}
+	return p.exploreURL(p.PrometheusDatasource, fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{namespace="%s", container="%s"}[5m])) by (pod)`, c.Namespace, c.Container))
+}
+
+func (p *LokiLogsProvider) exploreURL(datasource, expr string) string {
+	if p.GrafanaURL == "" {
+		return ""
+	}
+	left := map[string]interface{}{
+		"datasource": datasource,
+		"queries": []map[string]string{
+			{
+				"refId": "A",
+				"expr":  expr,
+			},
+		},
+		"range": map[string]string{
+			"from": "now-1h",
+			"to":   "now",
+		},
+	}
+	data, err := json.Marshal(left)
+	if err != nil {
+		log.Logger().Warnf("failed to marshal grafana explore state: %s", err.Error())
+		return ""
+	}
+	return stringhelpers.UrlJoin(p.GrafanaURL, "explore") + "?orgId=1&left=" + url.QueryEscape(string(data))
+}
+
+// OpenSearchLogsProvider creates Kibana or OpenSearch Dashboards URLs
+type OpenSearchLogsProvider struct {
+	URL         string
+	DashboardID string
+}
+
+// NewOpenSearchLogsProvider creates a Kibana or OpenSearch Dashboards provider configured from the environment
+func NewOpenSearchLogsProvider() *OpenSearchLogsProvider {
+	return &OpenSearchLogsProvider{
+		URL:         os.Getenv(EnvOpenSearchURL),
+		DashboardID: os.Getenv(EnvOpenSearchDashboard),
+	}
+}
+
+// LogsURL returns the discover URL of the container logs
+func (p *OpenSearchLogsProvider) LogsURL(c *LogsContext) string {
+	if p.URL == "" || c.Container == "" {
+		return ""
+	}
+	return stringhelpers.UrlJoin(p.URL, "app/discover") + "#/?" + p.state(c)
+}
+
+// MetricsURL returns the dashboard URL filtered to the container if a dashboard is configured
+func (p *OpenSearchLogsProvider) MetricsURL(c *LogsContext) string {
+	if p.URL == "" || p.DashboardID == "" || c.Container == "" {
+		return ""
+	}
+	return stringhelpers.UrlJoin(p.URL, "app/dashboards") + "#/view/" + url.PathEscape(p.DashboardID) + "?" + p.state(c)
+}
+
+func (p *OpenSearchLogsProvider) state(c *LogsContext) string {
+	query := fmt.Sprintf(`kubernetes.namespace_name:"%s" and kubernetes.container_name:"%s"`, c.Namespace, c.Container)
+	query = strings.ReplaceAll(strings.ReplaceAll(query, "!", "!!"), "'", "!'")
+	return "_g=" + url.QueryEscape("(time:(from:now-1h,to:now))") + "&_a=" + url.QueryEscape("(query:(language:kuery,query:'"+query+"'))")
+}
@@ -0,0 +1,66 @@
+package report_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/report"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogsProviders(t *testing.T) {
+	t.Setenv(report.EnvLogsProvider, "")
+	t.Setenv(report.EnvGrafanaURL, "")
+	t.Setenv(report.EnvOpenSearchURL, "")
+
+	c := &report.LogsContext{
+		Requirements: &jxcore.RequirementsConfig{},
+		Namespace:    "jx",
+		Container:    "lighthouse",
+	}
+	cluster := &c.Requirements.Cluster
+	cluster.ClusterName = "mycluster"
+
+	cluster.Provider = "gke"
+	cluster.ProjectID = "myproject"
+	cluster.Zone = "europe-west1-b"
+	assert.Equal(t, report.LogsProviderGCP, report.LogsProviderName(c.Requirements))
+	p := &report.GCPLogsProvider{}
+	assert.Contains(t, p.LogsURL(c), "project=myproject")
+	assert.Equal(t, "https://console.cloud.google.com/kubernetes/deployment/europe-west1-b/mycluster/jx/lighthouse/overview?project=myproject", p.MetricsURL(c))
+
+	cluster.Provider = "eks"
+	cluster.Region = "us-east-1"
+	assert.Equal(t, report.LogsProviderCloudWatch, report.LogsProviderName(c.Requirements))
+	cw := &report.CloudWatchLogsProvider{}
+	logsURL := cw.LogsURL(c)
+	assert.Contains(t, logsURL, "https://us-east-1.console.aws.amazon.com/cloudwatch/home?region=us-east-1#logsV2:logs-insights$3FqueryDetail$3D")
+	assert.Contains(t, logsURL, "~source~(~'*2faws*2fcontainerinsights*2fmycluster*2fapplication)")
+	assert.Contains(t, logsURL, "kubernetes.container_name*20*3d*20*22lighthouse*22")
+	assert.Contains(t, cw.MetricsURL(c), "CW*3a*3aEKS.cluster~(~'mycluster)~CW*3a*3aEKS.namespace~(~'jx)")
+
+	cluster.Provider = "aks"
+	assert.Equal(t, report.LogsProviderAzure, report.LogsProviderName(c.Requirements))
+	az := &report.AzureLogsProvider{}
+	assert.Empty(t, az.LogsURL(c))
+	az.ResourceID = "/subscriptions/123/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/mycluster"
+	assert.Contains(t, az.LogsURL(c), "LogsBlade/resourceId/%2Fsubscriptions%2F123%2FresourceGroups%2Frg")
+	assert.Equal(t, "https://portal.azure.com/#resource/subscriptions/123/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/mycluster/insights", az.MetricsURL(c))
+
+	cluster.Provider = "kubernetes"
+	assert.Empty(t, report.LogsProviderName(c.Requirements))
+
+	t.Setenv(report.EnvGrafanaURL, "https://grafana.example.com")
+	assert.Equal(t, report.LogsProviderLoki, report.LogsProviderName(c.Requirements))
+	loki := report.NewLokiLogsProvider()
+	assert.Equal(t, "https://grafana.example.com/explore?orgId=1&left=%7B%22datasource%22%3A%22loki%22%2C%22queries%22%3A%5B%7B%22expr%22%3A%22%7Bnamespace%3D%5C%22jx%5C%22%2C+container%3D%5C%22lighthouse%5C%22%7D%22%2C%22refId%22%3A%22A%22%7D%5D%2C%22range%22%3A%7B%22from%22%3A%22now-1h%22%2C%22to%22%3A%22now%22%7D%7D", loki.LogsURL(c))
+	assert.Contains(t, loki.MetricsURL(c), "%22datasource%22%3A%22prometheus%22")
+
+	t.Setenv(report.EnvLogsProvider, report.LogsProviderOpenSearch)
+	assert.Equal(t, report.LogsProviderOpenSearch, report.LogsProviderName(c.Requirements))
+	osp := &report.OpenSearchLogsProvider{URL: "https://kibana.example.com"}
+	assert.Equal(t, "https://kibana.example.com/app/discover#/?_g=%28time%3A%28from%3Anow-1h%2Cto%3Anow%29%29&_a=%28query%3A%28language%3Akuery%2Cquery%3A%27kubernetes.namespace_name%3A%22jx%22+and+kubernetes.container_name%3A%22lighthouse%22%27%29%29", osp.LogsURL(c))
+	assert.Empty(t, osp.MetricsURL(c))
+	osp.DashboardID = "k8s"
+	assert.Contains(t, osp.MetricsURL(c), "https://kibana.example.com/app/dashboards#/view/k8s?_g=")
+}
@@ -9,8 +9,10 @@ import (
 
 	"github.com/helmfile/helmfile/pkg/state"
 	charter "github.com/jenkins-x-plugins/jx-charter/pkg/apis/chart/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmhelpers"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/plugins"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
@@ -20,6 +22,7 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	gitcli "github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/helmer"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/services"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
@@ -43,11 +46,22 @@ var (
 
 	cmdLong = templates.LongDesc(`
 		Generates a markdown report of the helmfile based deployments in each namespace
+
+		The releases are always saved to releases.yaml in the output directory. Additional formats can be generated via the --format option: markdown (README.md), json (releases.json), csv (releases.csv), html (a static site in the site directory) and cyclonedx (an SBOM of the charts and their container images in sbom.cdx.json)
+
+		The logs and metrics URLs of each release are created by a provider chosen from the cluster provider in the jx-requirements.yml file: gcp for GKE, cloudwatch for EKS and azure for AKS. Otherwise loki is used if $JX_GRAFANA_URL is set or opensearch if $JX_OPENSEARCH_URL is set. The providers are configured via the environment variables $JX_CLOUDWATCH_LOG_GROUP, $JX_AZURE_RESOURCE_ID, $JX_GRAFANA_URL, $JX_GRAFANA_LOKI_DATASOURCE, $JX_GRAFANA_PROMETHEUS_DATASOURCE, $JX_OPENSEARCH_URL and $JX_OPENSEARCH_DASHBOARD. The $JX_LOGS_URL and $JX_METRICS_URL go templates override the provider URLs
+
+		The chart metadata of releases from OCI registries is fetched from the registry using the credentials in the --registry-config docker config file
+
+		Each release keeps a bounded history of its versions along with the git commit SHA of the repository. When a release is upgraded and --changelog is specified the changelog between the previous and current version is fetched from the CHANGELOG.md file or the release notes of the chart sources
 `)
 
 	cmdExample = templates.Examples(`
 		# generates a report of the deployments
 		%s helmfile report
+
+		# generates the markdown report, a static HTML site and an SBOM
+		%s helmfile report --format markdown --format html --format cyclonedx
 	`)
 )
 
@@ -56,6 +70,7 @@ type Options struct {
 	options.BaseOptions
 	Dir                     string
 	OutDir                  string
+	Formats                 []string
 	ConfigRootPath          string
 	Namespace               string
 	GitCommitMessage        string
@@ -63,7 +78,13 @@ type Options struct {
 	Helmfiles               []helmfiles.Helmfile
 	HelmBinary              string
 	DoGitCommit             bool
+	FetchChangelog          bool
+	HistorySize             int
+	GitSHA                  string
 	Gitter                  gitclient.Interface
+	ChangelogFetcher        releasereport.ChangelogFetcher
+	LogsProviderName        string
+	LogsProvider            LogsProvider
 	CommandRunner           cmdrunner.CommandRunner
 	HelmClient              helmer.Helmer
 	Requirements            *jxcore.Requirements
@@ -71,6 +92,8 @@ type Options struct {
 	PreviousNamespaceCharts map[string]map[string]*releasereport.ReleaseInfo
 	RepositoryInfo          map[string]*helmrepo.IndexFile
 	HelmSettings            *cli.EnvSettings
+	RegistryConfigFile      string
+	ChartClient             chartrepos.Client
 }
 
 // NewCmdHelmfileReport creates a command object for the command
@@ -81,7 +104,7 @@ func NewCmdHelmfileReport() (*cobra.Command, *Options) {
 		Use:     "report",
 		Short:   "Generates a markdown report of the helmfile based deployments in each namespace",
 		Long:    cmdLong,
-		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName, rootcmd.BinaryName),
 		Run: func(_ *cobra.Command, _ []string) {
 			err := o.Run()
 			helper.CheckErr(err)
@@ -91,6 +114,11 @@ func NewCmdHelmfileReport() (*cobra.Command, *Options) {
 	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory that contains the helmfile.yaml")
 	cmd.Flags().StringVarP(&o.OutDir, "out-dir", "o", "docs", "the output directory")
 	cmd.Flags().StringVarP(&o.ConfigRootPath, "config-root", "", "config-root", "the folder name containing the kubernetes resources")
+	cmd.Flags().StringArrayVarP(&o.Formats, "format", "f", []string{FormatMarkdown}, fmt.Sprintf("the report formats to generate. Supported values: %s", strings.Join(WriterFormats(), ", ")))
+	cmd.Flags().IntVarP(&o.HistorySize, "history-size", "", 10, "the maximum number of versions kept in the history of each release")
+	cmd.Flags().StringVarP(&o.LogsProviderName, "logs-provider", "", "", fmt.Sprintf("the provider of the logs and metrics URLs. If not specified it is chosen from the cluster provider in the requirements or $%s. Supported values: %s", EnvLogsProvider, strings.Join(LogsProviderNames(), ", ")))
+	cmd.Flags().StringVarP(&o.RegistryConfigFile, "registry-config", "", ociregistry.DefaultRegistryConfigFile, "the path to the docker config file with the credentials of OCI registries")
+	cmd.Flags().BoolVarP(&o.FetchChangelog, "changelog", "", false, "fetches the changelog between the previous and current version of each upgraded release from the chart sources")
 	o.AddFlags(cmd, "")
 	o.BaseOptions.AddBaseFlags(cmd)
 	return cmd, o
@@ -117,6 +145,12 @@ func (o *Options) Validate() error {
 		return errors.Wrapf(err, "failed to gather nested helmfiles")
 	}
 
+	for _, f := range o.Formats {
+		if Writers[f] == nil {
+			return errors.Errorf("unsupported format %s. Supported values: %s", f, strings.Join(WriterFormats(), ", "))
+		}
+	}
+
 	if o.GitCommitMessage == "" {
 		o.GitCommitMessage = "chore: resolved charts and values from the version stream"
 	}
@@ -133,6 +167,24 @@ func (o *Options) Validate() error {
 	if o.HelmClient == nil {
 		o.HelmClient = helmer.NewHelmCLIWithRunner(o.CommandRunner, o.HelmBinary, "", false)
 	}
+	if o.ChartClient == nil {
+		o.ChartClient, err = chartrepos.NewClientWithRegistryConfig(o.RegistryConfigFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create chart repository client")
+		}
+	}
+	if o.FetchChangelog && o.ChangelogFetcher == nil {
+		o.ChangelogFetcher = &releasereport.GitHubChangelogFetcher{}
+	}
+	if o.GitSHA == "" {
+		if o.Gitter == nil {
+			o.Gitter = gitcli.NewCLIClient("", o.CommandRunner)
+		}
+		o.GitSHA, err = gitclient.GetLatestCommitSha(o.Gitter, o.Dir)
+		if err != nil {
+			log.Logger().Debugf("failed to find the latest git commit sha in dir %s: %s", o.Dir, err.Error())
+		}
+	}
 	err = os.MkdirAll(o.OutDir, files.DefaultDirWritePermissions)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create output dir %s", o.OutDir)
@@ -142,6 +194,18 @@ func (o *Options) Validate() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to load requirements in dir %s", o.Dir)
 	}
+	if o.LogsProvider == nil {
+		if o.LogsProviderName == "" {
+			o.LogsProviderName = LogsProviderName(&o.Requirements.Spec)
+		}
+		if o.LogsProviderName != "" {
+			fn := LogsProviders[o.LogsProviderName]
+			if fn == nil {
+				return errors.Errorf("unsupported logs provider %s. Supported values: %s", o.LogsProviderName, strings.Join(LogsProviderNames(), ", "))
+			}
+			o.LogsProvider = fn()
+		}
+	}
 
 	o.HelmSettings = cli.New()
 	o.RepositoryInfo = make(map[string]*helmrepo.IndexFile)
@@ -207,16 +271,15 @@ func (o *Options) Run() error {
 	}
 	log.Logger().Infof("saved %s", info(path))
 
-	md, err := ToMarkdown(o.NamespaceCharts)
-	if err != nil {
-		return errors.Wrap(err, "failed to convert charts to markdown")
-	}
-	path = filepath.Join(o.OutDir, "README.md")
-	err = os.WriteFile(path, []byte(md), files.DefaultFileWritePermissions)
-	if err != nil {
-		return errors.Wrapf(err, "failed to save %s", path)
+	for _, f := range o.Formats {
+		paths, err := Writers[f].Write(o.OutDir, o.NamespaceCharts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write %s report", f)
+		}
+		for _, p := range paths {
+			log.Logger().Infof("saved %s", info(p))
+		}
 	}
-	log.Logger().Infof("saved %s", info(path))
 
 	return o.generateChartCRDs()
 }
@@ -230,7 +293,7 @@ func (o *Options) processHelmfile(helmfile helmfiles.Helmfile) (*releasereport.N
 	path := helmfile.Filepath
 	helmStates, err := helmfiles.LoadHelmfile(path)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to load helmfile %s", helmfile)
+		return nil, errors.Wrapf(err, "failed to load helmfile %s", helmfile.Filepath)
 	}
 
 	for _, helmState := range helmStates {
@@ -285,20 +348,21 @@ func (o *Options) createReleaseInfo(helmState *state.HelmState, ns string, rel *
 	if chartName == "" {
 		return nil, nil
 	}
-	paths := strings.SplitN(chartName, "/", 2)
 	answer := &releasereport.ReleaseInfo{}
 	answer.Version = rel.Version
-	switch len(paths) {
-	case 0:
-		return nil, nil
-	case 1:
-		answer.Name = paths[0]
-	default:
-		answer.RepositoryName = paths[0]
-		answer.Name = paths[1]
-	}
+	answer.RepositoryName, answer.Name = chartrepos.SplitChartName(chartName)
 
-	if answer.RepositoryName != "" {
+	if strings.HasPrefix(chartName, chartrepos.OCIScheme) {
+		// lets use the registry of charts referenced by an OCI URL
+		repo := &state.RepositorySpec{
+			URL: strings.TrimSuffix(strings.TrimPrefix(chartName, chartrepos.OCIScheme), "/"+answer.Name),
+			OCI: true,
+		}
+		err := o.enrichChartMetadata(answer, repo, rel, ns)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get chart metadata for %s", answer.String())
+		}
+	} else if answer.RepositoryName != "" {
 		// lets find the repo URL
 		for i := range helmState.Repositories {
 			repo := &helmState.Repositories[i]
@@ -326,23 +390,63 @@ func (o *Options) createReleaseInfo(helmState *state.HelmState, ns string, rel *
 	}
 
 	answer.ReleaseName = rel.Name
-	answer.LogsURL = getLogURL(&o.Requirements.Spec, ns, answer.Name)
+	answer.LogsURL, answer.MetricsURL = getLogsAndMetricsURLs(o.LogsProvider, &o.Requirements.Spec, ns, answer.Name)
+	o.updateHistory(answer, ns, rel)
 	return answer, nil
 }
 
-func (o *Options) enrichChartMetadata(i *releasereport.ReleaseInfo, repo *state.RepositorySpec, rel *state.ReleaseSpec, ns string) (err error) {
-	if repo.OCI {
-		return nil
+// updateHistory adds the current version to the history of the release and fetches the changelog if it was upgraded
+func (o *Options) updateHistory(i *releasereport.ReleaseInfo, ns string, rel *state.ReleaseSpec) {
+	i.History = nil
+	i.Changelog = ""
+	if nsMap, found := o.PreviousNamespaceCharts[ns]; found {
+		if ch := nsMap[rel.Name]; ch != nil {
+			i.History = ch.History
+			if ch.Version == i.Version {
+				i.Changelog = ch.Changelog
+			}
+
+			// lets include the previous version when migrating a report which has no history
+			if len(i.History) == 0 && ch.Version != "" {
+				i.AddHistory(releasereport.ReleaseVersion{
+					Version:  ch.Version,
+					Chart:    rel.Chart,
+					Deployed: ch.FirstDeployed,
+				}, o.HistorySize)
+			}
+		}
+	}
+
+	added := i.AddHistory(releasereport.ReleaseVersion{
+		Version:  i.Version,
+		Chart:    rel.Chart,
+		Deployed: createNow(),
+		GitSHA:   o.GitSHA,
+	}, o.HistorySize)
+	if !added || !o.FetchChangelog {
+		return
 	}
+	from := i.PreviousVersion()
+	if from == "" {
+		return
+	}
+	i.Changelog = releasereport.Changelog(o.ChangelogFetcher, i.Sources, from, i.Version)
+	if i.Changelog != "" {
+		log.Logger().Infof("found changelog for %s from %s to %s", info(rel.Name), info(from), info(i.Version))
+	}
+}
+
+func (o *Options) enrichChartMetadata(i *releasereport.ReleaseInfo, repo *state.RepositorySpec, rel *state.ReleaseSpec, ns string) (err error) {
 	// lets see if we can find the previous data in the previous release
 	if nsMap, found := o.PreviousNamespaceCharts[ns]; found {
 		ch := nsMap[rel.Name]
 		if ch != nil {
 			if ch.Version == rel.Version {
 				*i = *ch
-				// let's clear the old ingress/app URLs
+				// let's clear the old ingress/app URLs and images
 				i.ApplicationURL = ""
 				i.Ingresses = nil
+				i.Images = nil
 				return nil
 			}
 			i.FirstDeployed = ch.FirstDeployed
@@ -360,6 +464,16 @@ func (o *Options) enrichChartMetadata(i *releasereport.ReleaseInfo, repo *state.
 		return nil
 	}
 
+	if repo.OCI {
+		metadata, err := o.ChartClient.ChartMetadata(chartrepos.FromRepositorySpec(repo), name, i.Version)
+		if err != nil {
+			log.Logger().Warnf("failed to find metadata of chart %s version %s in OCI registry %s: %s", info(name), i.Version, repoURL, err.Error())
+			return nil
+		}
+		i.Metadata = *metadata
+		return nil
+	}
+
 	indexFile, exists := o.RepositoryInfo[i.RepositoryURL]
 	if !exists {
 		repoName, err = helmer.AddHelmRepoIfMissing(o.HelmClient, repoURL, repoName, repo.Username, repo.Password)
@@ -417,6 +531,10 @@ func (o *Options) discoverResources(ci *releasereport.ReleaseInfo, ns string, re
 			if err != nil {
 				return errors.Wrapf(err, "failed to discover ingress")
 			}
+			ci.Images, err = releasereport.DiscoverImages(chartDir)
+			if err != nil {
+				return errors.Wrapf(err, "failed to discover images")
+			}
 			return nil
 		}
 	}
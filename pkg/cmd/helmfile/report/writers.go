@@ -0,0 +1,121 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FormatMarkdown the markdown README.md report
+	FormatMarkdown = "markdown"
+	// FormatJSON the releases.json report
+	FormatJSON = "json"
+	// FormatCSV the releases.csv report
+	FormatCSV = "csv"
+	// FormatHTML the static HTML site report
+	FormatHTML = "html"
+	// FormatCycloneDX the CycloneDX SBOM of the charts and their images
+	FormatCycloneDX = "cyclonedx"
+)
+
+// Writer writes a report of the releases into an output directory
+type Writer interface {
+	// Write writes the report returning the paths of the generated files
+	Write(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error)
+}
+
+// WriterFunc allows a function to be used as a Writer
+type WriterFunc func(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error)
+
+// Write writes the report
+func (f WriterFunc) Write(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error) {
+	return f(outDir, charts)
+}
+
+// Writers the report writers indexed by format
+var Writers = map[string]Writer{
+	FormatMarkdown:  WriterFunc(writeMarkdown),
+	FormatJSON:      WriterFunc(writeJSON),
+	FormatCSV:       WriterFunc(writeCSV),
+	FormatHTML:      WriterFunc(WriteHTMLSite),
+	FormatCycloneDX: WriterFunc(writeCycloneDX),
+}
+
+// WriterFormats returns the sorted names of the supported formats
+func WriterFormats() []string {
+	var answer []string
+	for k := range Writers {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+func writeMarkdown(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error) {
+	md, err := ToMarkdown(charts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert charts to markdown")
+	}
+	path := filepath.Join(outDir, "README.md")
+	err = os.WriteFile(path, []byte(md), files.DefaultFileWritePermissions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to save %s", path)
+	}
+	return []string{path}, nil
+}
+
+func writeJSON(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error) {
+	path := filepath.Join(outDir, "releases.json")
+	err := saveJSON(path, charts)
+	if err != nil {
+		return nil, err
+	}
+	return []string{path}, nil
+}
+
+func writeCSV(outDir string, charts []*releasereport.NamespaceReleases) ([]string, error) {
+	path := filepath.Join(outDir, "releases.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create file %s", path)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	rows := [][]string{
+		{"namespace", "release", "chart", "version", "appVersion", "repositoryName", "repositoryUrl", "applicationUrl", "logsUrl", "metricsUrl", "resourcePath", "images"},
+	}
+	for _, ns := range charts {
+		for _, r := range ns.Releases {
+			rows = append(rows, []string{
+				ns.Namespace, r.ReleaseName, r.Name, r.Version, r.AppVersion, r.RepositoryName, r.RepositoryURL,
+				r.ApplicationURL, r.LogsURL, r.MetricsURL, r.ResourcesPath, strings.Join(r.Images, " "),
+			})
+		}
+	}
+	err = w.WriteAll(rows)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to write CSV file %s", path)
+	}
+	return []string{path}, nil
+}
+
+func saveJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal %s", path)
+	}
+	err = os.WriteFile(path, append(data, '\n'), files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package report_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/report"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportWriters(t *testing.T) {
+	var charts []*releasereport.NamespaceReleases
+
+	sourceFile := filepath.Join("testdata", "releases.yaml")
+	err := yamls.LoadFile(sourceFile, &charts)
+	require.NoError(t, err, "failed to load file %s", sourceFile)
+
+	releaseCount := 0
+	for _, ns := range charts {
+		releaseCount += len(ns.Releases)
+	}
+
+	tmpDir := t.TempDir()
+	for _, format := range report.WriterFormats() {
+		paths, err := report.Writers[format].Write(tmpDir, charts)
+		require.NoError(t, err, "failed to write format %s", format)
+		require.NotEmpty(t, paths, "no files generated for format %s", format)
+		for _, p := range paths {
+			assert.FileExists(t, p, "generated file for format %s", format)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(tmpDir, "releases.csv"))
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err, "failed to parse CSV")
+	assert.Len(t, rows, releaseCount+1, "CSV rows including the header")
+
+	var jsonCharts []*releasereport.NamespaceReleases
+	data, err := os.ReadFile(filepath.Join(tmpDir, "releases.json"))
+	require.NoError(t, err)
+	err = json.Unmarshal(data, &jsonCharts)
+	require.NoError(t, err, "failed to parse JSON")
+	assert.Len(t, jsonCharts, len(charts), "JSON namespaces")
+
+	assert.FileExists(t, filepath.Join(tmpDir, report.SiteDir, "index.html"))
+	releasePage := filepath.Join(tmpDir, report.SiteDir, "jx-staging", "nodey545.html")
+	data, err = os.ReadFile(releasePage)
+	require.NoError(t, err, "failed to load %s", releasePage)
+	assert.Contains(t, string(data), "http://nodey545-jx-staging.34.105.246.143.xip.io", "ingress in the release page")
+	assert.Contains(t, string(data), "gcr.io/myorg/nodey545:3.0.46", "image in the release page")
+
+	bom := &report.CycloneDXBOM{}
+	data, err = os.ReadFile(filepath.Join(tmpDir, report.CycloneDXFileName))
+	require.NoError(t, err)
+	err = json.Unmarshal(data, bom)
+	require.NoError(t, err, "failed to parse SBOM")
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+
+	images := map[string]report.CycloneDXComponent{}
+	charts2 := 0
+	for _, c := range bom.Components {
+		switch c.Type {
+		case "container":
+			images[c.Name] = c
+		case "application":
+			charts2++
+		}
+	}
+	assert.Equal(t, releaseCount, charts2, "chart components")
+	require.Len(t, images, 3, "image components")
+	assert.Equal(t, "3.0.46", images["gcr.io/myorg/nodey545"].Version)
+	assert.Equal(t, "pkg:docker/gcr.io/myorg/nodey545-init@sha256%3A0123456789abcdef", images["gcr.io/myorg/nodey545-init"].PURL)
+	assert.Equal(t, "pkg:docker/gcr.io/myorg/nodey545-sidecar@sha256%3Afedcba9876543210", images["gcr.io/myorg/nodey545-sidecar"].PURL)
+	assert.Empty(t, bom.Metadata.Timestamp, "the SBOM should not change every time it is generated")
+}
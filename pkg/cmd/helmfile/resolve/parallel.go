@@ -0,0 +1,119 @@
+package resolve
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
+	"github.com/pkg/errors"
+)
+
+// DefaultParallelism the default number of helmfiles resolved concurrently
+const DefaultParallelism = 4
+
+// processHelmfiles processes the helmfiles using a bounded pool of workers. Any error is reported for the first
+// helmfile in the list that failed so that the results do not depend on the scheduling of the workers
+func (o *Options) processHelmfiles(list []helmfiles.Helmfile) error {
+	workers := o.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(list) {
+		workers = len(list)
+	}
+
+	errs := make([]error, len(list))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				err := o.processHelmfile(list[i])
+				if err != nil {
+					errs[i] = errors.Wrapf(err, "failed to process helmfile %s", list[i].Filepath)
+				}
+			}
+		}()
+	}
+	for i := range list {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	// lets keep the results in a stable order
+	order := map[string]int{}
+	for i := range list {
+		order[list[i].Filepath] = i
+	}
+	sort.SliceStable(o.Results.HeldBack, func(i, j int) bool {
+		h1 := o.Results.HeldBack[i]
+		h2 := o.Results.HeldBack[j]
+		if h1.Helmfile != h2.Helmfile {
+			return order[h1.Helmfile] < order[h2.Helmfile]
+		}
+		return h1.Release < h2.Release
+	})
+	sort.SliceStable(o.Results.ReadOnlyHelmfiles, func(i, j int) bool {
+		return order[o.Results.ReadOnlyHelmfiles[i]] < order[o.Results.ReadOnlyHelmfiles[j]]
+	})
+	return nil
+}
+
+// addHeldBack records a release which was held back
+func (o *Options) addHeldBack(h HeldBackRelease) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.Results.HeldBack = append(o.Results.HeldBack, h)
+}
+
+// addReadOnlyHelmfile records a templated helmfile which could not be saved
+func (o *Options) addReadOnlyHelmfile(path string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.Results.ReadOnlyHelmfiles = append(o.Results.ReadOnlyHelmfiles, path)
+}
+
+// versionCache caches the version stream lookups of a run so they can be shared across helmfiles
+type versionCache struct {
+	resolver *versionstream.VersionResolver
+	lock     sync.Mutex
+	versions map[string]*versionstream.StableVersion
+}
+
+func newVersionCache(resolver *versionstream.VersionResolver) *versionCache {
+	return &versionCache{
+		resolver: resolver,
+		versions: map[string]*versionstream.StableVersion{},
+	}
+}
+
+// StableVersion returns the stable version of the given kind and name
+func (c *versionCache) StableVersion(kind versionstream.VersionKind, name string) (*versionstream.StableVersion, error) {
+	key := string(kind) + ":" + name
+
+	c.lock.Lock()
+	answer := c.versions[key]
+	c.lock.Unlock()
+	if answer != nil {
+		return answer, nil
+	}
+
+	answer, err := c.resolver.StableVersion(kind, name)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.versions[key] = answer
+	c.lock.Unlock()
+	return answer, nil
+}
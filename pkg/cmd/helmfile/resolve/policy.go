@@ -0,0 +1,176 @@
+package resolve
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmhelpers"
+	"github.com/pkg/errors"
+)
+
+const (
+	// UpdateScopePatch only adopt patch releases
+	UpdateScopePatch = "patch"
+	// UpdateScopeMinor only adopt minor and patch releases
+	UpdateScopeMinor = "minor"
+	// UpdateScopeMajor adopt any release
+	UpdateScopeMajor = "major"
+)
+
+// HeldBackRelease a release which was not updated to the version stream version due to its update policy
+type HeldBackRelease struct {
+	Helmfile         string
+	Release          string
+	Chart            string
+	Version          string
+	AvailableVersion string
+	Reason           string
+}
+
+// ChartVersionTimes finds when chart versions were created
+type ChartVersionTimes interface {
+	// Created returns when the chart version was created in the repository or a zero time if it is not known
+	Created(repoURL, chartName, version string) (time.Time, error)
+}
+
+// isOCIRepository returns true if the repository with the given name in the helmfile is an OCI registry
+func isOCIRepository(helmState *state.HelmState, name string) bool {
+	for i := range helmState.Repositories {
+		if helmState.Repositories[i].Name == name {
+			return helmState.Repositories[i].OCI
+		}
+	}
+	return false
+}
+
+func (o *Options) updatePolicyReason(release *state.ReleaseSpec, chartName, repository string, oci bool, version string) (string, error) {
+	if o.ChartVersionTimes == nil {
+		o.ChartVersionTimes = NewChartVersionTimes()
+	}
+	return HoldBackReason(o.ChartVersionTimes, release, chartName, repository, oci, version)
+}
+
+// HoldBackReason returns the reason the release should not be updated to the given version due to its update labels
+// or an empty string if it can be updated
+func HoldBackReason(times ChartVersionTimes, release *state.ReleaseSpec, chartName, repository string, oci bool, version string) (string, error) {
+	if release.Labels == nil {
+		return "", nil
+	}
+	scope := strings.TrimSpace(release.Labels[helmhelpers.UpdateScopeLabel])
+	constraint := strings.TrimSpace(release.Labels[helmhelpers.UpdateConstraintLabel])
+	minAgeText := strings.TrimSpace(release.Labels[helmhelpers.UpdateMinAgeLabel])
+	if scope == "" && constraint == "" && minAgeText == "" {
+		return "", nil
+	}
+
+	if scope != "" || constraint != "" {
+		newVersion, err := semver.NewVersion(version)
+		if err != nil {
+			return fmt.Sprintf("version %s is not a semantic version", version), nil
+		}
+		if scope != "" {
+			currentVersion, err := semver.NewVersion(release.Version)
+			if err != nil {
+				return fmt.Sprintf("current version %s is not a semantic version", release.Version), nil
+			}
+			reason, err := scopeReason(scope, currentVersion, newVersion)
+			if err != nil {
+				return "", errors.Wrapf(err, "invalid label %s on release %s", helmhelpers.UpdateScopeLabel, release.Name)
+			}
+			if reason != "" {
+				return reason, nil
+			}
+		}
+		if constraint != "" {
+			c, err := semver.NewConstraint(constraint)
+			if err != nil {
+				return "", errors.Wrapf(err, "invalid label %s on release %s", helmhelpers.UpdateConstraintLabel, release.Name)
+			}
+			if !c.Check(newVersion) {
+				return fmt.Sprintf("version does not satisfy constraint %s", constraint), nil
+			}
+		}
+	}
+
+	if minAgeText != "" {
+		minAge, err := ParseAge(minAgeText)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid label %s on release %s", helmhelpers.UpdateMinAgeLabel, release.Name)
+		}
+		if oci || strings.HasPrefix(repository, chartrepos.OCIScheme) || repository == "" {
+			return "cannot determine the age of the version for the minimum age " + minAgeText, nil
+		}
+		created, err := times.Created(repository, chartName, version)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to find when chart %s version %s was created", chartName, version)
+		}
+		if created.IsZero() {
+			return "cannot determine the age of the version for the minimum age " + minAgeText, nil
+		}
+		age := time.Since(created)
+		if age < minAge {
+			return fmt.Sprintf("version is only %s old which is less than the minimum age %s", age.Round(time.Hour).String(), minAgeText), nil
+		}
+	}
+	return "", nil
+}
+
+func scopeReason(scope string, currentVersion, newVersion *semver.Version) (string, error) {
+	switch scope {
+	case UpdateScopeMajor:
+		return "", nil
+	case UpdateScopeMinor:
+		if newVersion.Major() != currentVersion.Major() {
+			return "major version change not allowed by update scope minor", nil
+		}
+		return "", nil
+	case UpdateScopePatch:
+		if newVersion.Major() != currentVersion.Major() || newVersion.Minor() != currentVersion.Minor() {
+			return "minor or major version change not allowed by update scope patch", nil
+		}
+		return "", nil
+	default:
+		return "", errors.Errorf("unsupported update scope %s. Supported values: %s, %s, %s", scope, UpdateScopePatch, UpdateScopeMinor, UpdateScopeMajor)
+	}
+}
+
+// ParseAge parses a duration which may also use a 'd' suffix for days
+func ParseAge(text string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(text, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse days %s", text)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(text)
+}
+
+// NewChartVersionTimes creates a ChartVersionTimes which uses the index.yaml of chart repositories
+func NewChartVersionTimes() ChartVersionTimes {
+	return &indexChartVersionTimes{
+		client: chartrepos.NewClient(),
+	}
+}
+
+type indexChartVersionTimes struct {
+	client *chartrepos.HTTPClient
+}
+
+// Created returns when the chart version was created in the index of the repository
+func (c *indexChartVersionTimes) Created(repoURL, chartName, version string) (time.Time, error) {
+	index, err := c.client.Index(repoURL)
+	if err != nil {
+		return time.Time{}, err
+	}
+	cv, err := index.Get(chartName, version)
+	if err != nil || cv == nil {
+		return time.Time{}, nil
+	}
+	return cv.Created, nil
+}
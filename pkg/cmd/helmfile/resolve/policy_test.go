@@ -0,0 +1,110 @@
+package resolve_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/resolve"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChartVersionTimes map[string]time.Time
+
+func (f fakeChartVersionTimes) Created(_, chartName, version string) (time.Time, error) {
+	return f[chartName+"-"+version], nil
+}
+
+func TestHoldBackReason(t *testing.T) {
+	now := time.Now()
+	times := fakeChartVersionTimes{
+		"mychart-1.3.0": now.Add(-24 * time.Hour),
+		"mychart-1.2.4": now.Add(-30 * 24 * time.Hour),
+	}
+
+	testCases := []struct {
+		name     string
+		labels   map[string]string
+		version  string
+		oci      bool
+		held     bool
+		hasError bool
+	}{
+		{name: "no-labels", version: "2.0.0"},
+		{name: "patch-allowed", labels: map[string]string{helmhelpers.UpdateScopeLabel: "patch"}, version: "1.2.4"},
+		{name: "patch-minor", labels: map[string]string{helmhelpers.UpdateScopeLabel: "patch"}, version: "1.3.0", held: true},
+		{name: "minor-allowed", labels: map[string]string{helmhelpers.UpdateScopeLabel: "minor"}, version: "1.3.0"},
+		{name: "minor-major", labels: map[string]string{helmhelpers.UpdateScopeLabel: "minor"}, version: "2.0.0", held: true},
+		{name: "major-allowed", labels: map[string]string{helmhelpers.UpdateScopeLabel: "major"}, version: "2.0.0"},
+		{name: "invalid-scope", labels: map[string]string{helmhelpers.UpdateScopeLabel: "cheese"}, version: "2.0.0", hasError: true},
+		{name: "constraint-allowed", labels: map[string]string{helmhelpers.UpdateConstraintLabel: ">=1.2 <2"}, version: "1.3.0"},
+		{name: "constraint-held", labels: map[string]string{helmhelpers.UpdateConstraintLabel: ">=1.2 <2"}, version: "2.0.0", held: true},
+		{name: "invalid-constraint", labels: map[string]string{helmhelpers.UpdateConstraintLabel: "not a constraint"}, version: "2.0.0", hasError: true},
+		{name: "min-age-allowed", labels: map[string]string{helmhelpers.UpdateMinAgeLabel: "7d"}, version: "1.2.4"},
+		{name: "min-age-held", labels: map[string]string{helmhelpers.UpdateMinAgeLabel: "72h"}, version: "1.3.0", held: true},
+		{name: "min-age-unknown", labels: map[string]string{helmhelpers.UpdateMinAgeLabel: "72h"}, version: "1.4.0", held: true},
+		{name: "min-age-oci", labels: map[string]string{helmhelpers.UpdateMinAgeLabel: "1h"}, version: "1.2.4", oci: true, held: true},
+		{name: "invalid-min-age", labels: map[string]string{helmhelpers.UpdateMinAgeLabel: "soon"}, version: "1.2.4", hasError: true},
+	}
+
+	for _, tc := range testCases {
+		release := &state.ReleaseSpec{
+			Name:    "myrelease",
+			Chart:   "myrepo/mychart",
+			Version: "1.2.3",
+			Labels:  tc.labels,
+		}
+		reason, err := resolve.HoldBackReason(times, release, "mychart", "https://example.com/charts", tc.oci, tc.version)
+		if tc.hasError {
+			require.Error(t, err, "should have failed for %s", tc.name)
+			continue
+		}
+		require.NoError(t, err, "failed for %s", tc.name)
+		if tc.held {
+			assert.NotEmpty(t, reason, "should have held back for %s", tc.name)
+		} else {
+			assert.Empty(t, reason, "should not have held back for %s", tc.name)
+		}
+		t.Logf("%s: %s\n", tc.name, reason)
+	}
+}
+
+func TestChartVersionTimes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`apiVersion: v1
+entries:
+  mychart:
+  - name: mychart
+    version: 1.2.3
+    created: "2024-01-02T03:04:05Z"
+`))
+	}))
+	defer server.Close()
+
+	times := resolve.NewChartVersionTimes()
+	created, err := times.Created(server.URL, "mychart", "1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), created.UTC())
+
+	created, err = times.Created(server.URL, "mychart", "9.9.9")
+	require.NoError(t, err)
+	assert.True(t, created.IsZero(), "should not find a missing version")
+}
+
+func TestParseAge(t *testing.T) {
+	d, err := resolve.ParseAge("3d")
+	require.NoError(t, err)
+	assert.Equal(t, 72*time.Hour, d)
+
+	d, err = resolve.ParseAge("90m")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+}
@@ -0,0 +1,129 @@
+package resolve
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// gitGetter the go-getter prefix of remote charts fetched from git
+const gitGetter = "git"
+
+// IsRemoteChart returns true if the chart is fetched via go-getter such as 'git::https://github.com/myorg/charts.git@charts/mychart?ref=v1.2.3'
+func IsRemoteChart(chart string) bool {
+	return strings.Contains(chart, "::")
+}
+
+// RemoteChartGitRepository returns the git repository of a git based remote chart in the form used by the version stream
+// such as 'github.com/myorg/charts' along with the parsed URL. An empty repository is returned if the chart is not fetched from git
+func RemoteChartGitRepository(chart string) (string, *url.URL, error) {
+	getter, src, found := strings.Cut(chart, "::")
+	if !found || getter != gitGetter {
+		return "", nil, nil
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to parse remote chart URL %s", src)
+	}
+	// lets remove the path of the chart inside the git repository
+	repoPath, _, _ := strings.Cut(u.Path, "@")
+	gitRepo := strings.TrimSuffix(repoPath, ".git")
+	if u.Host != "" {
+		gitRepo = stringhelpers.UrlJoin(u.Host, gitRepo)
+	}
+	return gitRepo, u, nil
+}
+
+// resolveRemoteChart pins the 'ref' of a git based remote chart to the version of the git repository in the version stream
+func (o *Options) resolveRemoteChart(release *state.ReleaseSpec, helmfile string) error {
+	gitRepo, u, err := RemoteChartGitRepository(release.Chart)
+	if err != nil {
+		log.Logger().Infof("ignoring remote chart %s of release %s due to: %s", release.Chart, release.Name, err.Error())
+		return nil
+	}
+	if gitRepo == "" {
+		log.Logger().Debugf("ignoring remote chart %s release %s as it is not fetched from git", release.Chart, release.Name)
+		return nil
+	}
+	if IsLabelValue(release, helmhelpers.VersionLabel, helmhelpers.LockLabelValue) {
+		return nil
+	}
+
+	versionProperties, err := o.versions.StableVersion(versionstream.KindGit, gitRepo)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find version of git repository %s", gitRepo)
+	}
+	version := versionProperties.Version
+	if version == "" {
+		log.Logger().Debugf("could not find version for git repository %s of remote chart %s", gitRepo, release.Chart)
+		return nil
+	}
+	if o.ChartVersionTimes == nil {
+		o.ChartVersionTimes = NewChartVersionTimes()
+	}
+	ref := u.Query().Get("ref")
+	reason, err := PinRemoteChart(o.ChartVersionTimes, release, u, version, o.UpdateMode)
+	if err != nil {
+		return errors.Wrapf(err, "failed to pin remote chart of release %s", release.Name)
+	}
+	if reason != "" {
+		log.Logger().Infof("holding back remote chart %s at ref %s: %s", info(release.Chart), info(ref), reason)
+		o.addHeldBack(HeldBackRelease{
+			Helmfile:         helmfile,
+			Release:          release.Name,
+			Chart:            release.Chart,
+			Version:          ref,
+			AvailableVersion: RemoteChartRef(version),
+			Reason:           reason,
+		})
+	}
+	return nil
+}
+
+// RemoteChartRef returns the git ref of a remote chart for the given version using the same tag convention as terraform module sources
+func RemoteChartRef(version string) string {
+	if version != "" && !strings.HasPrefix(version, "v") && unicode.IsDigit(rune(version[0])) {
+		return "v" + version
+	}
+	return version
+}
+
+// PinRemoteChart pins the 'ref' of the remote chart of the release with the parsed URL to the given version.
+// An existing ref is only changed in update mode if the update labels of the release allow it, otherwise the reason
+// the chart is held back is returned
+func PinRemoteChart(times ChartVersionTimes, release *state.ReleaseSpec, u *url.URL, version string, updateMode bool) (string, error) {
+	if version == "" {
+		return "", nil
+	}
+	version = RemoteChartRef(version)
+	query := u.Query()
+	ref := query.Get("ref")
+	if ref == version || (ref != "" && !updateMode) {
+		return "", nil
+	}
+	if ref != "" {
+		// lets compare the refs as the release version for the update policy
+		current := *release
+		current.Version = ref
+		reason, err := HoldBackReason(times, &current, "", "", false, version)
+		if err != nil {
+			return "", err
+		}
+		if reason != "" {
+			return reason, nil
+		}
+	}
+	query.Set("ref", version)
+	u.RawQuery = query.Encode()
+	chart := gitGetter + "::" + u.String()
+	log.Logger().Debugf("resolved remote chart %s to %s", release.Chart, chart)
+	release.Chart = chart
+	return "", nil
+}
@@ -0,0 +1,103 @@
+package resolve_test
+
+import (
+	"testing"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/resolve"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteChartGitRepository(t *testing.T) {
+	testCases := []struct {
+		chart    string
+		expected string
+	}{
+		{
+			chart:    "git::https://github.com/myorg-ops/charts.git@openshift/templates/vault-operator?ref=master",
+			expected: "github.com/myorg-ops/charts",
+		},
+		{
+			chart:    "git::https://github.com/myorg-ops/charts@vault-operator",
+			expected: "github.com/myorg-ops/charts",
+		},
+		{
+			chart:    "s3::https://s3.amazonaws.com/mybucket/charts/vault-operator",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.True(t, resolve.IsRemoteChart(tc.chart), "chart %s should be remote", tc.chart)
+
+		got, _, err := resolve.RemoteChartGitRepository(tc.chart)
+		require.NoError(t, err, "failed to parse chart %s", tc.chart)
+		assert.Equal(t, tc.expected, got, "for chart %s", tc.chart)
+	}
+	assert.False(t, resolve.IsRemoteChart("jenkins-x/tekton"))
+}
+
+func TestRemoteChartGitRepositoryInvalidURL(t *testing.T) {
+	_, _, err := resolve.RemoteChartGitRepository("git::git@github.com:myorg-ops/charts.git@vault-operator?ref=v1.0.0")
+	require.Error(t, err, "should fail to parse a scp style URL")
+}
+
+func TestPinRemoteChart(t *testing.T) {
+	const chartURL = "git::https://github.com/myorg-ops/charts.git@vault-operator"
+
+	testCases := []struct {
+		name       string
+		ref        string
+		labels     map[string]string
+		version    string
+		updateMode bool
+		expected   string
+		held       bool
+	}{
+		{name: "no-ref", version: "1.3.0", expected: "v1.3.0"},
+		{name: "existing-ref", ref: "v1.2.3", version: "1.3.0", expected: "v1.2.3"},
+		{name: "update", ref: "v1.2.3", version: "2.0.0", updateMode: true, expected: "v2.0.0"},
+		{name: "update-scope-allowed", ref: "v1.2.3", labels: map[string]string{helmhelpers.UpdateScopeLabel: "minor"}, version: "1.3.0", updateMode: true, expected: "v1.3.0"},
+		{name: "update-scope-held", ref: "v1.2.3", labels: map[string]string{helmhelpers.UpdateScopeLabel: "patch"}, version: "1.3.0", updateMode: true, expected: "v1.2.3", held: true},
+		{name: "update-constraint-held", ref: "v1.2.3", labels: map[string]string{helmhelpers.UpdateConstraintLabel: "<2"}, version: "2.0.0", updateMode: true, expected: "v1.2.3", held: true},
+		{name: "update-min-age-held", ref: "v1.2.3", labels: map[string]string{helmhelpers.UpdateMinAgeLabel: "7d"}, version: "1.3.0", updateMode: true, expected: "v1.2.3", held: true},
+		{name: "no-version", ref: "v1.2.3", updateMode: true, expected: "v1.2.3"},
+	}
+
+	for _, tc := range testCases {
+		chart := chartURL
+		if tc.ref != "" {
+			chart += "?ref=" + tc.ref
+		}
+		release := &state.ReleaseSpec{
+			Name:   "vault-operator",
+			Chart:  chart,
+			Labels: tc.labels,
+		}
+		_, u, err := resolve.RemoteChartGitRepository(chart)
+		require.NoError(t, err, "failed to parse chart %s for %s", chart, tc.name)
+
+		reason, err := resolve.PinRemoteChart(fakeChartVersionTimes{}, release, u, tc.version, tc.updateMode)
+		require.NoError(t, err, "failed to pin chart for %s", tc.name)
+		assert.Equal(t, chartURL+"?ref="+tc.expected, release.Chart, "chart for %s", tc.name)
+		if tc.held {
+			assert.NotEmpty(t, reason, "should be held back for %s", tc.name)
+		} else {
+			assert.Empty(t, reason, "should not be held back for %s", tc.name)
+		}
+	}
+}
+
+func TestRemoteChartRef(t *testing.T) {
+	testCases := map[string]string{
+		"1.2.3":  "v1.2.3",
+		"v1.2.3": "v1.2.3",
+		"main":   "main",
+		"":       "",
+	}
+	for version, expected := range testCases {
+		assert.Equal(t, expected, resolve.RemoteChartRef(version), "RemoteChartRef for %q", version)
+	}
+}
@@ -2,16 +2,17 @@ package resolve
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/jxtmpl/reqvalues"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/migrations"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/pipelinecatalogs"
-	"github.com/jenkins-x-plugins/jx-gitops/pkg/quickstarthelpers"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
 
@@ -47,6 +48,20 @@ var (
 
 	cmdLong = templates.LongDesc(`
 		Resolves the helmfile.yaml from the version stream to specify versions and helm values
+
+		When using --update the following labels on a release can hold back new versions from the version stream:
+
+		* update.jenkins-x.io/scope: patch, minor or major to limit the kind of version change
+		* update.jenkins-x.io/constraint: a semver constraint such as '>=1.4 <2' the new version must satisfy
+		* update.jenkins-x.io/min-age: the minimum age such as '72h' or '7d' of a chart version before it is adopted
+
+		Remote charts fetched from git such as 'git::https://github.com/myorg/charts.git@charts/mychart?ref=master' have their 'ref'
+		pinned to the version of the git repository in the version stream unless the release has the 'version.jenkins-x.io: lock' label.
+		The update labels above also apply when changing an existing 'ref' though a min-age always holds back remote charts as the age of a git tag is not known.
+
+		Templated helmfiles (such as 'helmfile.yaml.gotmpl') are rendered with the values of the default environment before they are resolved.
+		Changes to the parts of a templated helmfile which contain no templates are saved but if a templated part would change the helmfile
+		is reported as read only and left as it is.
 `)
 
 	cmdExample = templates.Examples(`
@@ -73,12 +88,21 @@ type Options struct {
 	TestOutOfCluster        bool
 	Gitter                  gitclient.Interface
 	prefixes                *versionstream.RepositoryPrefixes
+	migrations              *migrations.Runner
 	Results                 Results
 	AddEnvironmentPipelines bool
+	ChartVersionTimes       ChartVersionTimes
+	Parallelism             int
+	versions                *versionCache
+	lock                    sync.Mutex
 }
 
 type Results struct {
 	RequirementsValuesFileName string
+	HeldBack                   []HeldBackRelease
+
+	// ReadOnlyHelmfiles the templated helmfiles which could be read but not rewritten without losing the template
+	ReadOnlyHelmfiles []string
 }
 
 // NewCmdHelmfileResolve creates a command object for the command
@@ -110,6 +134,7 @@ func (o *Options) AddFlags(cmd *cobra.Command, prefix string) {
 	cmd.Flags().StringVarP(&o.Helmfile, "helmfile", "", "", "the helmfile to resolve. If not specified defaults to 'helmfile.yaml' in the dir")
 	cmd.Flags().StringVarP(&o.GitCommitMessage, prefix+"commit-message", "", "chore: generated kubernetes resources from helm chart", "the git commit message used")
 	cmd.Flags().StringVarP(&o.Namespace, "namespace", "", "jx", "the default namespace if none is specified in the helmfile.yaml")
+	cmd.Flags().IntVarP(&o.Parallelism, "parallelism", "", DefaultParallelism, "the maximum number of helmfiles to resolve concurrently")
 	cmd.Flags().BoolVarP(&o.AddEnvironmentPipelines, "add-environment-pipelines", "", false, "skips the custom upgrade step for adding .lighthouse folder")
 
 	// git commit stuff....
@@ -161,6 +186,15 @@ func (o *Options) Validate() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to load repository prefixes at %s", o.VersionStreamDir)
 	}
+	o.prefixes = versionstreamer.IndexRepositoryPrefixes(o.prefixes)
+	o.versions = newVersionCache(o.Options.Resolver)
+
+	if o.Parallelism <= 0 {
+		o.Parallelism = DefaultParallelism
+	}
+	if o.ChartVersionTimes == nil {
+		o.ChartVersionTimes = NewChartVersionTimes()
+	}
 
 	if o.CommandRunner == nil {
 		o.CommandRunner = cmdrunner.QuietCommandRunner
@@ -183,6 +217,19 @@ func (o *Options) Run() error {
 	count := 0
 
 	if o.UpdateMode {
+		o.migrations, err = migrations.NewRunner(&migrations.Context{
+			Dir:                     o.Dir,
+			Requirements:            o.Options.Requirements,
+			Resolver:                resolver,
+			CommandRunner:           o.CommandRunner,
+			Gitter:                  o.Git(),
+			KptBinary:               o.KptBinary,
+			AddEnvironmentPipelines: o.AddEnvironmentPipelines,
+		}, o.VersionStreamDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load migrations")
+		}
+
 		increment, err := o.upgradeHelmfileStructure(o.Dir)
 		count += increment
 		if err != nil {
@@ -218,13 +265,19 @@ func (o *Options) Run() error {
 		return errors.Wrapf(err, "error gathering helmfiles")
 	}
 
-	for _, helmfile := range includedHelmfiles {
-		err := o.processHelmfile(helmfile)
+	err = o.processHelmfiles(includedHelmfiles)
+	if err != nil {
+		return err
+	}
+	if len(o.Results.ReadOnlyHelmfiles) > 0 {
+		log.Logger().Warnf("the following templated helmfiles could only be read and not safely rewritten so need to be updated by hand: %s", strings.Join(o.Results.ReadOnlyHelmfiles, ", "))
+	}
+
+	if o.migrations != nil {
+		err = o.migrations.Save()
 		if err != nil {
-			return errors.Wrapf(err, "failed to process helmfile %s", helmfile.Filepath)
+			return errors.Wrapf(err, "failed to record applied migrations")
 		}
-		// ToDo: What are we trying to do here?
-		count += 0
 	}
 
 	if !o.DoGitCommit {
@@ -251,16 +304,19 @@ func (o *Options) processHelmfile(helmfile helmfiles.Helmfile) error {
 	}
 
 	for _, helmState := range helmStates {
-		if o.UpdateMode {
-			err = o.CustomUpgrades(helmState)
+		if o.migrations != nil {
+			// migrations may run commands on the whole repository so lets run them one at a time
+			o.lock.Lock()
+			_, err = o.migrations.Run(helmState)
+			o.lock.Unlock()
 			if err != nil {
-				return errors.Wrapf(err, "failed to perform custom upgrades")
+				return errors.Wrapf(err, "failed to perform migrations")
 			}
 		}
 
 		err = o.resolveHelmfile(helmState, helmfile)
 		if err != nil {
-			return errors.Wrapf(err, "failed to resolve helmfile %s", helmfile)
+			return errors.Wrapf(err, "failed to resolve helmfile %s", helmfile.Filepath)
 		}
 
 		if o.UpdateMode {
@@ -294,12 +350,20 @@ func (o *Options) processHelmfile(helmfile helmfiles.Helmfile) error {
 		}
 	}
 
-	return helmfiles.SaveHelmfile(path, helmStates)
+	err = helmfiles.SaveHelmfile(path, helmStates)
+	if helmfiles.IsReadOnly(err) {
+		log.Logger().Warnf("not saving changes: %s", err.Error())
+		o.addReadOnlyHelmfile(path)
+		return nil
+	}
+	return err
 }
 
 func (o *Options) saveNamespaceJXValuesFile(helmfileDir, ns string) error {
 	jxReqValuesFileName := filepath.Join(helmfileDir, reqvalues.RequirementsValuesFileName)
+	o.lock.Lock()
 	o.Results.RequirementsValuesFileName = reqvalues.RequirementsValuesFileName
+	o.lock.Unlock()
 	requirements := *o.Options.Requirements
 	subDomain := strings.ReplaceAll(requirements.Ingress.NamespaceSubDomain, "jx", ns)
 	requirements.Ingress.NamespaceSubDomain = subDomain
@@ -399,9 +463,13 @@ func (o *Options) resolveHelmfile(helmState *state.HelmState, helmfile helmfiles
 		if release.Name == "" {
 			release.Name = chartName
 		}
-		// ignore remote charts
-		if strings.Contains(fullChartName, "::") {
-			log.Logger().Debugf("ignoring remote chart %s release %s", fullChartName, release.Name)
+		// remote charts can only have their git ref resolved
+		if IsRemoteChart(fullChartName) {
+			err = o.resolveRemoteChart(&release, helmfile.Filepath)
+			if err != nil {
+				return err
+			}
+			helmState.Releases[i].Chart = release.Chart
 			continue
 		}
 
@@ -535,13 +603,13 @@ func (o *Options) ensureEnvironment(helmState *state.HelmState, helmfile helmfil
 
 func (o *Options) updateRelease(helmState *state.HelmState, prefix, chartName string, release *state.ReleaseSpec, fullChartName, repository string, helmfile helmfiles.Helmfile) error {
 	// first try and match using the prefix and release name as we might have a version stream folder that uses helm alias
-	versionProperties, err := o.Options.Resolver.StableVersion(versionstream.KindChart, prefix+"/"+release.Name)
+	versionProperties, err := o.versions.StableVersion(versionstream.KindChart, prefix+"/"+release.Name)
 	if err != nil {
 		return errors.Wrapf(err, "failed to find version number for chart %s", release.Name)
 	}
 	// let's fall back to using the full chart name
 	if versionProperties.Missing() {
-		versionProperties, err = o.Options.Resolver.StableVersion(versionstream.KindChart, fullChartName)
+		versionProperties, err = o.versions.StableVersion(versionstream.KindChart, fullChartName)
 		if err != nil {
 			return errors.Wrapf(err, "failed to find version number for chart %s", fullChartName)
 		}
@@ -575,17 +643,15 @@ func (o *Options) updateRelease(helmState *state.HelmState, prefix, chartName st
 			}
 			if !found {
 				repository, err = versionstreamer.MatchRepositoryPrefix(o.prefixes, versionProperties.ReplacementChartPrefix)
-				oci := strings.HasPrefix(repository, "oci://")
-				if oci {
-					repository = repository[len("oci://"):]
-				}
 				if err != nil {
 					return err
 				}
+				repo := chartrepos.NewRepository(versionProperties.ReplacementChartPrefix, repository, false)
+				repository = repo.URL
 				helmState.Repositories = append(helmState.Repositories, state.RepositorySpec{
-					Name: versionProperties.ReplacementChartPrefix,
-					URL:  repository,
-					OCI:  oci,
+					Name: repo.Name,
+					URL:  repo.URL,
+					OCI:  repo.OCI,
 				})
 			}
 		}
@@ -612,8 +678,24 @@ func (o *Options) updateRelease(helmState *state.HelmState, prefix, chartName st
 		release.Version = version
 		versionChanged = true
 	} else if o.UpdateMode && release.Version != version && version != "" {
-		release.Version = version
-		versionChanged = true
+		reason, err := o.updatePolicyReason(release, chartName, repository, isOCIRepository(helmState, prefix), version)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			log.Logger().Infof("holding back chart %s at version %s rather than %s: %s", info(fullChartName), info(release.Version), info(version), reason)
+			o.addHeldBack(HeldBackRelease{
+				Helmfile:         helmfile.Filepath,
+				Release:          release.Name,
+				Chart:            fullChartName,
+				Version:          release.Version,
+				AvailableVersion: version,
+				Reason:           reason,
+			})
+		} else {
+			release.Version = version
+			versionChanged = true
+		}
 	}
 	if versionChanged {
 		log.Logger().Debugf("resolved chart %s version %s", fullChartName, version)
@@ -723,346 +805,6 @@ func (o *Options) GitCommit(outDir, commitMessage string) error {
 	return nil
 }
 
-// CustomUpgrades performs custom upgrades outside of the version stream/kpt approach
-func (o *Options) CustomUpgrades(helmstate *state.HelmState) error {
-	if helmstate.Releases == nil {
-		return nil
-	}
-	err := o.migrateQuickstartsFile()
-	if err != nil {
-		return errors.Wrapf(err, "failed to migrate quickstarts file")
-	}
-
-	var versionStreamPath string
-	if helmstate.OverrideNamespace == "" {
-		versionStreamPath = "versionStream"
-	} else {
-		versionStreamPath = "../../versionStream"
-
-		// lets remove the old top level jx-values.yaml as we are using multi-level helmfiles
-		oldJXValues := filepath.Join(o.Dir, "jx-values.yaml")
-		exists, err := files.FileExists(oldJXValues)
-		if err != nil {
-			return errors.Wrapf(err, "failed to check if file exists %s", oldJXValues)
-		}
-		if exists {
-			err = os.Remove(oldJXValues)
-			if err != nil {
-				return errors.Wrapf(err, "failed to remove old file %s", oldJXValues)
-			}
-		}
-	}
-
-	requirementsResource, _, err := jxcore.LoadRequirementsConfig(o.Dir, false)
-	if err != nil {
-		return errors.Wrapf(err, "failed to load the requirements configuration")
-	}
-	requirements := &requirementsResource.Spec
-	// lets replace the old tekton repositories if they are being used
-	for i := range helmstate.Repositories {
-		repo := &helmstate.Repositories[i]
-		cleanURL := strings.TrimSuffix(repo.URL, "/")
-		switch cleanURL {
-		case "https://kubernetes-charts.storage.googleapis.com":
-			repo.URL = "https://charts.helm.sh/stable"
-		case "https://comcast.github.io/kuberhealthy/helm-repos":
-			repo.URL = "https://kuberhealthy.github.io/kuberhealthy/helm-repos"
-		case "https://godaddy.github.io/kubernetes-external-secrets":
-			repo.URL = "https://external-secrets.github.io/kubernetes-external-secrets"
-		case "https://chrismellard.github.io/kubernetes-external-secrets":
-			repo.URL = "https://external-secrets.github.io/kubernetes-external-secrets"
-		case "https://storage.googleapis.com/jenkinsxio/charts":
-			if repo.Name == "external-secrets" {
-				repo.URL = "https://external-secrets.github.io/kubernetes-external-secrets"
-			}
-		case "http://chartmuseum.jenkins-x.io":
-			repo.URL = "https://storage.googleapis.com/chartmuseum.jenkins-x.io"
-		}
-	}
-
-	// lets replace the old tekton chart if its being used
-	for i := range helmstate.Releases {
-		release := &helmstate.Releases[i]
-		if release.Chart == "jenkins-x/tekton" {
-			release.Chart = "cdf/tekton-pipeline"
-			release.Namespace = "tekton-pipelines"
-
-			// lets make sure we have a cdf repository
-			found := false
-			for k := range helmstate.Repositories {
-				repo := helmstate.Repositories[k]
-				if repo.Name == "cdf" {
-					found = true
-					break
-				}
-			}
-			if !found {
-				helmstate.Repositories = append(helmstate.Repositories, state.RepositorySpec{
-					Name: "cdf",
-					URL:  "https://cdfoundation.github.io/tekton-helm-chart",
-				})
-			}
-			break
-		}
-	}
-
-	addedJxgh := false
-
-	// lets replace the old jx3 charts
-	for i := range helmstate.Releases {
-		release := &helmstate.Releases[i]
-		names := strings.SplitN(release.Chart, "/", 2)
-		if len(names) == 2 && names[0] == "jx3" {
-			chartName := names[1]
-			name := release.Name
-			release.Chart = "jxgh/" + chartName
-			addedJxgh = true
-
-			for j := range release.Values {
-				v := release.Values[j]
-				s, ok := v.(string)
-				if !ok {
-					continue
-				}
-				// lets switch invalid paths to the one inside a chart repo folder
-				if s == fmt.Sprintf("%s/charts/jx3/%s/values.yaml.gotmpl", versionStreamPath, chartName) {
-					release.Values[j] = fmt.Sprintf("%s/charts/jxgh/%s/values.yaml.gotmpl", versionStreamPath, chartName)
-					break
-				}
-				if s == fmt.Sprintf("%s/charts/jx3/%s/values.yaml.gotmpl", versionStreamPath, name) {
-					release.Values[j] = fmt.Sprintf("%s/charts/jxgh/%s/values.yaml.gotmpl", versionStreamPath, name)
-					break
-				}
-			}
-		}
-	}
-
-	// lets replace the old jenkins-x charts
-	for _, chartName := range []string{"jxboot-helmfile-resources", "bucketrepo", "nexus"} {
-		for i := range helmstate.Releases {
-			release := &helmstate.Releases[i]
-			if release.Chart == "jenkins-x/"+chartName {
-				release.Chart = "jxgh/" + chartName
-				addedJxgh = true
-
-				for j := range release.Values {
-					v := release.Values[j]
-					s, ok := v.(string)
-					// lets switch invalid paths to the one inside a chart repo folder
-					if ok && s == fmt.Sprintf("%s/charts/jenkins-x/%s/values.yaml.gotmpl", versionStreamPath, chartName) {
-						release.Values[j] = fmt.Sprintf("%s/charts/jxgh/%s/values.yaml.gotmpl", versionStreamPath, chartName)
-						break
-					}
-				}
-			}
-		}
-	}
-
-	// lets replace the old lighthouse chart repository location if its being used
-	for i := range helmstate.Releases {
-		release := &helmstate.Releases[i]
-		if release.Chart == "jenkins-x/lighthouse" {
-			release.Chart = "jxgh/lighthouse"
-			addedJxgh = true
-
-			for i := range release.Values {
-				v := release.Values[i]
-				s, ok := v.(string)
-				// lets switch invalid paths to the one inside a chart repo folder
-				if ok && s == fmt.Sprintf("%s/charts/jenkins-x/lighthouse/values.yaml.gotmpl", versionStreamPath) {
-					release.Values[i] = fmt.Sprintf("%s/charts/jxgh/lighthouse/values.yaml.gotmpl", versionStreamPath)
-					break
-				}
-			}
-		}
-	}
-
-	// lets check for terraform installed vault
-	if requirements.SecretStorage == jxcore.SecretStorageTypeVault && requirements.TerraformVault {
-		for i := range helmstate.Releases {
-			release := &helmstate.Releases[i]
-			if release.Chart == "jxgh/vault-instance" || release.Chart == "banzaicloud-stable/vault-operator" {
-				log.Logger().Infof("Terraform installed detected and Vault chart %s still present in helmfile. Please migrate secrets as necessary and remove this chart from your helmfile", release.Chart)
-			}
-		}
-	}
-
-	// lets replace the old chartmuseum chart if its being used
-	for i := range helmstate.Releases {
-		release := &helmstate.Releases[i]
-		if release.Chart == "jenkins-x/chartmuseum" {
-			release.Chart = "stable/chartmuseum"
-			o.updateVersionFromVersionStream(release)
-			release.Values = []interface{}{fmt.Sprintf("%s/charts/stable/chartmuseum/values.yaml.gotmpl", versionStreamPath)}
-
-			// lets make sure we have a cdf repository
-			found := false
-			for k := range helmstate.Repositories {
-				repo := helmstate.Repositories[k]
-				if repo.Name == "stable" {
-					found = true
-					break
-				}
-			}
-			if !found {
-				helmstate.Repositories = append(helmstate.Repositories, state.RepositorySpec{
-					Name: "stable",
-					URL:  "https://charts.helm.sh/stable",
-				})
-			}
-			break
-		}
-	}
-
-	// Replace old nginx ingress chart
-	for i := range helmstate.Releases {
-		release := &helmstate.Releases[i]
-		switch release.Chart {
-		case "stable/nginx-ingress":
-			release.Chart = "ingress-nginx/ingress-nginx"
-			o.updateVersionFromVersionStream(release)
-			release.Values = []interface{}{fmt.Sprintf("%s/charts/ingress-nginx/ingress-nginx/values.yaml.gotmpl", versionStreamPath)}
-
-			// lets make sure we have the ingress-nginx repository
-			found := false
-			for k := range helmstate.Repositories {
-				repo := helmstate.Repositories[k]
-				if repo.Name == "ingress-nginx" {
-					found = true
-					break
-				}
-			}
-			if !found {
-				helmstate.Repositories = append(helmstate.Repositories, state.RepositorySpec{
-					Name: "ingress-nginx",
-					URL:  "https://kubernetes.github.io/ingress-nginx",
-				})
-			}
-		case "ingress-nginx/ingress-nginx":
-			for i := range release.Values {
-				v := release.Values[i]
-				s, ok := v.(string)
-				// lets switch invalid paths to the one inside a chart repo folder
-				if ok && s == fmt.Sprintf("%s/charts/ingress-nginx/values.yaml.gotmpl", versionStreamPath) {
-					release.Values[i] = fmt.Sprintf("%s/charts/ingress-nginx/ingress-nginx/values.yaml.gotmpl", versionStreamPath)
-					break
-				}
-			}
-		}
-	}
-
-	ns := jxcore.DefaultNamespace
-
-	if requirements.SecretStorage == jxcore.SecretStorageTypeLocal && helmstate.OverrideNamespace == ns {
-		// lets make sure the local external secrets chart is included
-		found := false
-		for i := range helmstate.Releases {
-			release := &helmstate.Releases[i]
-			if release.Chart == "jxgh/local-external-secrets" {
-				found = true
-				break
-			}
-		}
-		if !found {
-			release := state.ReleaseSpec{
-				Chart: "jxgh/local-external-secrets",
-			}
-			addedJxgh = true
-			o.updateVersionFromVersionStream(&release)
-			helmstate.Releases = append(helmstate.Releases, release)
-		}
-	}
-
-	// lets replace the old jx-labs/ charts...
-	for _, name := range []string{"jenkins-x-crds", "pusher-wave", "vault-instance"} {
-		chartName := "jx-labs/" + name
-		for i := range helmstate.Releases {
-			release := &helmstate.Releases[i]
-			if release.Chart == chartName {
-				release.Chart = "jxgh/" + name
-				addedJxgh = true
-				if name == "jenkins-x-crds" {
-					release.Values = []interface{}{fmt.Sprintf("%s/charts/jxgh/jenkins-x-crds/values.yaml.gotmpl", versionStreamPath)}
-				}
-				o.updateVersionFromVersionStream(release)
-				break
-			}
-		}
-	}
-
-	// lets ensure we have the jx-build-controller installed
-	if helmstate.OverrideNamespace == "jx" && isDevCluster(helmstate) {
-		found := false
-		for i := range helmstate.Releases {
-			release := &helmstate.Releases[i]
-			if release.Chart == "jxgh/jx-build-controller" {
-				found = true
-				break
-			}
-		}
-		if !found && helmstate.OverrideNamespace == "jx" {
-			helmstate.Releases = append(helmstate.Releases, state.ReleaseSpec{
-				Chart: "jxgh/jx-build-controller",
-			})
-			addedJxgh = true
-		}
-	}
-
-	if addedJxgh {
-		// lets make sure we have a jxgh repository
-		found := false
-		for k := range helmstate.Repositories {
-			repo := helmstate.Repositories[k]
-			if repo.Name == "jxgh" {
-				found = true
-				break
-			}
-		}
-		if !found {
-			helmstate.Repositories = append(helmstate.Repositories, state.RepositorySpec{
-				Name: "jxgh",
-				URL:  "https://jenkins-x-charts.github.io/repo",
-			})
-		}
-	}
-
-	if o.AddEnvironmentPipelines {
-		lighthouseTriggerFile := filepath.Join(o.Dir, ".lighthouse", "jenkins-x", "triggers.yaml")
-		exists, err := files.FileExists(lighthouseTriggerFile)
-		if err != nil {
-			return errors.Wrapf(err, "failed to detect file %s", lighthouseTriggerFile)
-		}
-		if !exists {
-			bin := o.KptBinary
-			if bin == "" {
-				bin, err = plugins.GetKptBinary(plugins.KptVersion)
-				if err != nil {
-					return err
-				}
-			}
-
-			args := []string{"pkg", "get", "https://github.com/jenkins-x/jx3-pipeline-catalog.git/environment/.lighthouse", o.Dir}
-			c := &cmdrunner.Command{
-				Name: bin,
-				Args: args,
-				Dir:  o.Dir,
-			}
-			_, err = o.CommandRunner(c)
-			if err != nil {
-				return errors.Wrapf(err, "failed to get environment tekton pipeline via kpt in dir %s", o.Dir)
-			}
-
-			err = gitclient.Add(o.Git(), o.Dir, ".lighthouse")
-			if err != nil {
-				return errors.Wrapf(err, "failed to add .lighthouse dir to git")
-			}
-
-			log.Logger().Infof("got tekton pipeline for envirnment at %s", lighthouseTriggerFile)
-		}
-	}
-	return nil
-}
-
 // removeRedundantRepositories removes any repositories from a state.HelmState that are not referenced by any releases
 func removeRedundantRepositories(helmstate *state.HelmState) {
 	requiredRepositories := make(map[string]bool)
@@ -1080,33 +822,6 @@ func removeRedundantRepositories(helmstate *state.HelmState) {
 	helmstate.Repositories = cleanedRepositories
 }
 
-func isDevCluster(helmState *state.HelmState) bool {
-	for k := range helmState.Releases {
-		release := helmState.Releases[k]
-		_, local := helmfiles.SpitChartName(release.Chart)
-		if local == "jxboot-helmfile-resources" || local == "lighthouse" {
-			return true
-		}
-	}
-	return false
-}
-
-func (o *Options) updateVersionFromVersionStream(release *state.ReleaseSpec) {
-	versionProperties, err := o.Options.Resolver.StableVersion(versionstream.KindChart, release.Chart)
-	if err != nil {
-		log.Logger().Warnf("failed to find version number for chart %s", release.Chart)
-		release.Version = ""
-	}
-
-	if versionProperties == nil {
-		log.Logger().Warnf("failed to find version number for chart %s", release.Chart)
-		release.Version = ""
-		return
-	}
-
-	release.Version = versionProperties.Version
-}
-
 func (o *Options) upgradePipelineCatalog() error {
 	pc, path, err := pipelinecatalogs.LoadPipelineCatalogs(o.Dir)
 	if err != nil {
@@ -1140,29 +855,3 @@ func (o *Options) upgradePipelineCatalog() error {
 	log.Logger().Infof("modified %s", info(path))
 	return nil
 }
-
-func (o *Options) migrateQuickstartsFile() error {
-	qs, path, err := quickstarthelpers.LoadQuickstarts(o.Dir)
-	if err != nil {
-		return errors.Wrapf(err, "failed to load quickstarts")
-	}
-
-	modified := false
-	for i := range qs.Spec.Imports {
-		ip := &qs.Spec.Imports[i]
-		if strings.HasSuffix(ip.File, ".yml") {
-			ip.File = strings.TrimSuffix(ip.File, ".yml") + ".yaml"
-			modified = true
-		}
-	}
-	if !modified {
-		return nil
-	}
-
-	err = yamls.SaveFile(qs, path)
-	if err != nil {
-		return errors.Wrapf(err, "failed to save file %s", path)
-	}
-	log.Logger().Infof("patched %s to use correct .yaml extension", info(path))
-	return nil
-}
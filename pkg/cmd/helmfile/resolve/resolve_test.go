@@ -29,9 +29,10 @@ var generateTestOutput = false
 
 func TestStepHelmfileResolve(t *testing.T) {
 	tests := []struct {
-		folder     string
-		helmfile   string
-		namespaces []string
+		folder      string
+		helmfile    string
+		namespaces  []string
+		parallelism int
 	}{
 		{
 			folder:     "helmfile_subfolder",
@@ -59,6 +60,11 @@ func TestStepHelmfileResolve(t *testing.T) {
 			folder:     "custom-env-ingress",
 			namespaces: []string{"foo", "jx", "jx-staging", "secret-infra", "tekton-pipelines"},
 		},
+		{
+			folder:      "custom-env-ingress",
+			namespaces:  []string{"foo", "jx", "jx-staging", "secret-infra", "tekton-pipelines"},
+			parallelism: 1,
+		},
 		{
 			folder:     "no-versionstream",
 			namespaces: []string{"jx", "external-secrets", "foo", "tekton-pipelines"},
@@ -147,6 +153,9 @@ func TestStepHelmfileResolve(t *testing.T) {
 		if test.helmfile != "" {
 			o.Helmfile = test.helmfile
 		}
+		if test.parallelism > 0 {
+			o.Parallelism = test.parallelism
+		}
 
 		err = o.Run()
 		require.NoError(t, err, "failed to run the command")
@@ -310,7 +310,7 @@ func (o *Options) updateStatus(ctx context.Context, env *environment, provider,
 		State:           "success",
 		TargetLink:      release.ApplicationURL,
 		LogLink:         release.LogsURL,
-		Description:     fmt.Sprintf("Deployment %s", strings.TrimPrefix(release.Version, "v")),
+		Description:     deploymentDescription(release),
 		Environment:     env.name,
 		EnvironmentLink: env.url,
 		AutoInactive:    o.AutoInactive,
@@ -324,6 +324,16 @@ func (o *Options) updateStatus(ctx context.Context, env *environment, provider,
 	return nil
 }
 
+// deploymentDescription describes the deployment including the version it was upgraded from if the version changed
+func deploymentDescription(release *releasereport.ReleaseInfo) string {
+	answer := fmt.Sprintf("Deployment %s", strings.TrimPrefix(release.Version, "v"))
+	previous := release.PreviousVersion()
+	if previous != "" {
+		answer += fmt.Sprintf(" (upgraded from %s)", strings.TrimPrefix(previous, "v"))
+	}
+	return answer
+}
+
 func (o *Options) CreateNewDeployment(ctx context.Context, ref, environment, fullRepoName string) (*scm.Deployment, error) {
 	_, name := scm.Split(fullRepoName)
 	deploymentInput := &scm.DeploymentInput{
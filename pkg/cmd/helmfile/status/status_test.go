@@ -272,6 +272,87 @@ func TestNewCmdHelmfileStatus_UpdateStatus(t *testing.T) {
 			},
 			expectedStatuses: map[string][]*scm.DeploymentStatus{},
 		},
+		{
+			name: "upgraded release includes the version transition",
+			inputArgs: inputArgs{
+				env:   &environment{name: prod},
+				repo:  &v1alpha1.Repository{Name: repoName},
+				group: &v1alpha1.RepositoryGroup{Owner: repoOwner},
+				release: &releasereport.ReleaseInfo{
+					Metadata: chart.Metadata{Version: "2"},
+					History: []releasereport.ReleaseVersion{
+						{Version: "2"},
+						{Version: "1"},
+					},
+				},
+			},
+			initialDeployments: []*scm.Deployment{},
+			expectedDeployments: []*scm.Deployment{
+				{
+					Name:                  repoName,
+					Environment:           prod,
+					ID:                    "deployment-1",
+					Namespace:             repoOwner,
+					Ref:                   "v2",
+					Task:                  "deploy",
+					Description:           "release fakeRepo for reference 2",
+					ProductionEnvironment: true,
+					OriginalEnvironment:   prod,
+					Payload:               "",
+				},
+			},
+			expectedStatuses: map[string][]*scm.DeploymentStatus{
+				fullRepoName + "/deployment-1": {
+					{
+						ID:          "status-1",
+						State:       "success",
+						Description: "Deployment 2 (upgraded from 1)",
+						Environment: prod,
+					},
+				},
+			},
+		},
+		{
+			name: "release changed without a new version has no version transition",
+			inputArgs: inputArgs{
+				env:   &environment{name: prod},
+				repo:  &v1alpha1.Repository{Name: repoName},
+				group: &v1alpha1.RepositoryGroup{Owner: repoOwner},
+				release: &releasereport.ReleaseInfo{
+					Metadata: chart.Metadata{Version: "2"},
+					History: []releasereport.ReleaseVersion{
+						{Version: "2", Chart: "jx3/bar"},
+						{Version: "2", Chart: "jx3/foo"},
+						{Version: "1", Chart: "jx3/foo"},
+					},
+				},
+			},
+			initialDeployments: []*scm.Deployment{},
+			expectedDeployments: []*scm.Deployment{
+				{
+					Name:                  repoName,
+					Environment:           prod,
+					ID:                    "deployment-1",
+					Namespace:             repoOwner,
+					Ref:                   "v2",
+					Task:                  "deploy",
+					Description:           "release fakeRepo for reference 2",
+					ProductionEnvironment: true,
+					OriginalEnvironment:   prod,
+					Payload:               "",
+				},
+			},
+			expectedStatuses: map[string][]*scm.DeploymentStatus{
+				fullRepoName + "/deployment-1": {
+					{
+						ID:          "status-1",
+						State:       "success",
+						Description: "Deployment 2",
+						Environment: prod,
+					},
+				},
+			},
+		},
 		{
 			name: "no release version (skip deployment & status)",
 			inputArgs: inputArgs{
@@ -19,7 +19,6 @@ import (
 
 const (
 	HelmfileFolder = "helmfiles"
-	helmfileName   = "helmfile.yaml"
 )
 
 var (
@@ -88,10 +87,18 @@ func (o *Options) Run() error {
 
 	configureHelmStatePaths(namespaceReleases)
 
-	parentHelmStates = configureParentHelmState(parentHelmStates, namespaceReleases)
+	namespaceHelmfiles := map[string]string{}
+	for ns := range namespaceReleases {
+		namespaceHelmfiles[ns] = helmfiles.NamespaceHelmfile(o.Dir, ns)
+	}
+
+	parentHelmStates, err = configureParentHelmState(o.Dir, parentHelmStates, namespaceReleases, namespaceHelmfiles)
+	if err != nil {
+		return errors.Wrapf(err, "failed to configure parent helmfile")
+	}
 
 	for ns, hs := range namespaceReleases {
-		helmfile := getHelmfileAbsolute(o.Dir, ns)
+		helmfile := namespaceHelmfiles[ns]
 		err = helmfiles.SaveNewHelmfile(helmfile, hs)
 		if err != nil {
 			return errors.Wrapf(err, "error saving helmfile %s", helmfile)
@@ -106,15 +113,7 @@ func (o *Options) Run() error {
 	return nil
 }
 
-func getHelmfileRelative(namespace string) string {
-	return filepath.Join(HelmfileFolder, namespace, helmfileName)
-}
-
-func getHelmfileAbsolute(workingDirectory, namespace string) string {
-	return filepath.Join(workingDirectory, getHelmfileRelative(namespace))
-}
-
-func configureParentHelmState(helmStates []*state.HelmState, nestedStates map[string][]*state.HelmState) []*state.HelmState { //nolint:gocritic
+func configureParentHelmState(dir string, helmStates []*state.HelmState, nestedStates map[string][]*state.HelmState, namespaceHelmfiles map[string]string) ([]*state.HelmState, error) { //nolint:gocritic
 	lastHelmState := helmStates[len(helmStates)-1]
 	hs := state.HelmState{
 		FilePath:       lastHelmState.FilePath,
@@ -135,11 +134,15 @@ func configureParentHelmState(helmStates []*state.HelmState, nestedStates map[st
 	sort.Strings(keys)
 
 	for _, ns := range keys {
+		rel, err := filepath.Rel(dir, namespaceHelmfiles[ns])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find path of helmfile for namespace %s", ns)
+		}
 		hs.Helmfiles = append(hs.Helmfiles, state.SubHelmfileSpec{
-			Path: getHelmfileRelative(ns),
+			Path: filepath.ToSlash(rel),
 		})
 	}
-	return []*state.HelmState{&hs}
+	return []*state.HelmState{&hs}, nil
 }
 
 func configureHelmStatePaths(releases map[string][]*state.HelmState) {
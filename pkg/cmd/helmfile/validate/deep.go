@@ -0,0 +1,207 @@
+package validate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/structure"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+)
+
+const (
+	// CheckStructure the nested helmfile is not in the canonical folder structure
+	CheckStructure = "structure"
+	// CheckNamespace the release namespace does not match the folder
+	CheckNamespace = "namespace"
+	// CheckRepository the release repository is missing or invalid
+	CheckRepository = "repository"
+	// CheckDuplicateRelease the release name is used more than once
+	CheckDuplicateRelease = "duplicate-release"
+	// CheckValuesFile the values file of a release does not exist
+	CheckValuesFile = "values-file"
+	// CheckUnpinnedVersion the release has no version
+	CheckUnpinnedVersion = "unpinned-version"
+	// CheckUnusedRepository the repository is not used by any release
+	CheckUnusedRepository = "unused-repository"
+	// CheckUnknownNeeds the release needs an unknown release
+	CheckUnknownNeeds = "unknown-needs"
+	// CheckCyclicNeeds the needs of releases form a cycle
+	CheckCyclicNeeds = "cyclic-needs"
+	// CheckHelmfileFolders the nested helmfiles on disk do not match the root helmfile
+	CheckHelmfileFolders = "helmfile-folders"
+)
+
+// releaseRef refers to a release in a nested helmfile
+type releaseRef struct {
+	helmfile *namespaceHelmfile
+	index    int
+}
+
+func (r *releaseRef) release() *state.ReleaseSpec {
+	return &r.helmfile.state.Releases[r.index]
+}
+
+func (r *releaseRef) namespace() string {
+	ns := r.release().Namespace
+	if ns == "" {
+		ns = r.helmfile.namespace
+	}
+	return ns
+}
+
+func (r *releaseRef) name() string {
+	return releaseName(r.release())
+}
+
+func (r *releaseRef) key() string {
+	return r.namespace() + "/" + r.name()
+}
+
+// releaseName returns the name of the release which defaults to the chart name
+func releaseName(release *state.ReleaseSpec) string {
+	if release.Name != "" {
+		return release.Name
+	}
+	_, name := chartrepos.SplitChartName(release.Chart)
+	return name
+}
+
+// checkReleases checks for duplicate releases, missing values files, unpinned versions and unused repositories
+func (o *Options) checkReleases(helmfiles []*namespaceHelmfile) {
+	names := map[string][]*releaseRef{}
+	for _, hf := range helmfiles {
+		usedRepositories := map[string]bool{}
+		dir := filepath.Join(o.Dir, filepath.Dir(hf.path))
+		for i := range hf.state.Releases {
+			ref := &releaseRef{helmfile: hf, index: i}
+			release := ref.release()
+			line := hf.releaseLine(i)
+			names[ref.name()] = append(names[ref.name()], ref)
+
+			prefix := strings.SplitN(release.Chart, "/", 2)[0]
+			usedRepositories[prefix] = true
+
+			if release.Version == "" && !helmhelpers.IsChartNameRelative(release.Chart) && !helmhelpers.IsChartRemote(release.Chart) {
+				o.addFinding(SeverityWarning, CheckUnpinnedVersion, hf.path, line, release.Name,
+					fmt.Sprintf("release %s of chart %s does not have a pinned version", release.Name, release.Chart))
+			}
+
+			for _, v := range release.Values {
+				path, ok := v.(string)
+				if !ok || strings.Contains(path, "{{") || strings.Contains(path, "://") {
+					continue
+				}
+				exists, err := files.FileExists(filepath.Join(dir, path))
+				if err != nil || !exists {
+					o.addFinding(SeverityError, CheckValuesFile, hf.path, line, release.Name,
+						fmt.Sprintf("values file %s of release %s does not exist", path, release.Name))
+				}
+			}
+		}
+
+		for k := range hf.state.Repositories {
+			repo := hf.state.Repositories[k]
+			if !usedRepositories[repo.Name] {
+				o.addFinding(SeverityWarning, CheckUnusedRepository, hf.path, hf.repositoryLine(k), "",
+					fmt.Sprintf("repository %s is not used by any release", repo.Name))
+			}
+		}
+	}
+
+	var keys []string
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, name := range keys {
+		refs := names[name]
+		if len(refs) < 2 {
+			continue
+		}
+		for i := 1; i < len(refs); i++ {
+			ref := refs[i]
+			first := refs[0]
+			severity := SeverityWarning
+			if ref.namespace() == first.namespace() {
+				severity = SeverityError
+			}
+			o.addFinding(severity, CheckDuplicateRelease, ref.helmfile.path, ref.helmfile.releaseLine(ref.index), name,
+				fmt.Sprintf("release %s in namespace %s is also defined in namespace %s in %s", name, ref.namespace(), first.namespace(), first.helmfile.path))
+		}
+	}
+}
+
+// checkNeeds checks that the needs of releases refer to known releases and do not form cycles
+func (o *Options) checkNeeds(nestedHelmfiles []*namespaceHelmfile) {
+	releases := map[string]*releaseRef{}
+	g := &helmfiles.ReleaseGraph{}
+	for _, hf := range nestedHelmfiles {
+		for i := range hf.state.Releases {
+			ref := &releaseRef{helmfile: hf, index: i}
+			release := ref.release()
+			var needs []string
+			for _, need := range release.Needs {
+				needs = append(needs, helmfiles.NeedKey(ref.namespace(), need))
+			}
+			node := &helmfiles.ReleaseNode{
+				Key:       ref.key(),
+				Namespace: ref.namespace(),
+				Name:      ref.name(),
+				Chart:     release.Chart,
+				Helmfile:  hf.path,
+			}
+			if g.AddRelease(node, needs) {
+				releases[ref.key()] = ref
+			}
+		}
+	}
+	g.LinkNeeds()
+
+	for _, d := range g.Dangling {
+		ref := releases[d.Release]
+		o.addFinding(SeverityError, CheckUnknownNeeds, ref.helmfile.path, ref.helmfile.releaseLine(ref.index), ref.release().Name,
+			fmt.Sprintf("release %s needs %s which is not a known release", ref.release().Name, d.Need))
+	}
+	for _, cycle := range g.Cycles() {
+		ref := releases[cycle[0]]
+		o.addFinding(SeverityError, CheckCyclicNeeds, ref.helmfile.path, ref.helmfile.releaseLine(ref.index), ref.release().Name,
+			fmt.Sprintf("cyclic needs: %s", strings.Join(cycle, " -> ")))
+	}
+}
+
+// checkHelmfileFolders checks that every nested helmfile folder on disk is included in the root helmfile
+func (o *Options) checkHelmfileFolders(root *state.HelmState) {
+	rootPath := o.relPath(o.Helmfile)
+	rootDir := filepath.Dir(o.Helmfile)
+	included := map[string]bool{}
+	for _, nested := range root.Helmfiles {
+		included[filepath.Clean(nested.Path)] = true
+	}
+
+	paths, err := filepath.Glob(filepath.Join(rootDir, structure.HelmfileFolder, "*", "helmfile.yaml"))
+	if err != nil {
+		return
+	}
+	templatePaths, err := filepath.Glob(filepath.Join(rootDir, structure.HelmfileFolder, "*", "helmfile.yaml"+helmfiles.TemplateSuffix))
+	if err != nil {
+		return
+	}
+	paths = append(paths, templatePaths...)
+	sort.Strings(paths)
+	for _, path := range paths {
+		rel, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			continue
+		}
+		if !included[rel] {
+			o.addFinding(SeverityWarning, CheckHelmfileFolders, rootPath, 0, "",
+				fmt.Sprintf("nested helmfile %s is not included in the helmfiles of the root helmfile", rel))
+		}
+	}
+}
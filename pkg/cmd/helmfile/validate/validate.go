@@ -1,35 +1,97 @@
 package validate
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/yaml2s"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+const (
+	// SeverityError a finding which fails the validation
+	SeverityError = "error"
+	// SeverityWarning a finding which only fails the validation if warnings are treated as errors
+	SeverityWarning = "warning"
+
+	// FormatText displays the findings as text
+	FormatText = "text"
+	// FormatJSON displays the findings as JSON
+	FormatJSON = "json"
+)
+
 var (
 	cmdLong = templates.LongDesc(`
 		Parses a helmfile and any nested helmfiles and validates they conform to a canonical directory structure for jx based around namespace
+
+		The --deep option also detects duplicate release names, missing values files, releases without a pinned version, unused repositories, needs which refer to unknown releases or are cyclic and nested helmfile folders which are not included in the root helmfile
 `)
 
 	cmdExample = templates.Examples(`
 		# Validates helmfile.yaml within the current directory
 		%s helmfile validate
+
+		# Performs all the validation checks failing on any warnings
+		%s helmfile validate --deep --warnings-as-errors
+
+		# Outputs the findings as JSON
+		%s helmfile validate --deep --format json
 	`)
+
+	formats = []string{FormatText, FormatJSON}
 )
 
+// Finding a problem found in a helmfile
+type Finding struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Release  string `json:"release,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Location returns the file and line of the finding
+func (f *Finding) Location() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
 type Options struct {
-	Dir       string
-	Helmfile  string
-	OutputDir string
+	Dir              string
+	Helmfile         string
+	OutputDir        string
+	Deep             bool
+	WarningsAsErrors bool
+	Format           string
+	Out              io.Writer
+	Findings         []*Finding
+}
+
+// namespaceHelmfile a nested helmfile for a namespace
+type namespaceHelmfile struct {
+	path      string
+	namespace string
+	state     *state.HelmState
+	file      *ast.File
 }
 
 func NewCmdHelmfileValidate() (*cobra.Command, *Options) {
@@ -39,7 +101,7 @@ func NewCmdHelmfileValidate() (*cobra.Command, *Options) {
 		Use:     "validate",
 		Short:   "Validates helmfile.yaml against a jx canonical tree of helmfiles",
 		Long:    cmdLong,
-		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName, rootcmd.BinaryName, rootcmd.BinaryName),
 		Run: func(_ *cobra.Command, _ []string) {
 			err := o.Run()
 			helper.CheckErr(err)
@@ -48,6 +110,9 @@ func NewCmdHelmfileValidate() (*cobra.Command, *Options) {
 
 	cmd.Flags().StringVarP(&o.Helmfile, "helmfile", "", "", "the helmfile to template. Defaults to 'helmfile.yaml' in the directory")
 	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory that contains helmfile.yml")
+	cmd.Flags().BoolVarP(&o.Deep, "deep", "", false, "performs all the validation checks of the releases, values files, repositories and needs")
+	cmd.Flags().BoolVarP(&o.WarningsAsErrors, "warnings-as-errors", "", false, "fails the validation if there are any warnings")
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatText, "the output format of the findings. Supported values: "+strings.Join(formats, ", "))
 
 	return cmd, o
 }
@@ -64,10 +129,19 @@ func (o *Options) Validate() error {
 			return errors.Wrapf(err, "failed to create temporary output directory")
 		}
 	}
+	if o.Format == "" {
+		o.Format = FormatText
+	}
+	if stringhelpers.StringArrayIndex(formats, o.Format) < 0 {
+		return options.InvalidOption("format", o.Format, formats)
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
 	return nil
 }
 
-func (o Options) Run() error {
+func (o *Options) Run() error {
 	err := o.Validate()
 	if err != nil {
 		return errors.Wrapf(err, "failed to validate")
@@ -75,46 +149,103 @@ func (o Options) Run() error {
 
 	rootHelmState := state.HelmState{}
 
-	err = yaml2s.LoadFile(o.Helmfile, &rootHelmState)
+	rootFile, err := loadHelmState(o.Helmfile, &rootHelmState)
 	if err != nil {
 		return errors.Wrapf(err, "fail to load yaml file %s", o.Helmfile)
 	}
-	for _, nestedState := range rootHelmState.Helmfiles {
-		err := o.validateSubHelmFile(nestedState.Path)
+
+	o.Findings = nil
+	var nested []*namespaceHelmfile
+	for i, nestedState := range rootHelmState.Helmfiles {
+		hf, err := o.validateSubHelmFile(nestedState.Path)
 		if err != nil {
-			return fmt.Errorf("failed to process nested helmfile %s with error %w", nestedState.Path, err)
+			o.addFinding(SeverityError, CheckStructure, o.relPath(o.Helmfile), lineOf(rootFile, fmt.Sprintf("$.helmfiles[%d]", i)), "",
+				fmt.Sprintf("failed to process nested helmfile %s with error %s", nestedState.Path, err.Error()))
+			continue
 		}
+		nested = append(nested, hf)
 	}
 
+	if o.Deep {
+		o.checkReleases(nested)
+		o.checkNeeds(nested)
+		o.checkHelmfileFolders(&rootHelmState)
+	}
+
+	err = o.displayFindings()
+	if err != nil {
+		return errors.Wrapf(err, "failed to display findings")
+	}
+
+	errorCount := 0
+	warningCount := 0
+	var first *Finding
+	for _, f := range o.Findings {
+		if f.Severity == SeverityError || o.WarningsAsErrors {
+			if first == nil {
+				first = f
+			}
+		}
+		if f.Severity == SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	if first != nil {
+		return errors.Errorf("helmfile validation found %d errors and %d warnings: %s: %s", errorCount, warningCount, first.Location(), first.Message)
+	}
 	return nil
 }
 
-func (o Options) validateSubHelmFile(path string) error {
+func (o *Options) validateSubHelmFile(path string) (*namespaceHelmfile, error) {
 	targetNamespace, err := o.getSubHelmfileNamespace(path)
 	if err != nil {
-		return fmt.Errorf("failed to determine namespace from path %w", err)
+		return nil, fmt.Errorf("failed to determine namespace from path %w", err)
 	}
 
 	helmState := state.HelmState{}
-	err = yaml2s.LoadFile(filepath.Join(o.Dir, path), &helmState)
+	fileName := filepath.Join(o.Dir, path)
+	file, err := loadHelmState(fileName, &helmState)
 	if err != nil {
-		return fmt.Errorf("failed to load helmfile - %w", err)
+		return nil, fmt.Errorf("failed to load helmfile - %w", err)
+	}
+	hf := &namespaceHelmfile{
+		path:      o.relPath(fileName),
+		namespace: targetNamespace,
+		state:     &helmState,
+		file:      file,
 	}
 
+	for k := range helmState.Repositories {
+		repo := helmState.Repositories[k]
+		if strings.HasPrefix(repo.URL, chartrepos.OCIScheme) {
+			o.addFinding(SeverityError, CheckRepository, hf.path, hf.repositoryLine(k), "",
+				fmt.Sprintf("repository %s URL %s should not include the %s scheme and should use oci: true instead", repo.Name, repo.URL, chartrepos.OCIScheme))
+		}
+	}
 	for k := range helmState.Releases {
 		release := helmState.Releases[k]
 		if release.Namespace != targetNamespace {
-			return fmt.Errorf("namespace for release %s is %s does not match namespace of folder %s", release.Name, release.Namespace, targetNamespace)
+			o.addFinding(SeverityError, CheckNamespace, hf.path, hf.releaseLine(k), release.Name,
+				fmt.Sprintf("namespace for release %s is %s does not match namespace of folder %s", release.Name, release.Namespace, targetNamespace))
 		}
 		chartRepo, err := getChartRepository(release.Chart)
 		if err != nil {
-			return fmt.Errorf("failed parsing repo name for chart %s", release.Chart)
+			o.addFinding(SeverityError, CheckRepository, hf.path, hf.releaseLine(k), release.Name,
+				fmt.Sprintf("failed parsing repo name for chart %s", release.Chart))
+			continue
+		}
+		// charts referenced by an OCI URL do not need a repository
+		if chartRepo == "" {
+			continue
 		}
 		if err := checkChartRepositoryExists(chartRepo, helmState.Repositories); err != nil {
-			return fmt.Errorf("error finding chart repo for %s", chartRepo)
+			o.addFinding(SeverityError, CheckRepository, hf.path, hf.releaseLine(k), release.Name,
+				fmt.Sprintf("error finding chart repo for %s", chartRepo))
 		}
 	}
-	return nil
+	return hf, nil
 }
 
 func checkChartRepositoryExists(chartRepo string, repos []state.RepositorySpec) error {
@@ -128,6 +259,9 @@ func checkChartRepositoryExists(chartRepo string, repos []state.RepositorySpec)
 }
 
 func getChartRepository(chart string) (string, error) {
+	if strings.HasPrefix(chart, chartrepos.OCIScheme) {
+		return "", nil
+	}
 	chartSplit := strings.Split(chart, "/")
 	if len(chartSplit) != 2 {
 		return "", fmt.Errorf("failed to determine chartname for %s", chart)
@@ -135,7 +269,7 @@ func getChartRepository(chart string) (string, error) {
 	return chartSplit[0], nil
 }
 
-func (o Options) getSubHelmfileNamespace(path string) (string, error) {
+func (o *Options) getSubHelmfileNamespace(path string) (string, error) {
 	subHelmRel := path
 
 	if filepath.IsAbs(path) {
@@ -153,3 +287,107 @@ func (o Options) getSubHelmfileNamespace(path string) (string, error) {
 
 	return directories[1], nil
 }
+
+func (o *Options) addFinding(severity, check, file string, line int, release, message string) {
+	o.Findings = append(o.Findings, &Finding{
+		Severity: severity,
+		Check:    check,
+		File:     file,
+		Line:     line,
+		Release:  release,
+		Message:  message,
+	})
+}
+
+func (o *Options) displayFindings() error {
+	if o.Format == FormatJSON {
+		findings := o.Findings
+		if findings == nil {
+			findings = []*Finding{}
+		}
+		data, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal findings to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	}
+	for _, f := range o.Findings {
+		_, err := fmt.Fprintf(o.Out, "%s: %s: %s\n", f.Severity, f.Location(), f.Message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relPath returns the path relative to the directory
+func (o *Options) relPath(path string) string {
+	answer, err := filepath.Rel(o.Dir, path)
+	if err != nil {
+		return path
+	}
+	return answer
+}
+
+func (h *namespaceHelmfile) releaseLine(i int) int {
+	return lineOf(h.file, fmt.Sprintf("$.releases[%d]", i))
+}
+
+func (h *namespaceHelmfile) repositoryLine(i int) int {
+	return lineOf(h.file, fmt.Sprintf("$.repositories[%d]", i))
+}
+
+// loadHelmState loads the helmfile into the helm state returning the parsed file for line numbers.
+// Templated helmfiles are rendered first and their documents merged so there are no line numbers for them
+func loadHelmState(path string, helmState *state.HelmState) (*ast.File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read file %s", path)
+	}
+	if !helmfiles.IsTemplated(path, data) {
+		err = yaml2s.LoadFile(path, helmState)
+		if err != nil {
+			return nil, err
+		}
+		return parseFile(path), nil
+	}
+	helmStates, err := helmfiles.LoadHelmfile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, hs := range helmStates {
+		if hs.OverrideNamespace != "" {
+			helmState.OverrideNamespace = hs.OverrideNamespace
+		}
+		helmState.Helmfiles = append(helmState.Helmfiles, hs.Helmfiles...)
+		helmState.Repositories = append(helmState.Repositories, hs.Repositories...)
+		helmState.Releases = append(helmState.Releases, hs.Releases...)
+	}
+	return nil, nil
+}
+
+// parseFile parses the YAML file so that we can find the line numbers of findings
+func parseFile(path string) *ast.File {
+	file, err := parser.ParseFile(path, 0)
+	if err != nil {
+		return nil
+	}
+	return file
+}
+
+// lineOf returns the line number of the YAML path in the file or 0 if it cannot be found
+func lineOf(file *ast.File, path string) int {
+	if file == nil {
+		return 0
+	}
+	p, err := yaml.PathString(path)
+	if err != nil {
+		return 0
+	}
+	node, err := p.FilterFile(file)
+	if err != nil || node == nil || node.GetToken() == nil {
+		return 0
+	}
+	return node.GetToken().Position.Line
+}
@@ -1,11 +1,15 @@
 package validate_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/helmfile/validate"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,6 +49,21 @@ func TestStepHelmfileStructure(t *testing.T) {
 			returnError: true,
 			errorString: "",
 		},
+		{
+			testFolder:  "oci",
+			returnError: false,
+			errorString: "",
+		},
+		{
+			testFolder:  "oci_scheme",
+			returnError: true,
+			errorString: "",
+		},
+		{
+			testFolder:  "templated",
+			returnError: false,
+			errorString: "",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -68,3 +87,85 @@ func TestStepHelmfileStructure(t *testing.T) {
 		}
 	}
 }
+
+func TestHelmfileValidateDeep(t *testing.T) {
+	_, o := validate.NewCmdHelmfileValidate()
+	o.Dir = filepath.Join("testdata", "deep")
+	o.Deep = true
+	o.Format = validate.FormatJSON
+	buf := &bytes.Buffer{}
+	o.Out = buf
+
+	err := o.Run()
+	require.Error(t, err, "should have failed with errors")
+
+	var findings []*validate.Finding
+	err = json.Unmarshal(buf.Bytes(), &findings)
+	require.NoError(t, err, "failed to parse output %s", buf.String())
+
+	type key struct {
+		check    string
+		severity string
+		release  string
+	}
+	actual := map[key]*validate.Finding{}
+	for _, f := range findings {
+		t.Logf("%s: %s: %s\n", f.Severity, f.Location(), f.Message)
+		actual[key{f.Check, f.Severity, f.Release}] = f
+	}
+	expected := []key{
+		{validate.CheckValuesFile, validate.SeverityError, "lighthouse"},
+		{validate.CheckUnpinnedVersion, validate.SeverityWarning, "jx-pipelines-visualizer"},
+		{validate.CheckUnknownNeeds, validate.SeverityError, "jx-pipelines-visualizer"},
+		{validate.CheckCyclicNeeds, validate.SeverityError, "bar"},
+		{validate.CheckUnusedRepository, validate.SeverityWarning, ""},
+		{validate.CheckDuplicateRelease, validate.SeverityWarning, "lighthouse"},
+		{validate.CheckHelmfileFolders, validate.SeverityWarning, ""},
+	}
+	for _, k := range expected {
+		assert.Contains(t, actual, k, "missing finding %v", k)
+	}
+	assert.Len(t, findings, len(expected), "findings")
+
+	values := actual[key{validate.CheckValuesFile, validate.SeverityError, "lighthouse"}]
+	if assert.NotNil(t, values) {
+		assert.Equal(t, filepath.Join("helmfiles", "jx", "helmfile.yaml"), values.File)
+		assert.Equal(t, 8, values.Line, "line of the release")
+	}
+}
+
+func TestHelmfileValidateWarningsAsErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join("testdata", "input"), tmpDir)
+	require.NoError(t, err, "failed to copy testdata")
+
+	_, o := validate.NewCmdHelmfileValidate()
+	o.Dir = tmpDir
+	o.Deep = true
+	o.Out = &bytes.Buffer{}
+	err = o.Run()
+	require.NoError(t, err, "should only have warnings")
+
+	o.WarningsAsErrors = true
+	err = o.Run()
+	require.NoError(t, err, "should have no warnings")
+
+	path := filepath.Join(tmpDir, "helmfiles", "jx", "helmfile.yaml")
+	err = os.WriteFile(path, []byte(`releases:
+  - chart: stable/chartmuseum
+    name: chartmuseum
+    namespace: jx
+repositories:
+  - name: stable
+    url: someurl
+`), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save %s", path)
+
+	o.WarningsAsErrors = false
+	err = o.Run()
+	require.NoError(t, err, "should only have warnings")
+
+	o.WarningsAsErrors = true
+	err = o.Run()
+	require.Error(t, err, "should fail on warnings")
+}
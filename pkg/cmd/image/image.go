@@ -2,13 +2,19 @@ package image
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/imagepaths"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -18,6 +24,14 @@ import (
 var (
 	cmdLong = templates.LongDesc(`
 		Updates images in the kubernetes resources from the version stream
+
+		The paths to the images in each kind of resource default to the containers, init containers and ephemeral containers of Pods, Deployments, StatefulSets, DaemonSets, ReplicaSets, ReplicationControllers, Jobs, CronJobs, Knative Services and Argo Rollouts along with the steps of the Tekton Pipelines, PipelineRuns, Tasks and TaskRuns.
+
+		The paths can be extended or overridden via an ImagePaths resource in the image-paths.yaml file of the version stream and then the .jx/gitops/image-paths.yaml file in the cluster repository. An entry with the same kind and apiVersion replaces the paths of an earlier entry.
+
+		Use --report to list every image reference found and whether it was updated.
+
+		Use --pin-digest to pin each image to the digest of its tag by writing 'repo:tag@sha256:...'. The digests are looked up via the v2 registry API using the credentials in the --registry-config docker config file and cached in the --digest-cache file so that repeated runs do not need to access the registries.
 `)
 
 	cmdExample = templates.Examples(`
@@ -25,41 +39,13 @@ var (
 		%s image
 		# modify the images in the ./src dir using the current dir to find the version stream
 		%[1]s image --source-dir ./src --dir . 
-	`)
 
-	kindToPaths = map[string][][]string{
-		"Deployment": {
-			{
-				"spec", "template", "spec", "initContainers", "image",
-			},
-			{
-				"spec", "template", "spec", "containers", "image",
-			},
-		},
-		"Job": {
-			{
-				"spec", "template", "spec", "initContainers", "image",
-			},
-			{
-				"spec", "template", "spec", "containers", "image",
-			},
-		},
-		"Pipeline": {
-			{
-				"spec", "tasks", "taskSpec", "steps", "image",
-			},
-		},
-		"PipelineRun": {
-			{
-				"spec", "pipelineSpec", "tasks", "taskSpec", "steps", "image",
-			},
-		},
-		"Task": {
-			{
-				"spec", "steps", "image",
-			},
-		},
-	}
+		# modify the images and report every image reference found
+		%[1]s image --report
+
+		# modify the images and pin them to the digests of their tags
+		%[1]s image --pin-digest --registry-config ~/.docker/config.json
+	`)
 )
 
 // Options the options for the command
@@ -67,7 +53,29 @@ type Options struct {
 	kyamls.Filter
 	VersionStreamer versionstreamer.Options
 	SourceDir       string
+	Report          bool
+	PinDigest       bool
+	RegistryConfig  string
+	DigestCacheFile string
+	ImagePaths      *imagepaths.Table
 	ImageResolver   func(string, []string, string) (string, error)
+	Registry        ociregistry.DigestResolver
+
+	// digests looks up the digests of image tags via the digest cache
+	digests ociregistry.DigestResolver
+
+	// References the image references found
+	References []*ImageReference
+}
+
+// ImageReference an image found in a kubernetes resource
+type ImageReference struct {
+	File     string
+	Kind     string
+	Path     string
+	Image    string
+	NewImage string
+	Updated  bool
 }
 
 // NewCmdUpdateImage creates a command object for the command
@@ -86,6 +94,10 @@ func NewCmdUpdateImage() (*cobra.Command, *Options) {
 		},
 	}
 	cmd.Flags().StringVarP(&o.SourceDir, "source-dir", "s", "content-root", "the directory to recursively look for the *.yaml files to modify")
+	cmd.Flags().BoolVarP(&o.Report, "report", "", false, "reports every image reference found and whether it was updated")
+	cmd.Flags().BoolVarP(&o.PinDigest, "pin-digest", "", false, "pins each image to the digest of its tag")
+	cmd.Flags().StringVarP(&o.RegistryConfig, "registry-config", "", ociregistry.DefaultRegistryConfigFile, "the path to the docker config file with the credentials of the registries used to lookup digests")
+	cmd.Flags().StringVarP(&o.DigestCacheFile, "digest-cache", "", "", "the file used to cache the digests of image tags. Defaults to .jx/gitops/"+v1alpha1.ImageDigestsFileName+" in the --dir")
 	o.Filter.AddFlags(cmd)
 	o.VersionStreamer.AddFlags(cmd)
 	return cmd, o
@@ -105,24 +117,94 @@ func (o *Options) Run() error {
 	if o.ImageResolver == nil {
 		o.ImageResolver = o.resolveImage
 	}
+	if o.ImagePaths == nil {
+		o.ImagePaths, err = imagepaths.LoadTable(o.VersionStreamer.Dir, o.VersionStreamer.VersionStreamDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load image paths")
+		}
+	}
+	var digestCache *ociregistry.DigestCache
+	if o.PinDigest {
+		digestCache, err = o.loadDigestCache()
+		if err != nil {
+			return errors.Wrapf(err, "failed to load the image digest cache")
+		}
+	}
+	o.References = nil
 	modifyFn := func(node *yaml.RNode, path string) (bool, error) {
 		kind := kyamls.GetKind(node, path)
+		apiVersion := kyamls.GetAPIVersion(node, path)
 		answer := false
-		pathsSlice := kindToPaths[kind]
-		if len(pathsSlice) > 0 {
-			for _, jsonNames := range pathsSlice {
-				flag, err := o.modifyImages(node, path, "", jsonNames...)
-				if err != nil {
-					return flag, err
-				}
-				if flag {
-					answer = true
-				}
+		start := len(o.References)
+		for _, jsonNames := range o.ImagePaths.Paths(apiVersion, kind) {
+			flag, err := o.modifyImages(node, path, "", jsonNames...)
+			if err != nil {
+				return flag, err
+			}
+			if flag {
+				answer = true
 			}
 		}
+		for _, r := range o.References[start:] {
+			r.Kind = kind
+		}
 		return answer, nil
 	}
-	return kyamls.ModifyFiles(o.SourceDir, modifyFn, o.Filter)
+	err = kyamls.ModifyFiles(o.SourceDir, modifyFn, o.Filter)
+	if err != nil {
+		return err
+	}
+	if digestCache != nil {
+		err = digestCache.Save()
+		if err != nil {
+			return errors.Wrapf(err, "failed to save the image digest cache")
+		}
+	}
+	if o.Report {
+		o.printReport()
+	}
+	return nil
+}
+
+// loadDigestCache loads the digest cache wrapping the registry so that digests are only looked up once
+func (o *Options) loadDigestCache() (*ociregistry.DigestCache, error) {
+	if o.DigestCacheFile == "" {
+		o.DigestCacheFile = filepath.Join(o.VersionStreamer.Dir, ".jx", "gitops", v1alpha1.ImageDigestsFileName)
+	}
+	if o.Registry == nil {
+		client, err := ociregistry.NewClient(o.RegistryConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create registry client")
+		}
+		o.Registry = client
+	}
+	cache, err := ociregistry.LoadDigestCache(o.DigestCacheFile, o.Registry)
+	if err != nil {
+		return nil, err
+	}
+	o.digests = cache
+	return cache, nil
+}
+
+func (o *Options) printReport() {
+	out := o.VersionStreamer.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	t := table.CreateTable(out)
+	t.AddRow("FILE", "KIND", "PATH", "IMAGE", "NEW IMAGE", "UPDATED")
+	for _, r := range o.References {
+		file, err := filepath.Rel(o.SourceDir, r.File)
+		if err != nil {
+			file = r.File
+		}
+		updated := "no"
+		if r.Updated {
+			updated = "yes"
+		}
+		t.AddRow(file, r.Kind, r.Path, r.Image, r.NewImage, updated)
+	}
+	t.Render()
 }
 
 func (o *Options) modifyImages(node *yaml.RNode, filePath, jsonPath string, names ...string) (bool, error) {
@@ -137,8 +219,10 @@ func (o *Options) modifyImages(node *yaml.RNode, filePath, jsonPath string, name
 
 	if node.YNode().Kind == yaml.SequenceNode {
 		err := node.VisitElements(func(sn *yaml.RNode) error {
-			var err error
-			flag, err = o.modifyImages(sn, filePath, jsonPath, names...)
+			modified, err := o.modifyImages(sn, filePath, jsonPath, names...)
+			if modified {
+				flag = true
+			}
 			return err
 		})
 		if err != nil {
@@ -166,26 +250,49 @@ func (o *Options) modifyImages(node *yaml.RNode, filePath, jsonPath string, name
 				return errors.Wrapf(err, "failed to get the image value of %s for path %s for file %s", keyText, childJSONPath, filePath)
 			}
 
-			imageWithoutTag := strings.TrimSpace(valueText)
+			valueText = strings.TrimSpace(valueText)
+			image, _, _ := strings.Cut(valueText, "@")
+			imageWithoutTag := image
 			idx := strings.LastIndex(imageWithoutTag, ":")
-			if idx > 0 {
+			if idx > strings.LastIndex(imageWithoutTag, "/") {
 				imageWithoutTag = imageWithoutTag[0:idx]
 			}
-			newValue, err := o.ImageResolver(imageWithoutTag, names, filePath)
+			resolved, err := o.ImageResolver(imageWithoutTag, names, filePath)
 			if err != nil {
 				return errors.Wrapf(err, "failed to get the image value of %s for path %s for file %s", keyText, childJSONPath, filePath)
 			}
-			if newValue != imageWithoutTag {
+			newValue := valueText
+			if resolved != imageWithoutTag && resolved != image {
+				newValue = resolved
+			}
+			if o.PinDigest {
+				newValue, err = o.pinDigest(newValue)
+				if err != nil {
+					return errors.Wrapf(err, "failed to pin the digest of %s for path %s for file %s", newValue, childJSONPath, filePath)
+				}
+			}
+			ref := &ImageReference{
+				File:  filePath,
+				Path:  childJSONPath,
+				Image: valueText,
+			}
+			if newValue != valueText {
 				mn.Value.SetYNode(&yaml.Node{Kind: yaml.ScalarNode, Value: newValue})
 				log.Logger().Infof("modify %s: %s => %s for file %s", childJSONPath, valueText, newValue, filePath)
+				ref.NewImage = newValue
+				ref.Updated = true
 				flag = true
 			} else {
 				log.Logger().Debugf("not modifying %s: %s for file %s", childJSONPath, valueText, filePath)
 			}
+			o.References = append(o.References, ref)
 			return nil
 		}
 
-		flag, err = o.modifyImages(mn.Value, filePath, childJSONPath, names[1:]...)
+		modified, err := o.modifyImages(mn.Value, filePath, childJSONPath, names[1:]...)
+		if modified {
+			flag = true
+		}
 		return err
 	})
 	if err != nil {
@@ -194,6 +301,21 @@ func (o *Options) modifyImages(node *yaml.RNode, filePath, jsonPath string, name
 	return flag, nil
 }
 
+// pinDigest returns the image pinned to the digest of its tag. Images without a tag are not pinned
+func (o *Options) pinDigest(image string) (string, error) {
+	host, repository, tag, _ := ociregistry.ParseImage(image)
+	if tag == "" {
+		log.Logger().Debugf("not pinning image %s as it has no tag", image)
+		return image, nil
+	}
+	digest, err := o.digests.Digest(host, repository, tag)
+	if err != nil {
+		return "", err
+	}
+	name, _, _ := strings.Cut(image, "@")
+	return name + "@" + digest, nil
+}
+
 // resolveImage resolves the given container image from the version stream
 func (o *Options) resolveImage(image string, _ []string, _ string) (string, error) {
 	resolver := o.VersionStreamer.Resolver
@@ -1,14 +1,21 @@
 package image_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/image"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/fakeregistry"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,6 +34,9 @@ func TestUpdateImages(t *testing.T) {
 
 	o.SourceDir = filepath.Join(tmpDir, "src")
 	o.VersionStreamer.Dir = tmpDir
+	o.Report = true
+	buf := &bytes.Buffer{}
+	o.VersionStreamer.Out = buf
 
 	t.Logf("modifying files at %s\n", o.SourceDir)
 
@@ -49,4 +59,98 @@ func TestUpdateImages(t *testing.T) {
 		return nil
 	})
 	require.NoError(t, err, "failed to walk expected files")
+
+	references := map[string]bool{}
+	for _, r := range o.References {
+		rel, err := filepath.Rel(o.SourceDir, r.File)
+		require.NoError(t, err, "failed to find relative path of %s", r.File)
+		references[rel+" "+r.Kind+" "+r.Path+" "+r.Image] = r.Updated
+	}
+	assert.Len(t, o.References, 17, "image references")
+	assert.True(t, references["cronjob.yaml CronJob spec.jobTemplate.spec.template.spec.containers.image gcr.io/jenkinsxio/jx-cli:3.0.1"], "cronjob updated")
+	assert.True(t, references["pod.yaml Pod spec.ephemeralContainers.image ubuntu:1.0.0"], "ephemeral container updated")
+	assert.True(t, references["workflow.yaml Workflow spec.templates.container.image ubuntu:1.0.0"], "custom kind updated")
+	assert.Contains(t, references, "pod.yaml Pod spec.containers.image gcr.io/jenkinsxio/dontchange:1.2.3", "unchanged image reported")
+	assert.False(t, references["pod.yaml Pod spec.containers.image gcr.io/jenkinsxio/dontchange:1.2.3"], "image not in version stream updated")
+	assert.Contains(t, buf.String(), "NEW IMAGE", "report output")
+	t.Logf("report:\n%s\n", buf.String())
+}
+
+func TestPinImageDigests(t *testing.T) {
+	registry := fakeregistry.New()
+	defer registry.Close()
+	registry.Credentials = ociregistry.Credentials{Username: "myuser", Password: "mypassword"}
+	digest := registry.AddImage("myorg/myapp", "1.2.3")
+
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join("testdata", "input"), tmpDir)
+	require.NoError(t, err, "failed to copy testdata")
+
+	registryConfig := filepath.Join(tmpDir, "config.json")
+	err = os.WriteFile(registryConfig, []byte(`{"auths": {"`+registry.Host+`": {"username": "myuser", "password": "mypassword"}}}`), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save %s", registryConfig)
+
+	srcDir := filepath.Join(tmpDir, "pin")
+	err = os.MkdirAll(srcDir, files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create %s", srcDir)
+	path := filepath.Join(srcDir, "deployment.yaml")
+	err = os.WriteFile(path, []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mydeployment
+spec:
+  template:
+    spec:
+      containers:
+      - image: `+registry.Host+`/myorg/myapp:1.2.3
+        name: app
+      - image: `+registry.Host+`/myorg/myapp:1.2.3@sha256:0000
+        name: stale
+      - image: `+registry.Host+`/myorg/myapp
+        name: untagged
+`), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save %s", path)
+
+	newOptions := func() *image.Options {
+		_, o := image.NewCmdUpdateImage()
+		o.SourceDir = srcDir
+		o.VersionStreamer.Dir = tmpDir
+		o.PinDigest = true
+		o.RegistryConfig = registryConfig
+		client := registry.Client()
+		client.DockerConfig, err = ociregistry.LoadDockerConfig(registryConfig)
+		require.NoError(t, err, "failed to load %s", registryConfig)
+		o.Registry = client
+		return o
+	}
+
+	o := newOptions()
+	err = o.Run()
+	require.NoError(t, err, "failed to pin image digests")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to load %s", path)
+	text := string(data)
+	pinned := registry.Host + "/myorg/myapp:1.2.3@" + digest
+	assert.Equal(t, 2, strings.Count(text, "image: "+pinned+"\n"), "pinned images in:\n%s", text)
+	assert.Contains(t, text, "image: "+registry.Host+"/myorg/myapp\n", "untagged image should not be pinned")
+
+	cacheFile := filepath.Join(tmpDir, ".jx", "gitops", v1alpha1.ImageDigestsFileName)
+	cache := &v1alpha1.ImageDigests{}
+	err = yamls.LoadFile(cacheFile, cache)
+	require.NoError(t, err, "failed to load %s", cacheFile)
+	assert.Equal(t, v1alpha1.KindImageDigests, cache.Kind, "kind of %s", cacheFile)
+	assert.Equal(t, digest, cache.Spec.Digests[registry.Host+"/myorg/myapp:1.2.3"], "cached digest in %s", cacheFile)
+
+	// lets verify that repeated runs use the cache rather than the registry
+	requests := len(registry.Requests)
+	err = o.Run()
+	require.NoError(t, err, "failed to rerun the same options")
+	_, wrapped := o.Registry.(*ociregistry.DigestCache)
+	assert.False(t, wrapped, "the registry should not be replaced by the digest cache")
+
+	o = newOptions()
+	err = o.Run()
+	require.NoError(t, err, "failed to pin image digests again")
+	assert.Len(t, registry.Requests, requests, "should not access the registry when the digests are cached")
 }
@@ -2,37 +2,81 @@ package lint
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apiresources"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/lintpolicy"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
 	"github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/linter"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+const (
+	// FormatHuman the default human readable output
+	FormatHuman = "human"
+	// FormatJSON the JSON output of the findings
+	FormatJSON = "json"
+	// FormatSARIF the SARIF output of the findings for code scanning tools
+	FormatSARIF = "sarif"
+
+	// FailOnNone never fail the command on findings
+	FailOnNone = "none"
+)
+
 var (
+	info = termcolor.ColorInfo
+
 	splitLong = templates.LongDesc(`
 		Lints the gitops files in the file system
+
+		As well as validating the well known configuration files the kubernetes resources in the config-root directory are checked against the built in rules and any custom rules in the .jx/gitops/lint-policy.yaml file.
+
+		Rules can be ignored for a resource via the 'gitops.jenkins-x.io/lint-ignore' annotation or for a whole file via a comment of the form '# gitops.jenkins-x.io/lint-ignore: rule1,rule2'
+
+		Findings are reported but do not fail the command unless the --fail-on flag is specified
 `)
 
 	splitExample = templates.Examples(`
 		# lint files
 		%s lint --dir .
+
+		# fail if there are any findings with error severity
+		%s lint --fail-on error
+
+		# generate a SARIF report for code scanning tools
+		%s lint --format sarif --out lint.sarif
 	`)
+
+	formats = []string{FormatHuman, FormatJSON, FormatSARIF, linter.FormatTap}
 )
 
 // Options the options for the command
 type Options struct {
 	linter.Options
 
-	Dir     string
-	Verbose bool
-	Linters []linter.Linter
+	Dir        string
+	PolicyFile string
+	FailOn     string
+	ConfigRoot string
+	Verbose    bool
+	Out        io.Writer
+	Linters    []linter.Linter
+	Engine     *lintpolicy.Engine
+	Findings   []lintpolicy.Finding
 }
 
 // NewCmdLint creates a command object for the command
@@ -43,18 +87,42 @@ func NewCmdLint() (*cobra.Command, *Options) {
 		Use:     "lint",
 		Short:   "Lints the gitops files in the file system",
 		Long:    splitLong,
-		Example: fmt.Sprintf(splitExample, rootcmd.BinaryName),
+		Example: fmt.Sprintf(splitExample, rootcmd.BinaryName, rootcmd.BinaryName, rootcmd.BinaryName),
 		Run: func(_ *cobra.Command, _ []string) {
 			err := o.Run()
 			helper.CheckErr(err)
 		},
 	}
 	cmd.Flags().StringVarP(&o.Dir, "dir", "d", ".", "the directory to recursively look for the *.yaml or *.yml files")
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatHuman, fmt.Sprintf("the output format. One of: %v", formats))
+	cmd.Flags().StringVarP(&o.OutFile, "out", "o", "", "the file to write the results to. If not specified the results are written to the terminal")
+	cmd.Flags().StringVarP(&o.PolicyFile, "policy", "", "", "the lint policy file. Defaults to .jx/gitops/lint-policy.yaml in the directory")
+	cmd.Flags().StringVarP(&o.ConfigRoot, "config-root", "", "config-root", "the directory of the kubernetes resources relative to the directory which are checked against the rules")
+	cmd.Flags().StringVarP(&o.FailOn, "fail-on", "", FailOnNone, "the minimum severity of findings which fails the command. One of: error, warning, info, none")
 	return cmd, o
 }
 
 // Validate verifies the configuration
 func (o *Options) Validate() error {
+	if o.Format == "" {
+		o.Format = FormatHuman
+	}
+	if stringhelpers.StringArrayIndex(formats, o.Format) < 0 {
+		return errors.Errorf("unsupported format %s. Supported values: %v", o.Format, formats)
+	}
+	if o.FailOn == "" {
+		o.FailOn = FailOnNone
+	}
+	if o.FailOn != FailOnNone && lintpolicy.SeverityLevel(v1alpha1.LintSeverity(o.FailOn)) == 0 {
+		return errors.Errorf("unsupported fail-on value %s", o.FailOn)
+	}
+	if o.ConfigRoot == "" {
+		o.ConfigRoot = "config-root"
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+
 	o.Linters = append(o.Linters,
 		linter.Linter{
 			Path: filepath.Join(".jx", "gitops", v1alpha1.SourceConfigFileName),
@@ -80,6 +148,24 @@ func (o *Options) Validate() error {
 				return o.LintResource(path, test, &v4beta1.Requirements{})
 			},
 		},
+		linter.Linter{
+			Path: filepath.Join(".jx", "gitops", v1alpha1.LintPolicyFileName),
+			Linter: func(path string, test *linter.Test) error {
+				return o.LintResource(path, test, &v1alpha1.LintPolicy{})
+			},
+		},
+		linter.Linter{
+			Path: filepath.Join(".jx", "gitops", v1alpha1.GCPolicyFileName),
+			Linter: func(path string, test *linter.Test) error {
+				return o.LintResource(path, test, &v1alpha1.GCPolicy{})
+			},
+		},
+		linter.Linter{
+			Path: filepath.Join(".jx", "gitops", v1alpha1.APIResourcesFileName),
+			Linter: func(path string, test *linter.Test) error {
+				return o.LintResource(path, test, &v1alpha1.APIResources{})
+			},
+		},
 		linter.Linter{
 			Path: "helmfile.yaml",
 			Linter: func(path string, test *linter.Test) error {
@@ -87,6 +173,45 @@ func (o *Options) Validate() error {
 			},
 		},
 	)
+
+	if o.Engine == nil {
+		policyFile := o.PolicyFile
+		if policyFile == "" {
+			policyFile = filepath.Join(o.Dir, lintpolicy.PolicyFile)
+		}
+		policy, err := lintpolicy.LoadPolicy(policyFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load lint policy")
+		}
+		namespacedKinds, err := apiresources.NamespacedKinds(filepath.Join(o.Dir, apiresources.DefaultFile))
+		if err != nil {
+			return errors.Wrapf(err, "failed to load the namespaced kinds")
+		}
+		err = apiresources.AddCustomResourceDefinitions(namespacedKinds, filepath.Join(o.Dir, o.ConfigRoot))
+		if err != nil {
+			return errors.Wrapf(err, "failed to find the namespaced kinds of the custom resources")
+		}
+		ctx := &lintpolicy.Context{
+			Dir:             o.Dir,
+			ConfigRoot:      o.ConfigRoot,
+			NamespacedKinds: namespacedKinds,
+		}
+		exists, err := files.FileExists(filepath.Join(o.Dir, v4beta1.RequirementsConfigFileName))
+		if err != nil {
+			return errors.Wrapf(err, "failed to check for requirements file")
+		}
+		if exists {
+			requirementsResource, _, err := v4beta1.LoadRequirementsConfig(o.Dir, false)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load requirements in dir %s", o.Dir)
+			}
+			ctx.Requirements = &requirementsResource.Spec
+		}
+		o.Engine, err = lintpolicy.NewEngine(ctx, policy)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create lint engine from policy %s", policyFile)
+		}
+	}
 	return nil
 }
 
@@ -97,5 +222,146 @@ func (o *Options) Run() error {
 		return errors.Wrapf(err, "failed to validate")
 	}
 
-	return o.Lint(o.Linters, o.Dir)
+	err = o.lintFiles()
+	if err != nil {
+		return err
+	}
+
+	findings, err := o.Engine.Lint(o.ConfigRoot)
+	if err != nil {
+		return errors.Wrapf(err, "failed to lint resources in %s", o.ConfigRoot)
+	}
+	o.Findings = append(o.Findings, findings...)
+
+	err = o.writeResults()
+	if err != nil {
+		return errors.Wrapf(err, "failed to write results")
+	}
+
+	if o.FailOn == FailOnNone {
+		return nil
+	}
+	level := lintpolicy.SeverityLevel(v1alpha1.LintSeverity(o.FailOn))
+	count := 0
+	for i := range o.Findings {
+		if lintpolicy.SeverityLevel(o.Findings[i].Severity) >= level {
+			count++
+		}
+	}
+	if count > 0 {
+		return errors.Errorf("found %d lint findings with severity %s or higher", count, o.FailOn)
+	}
+	return nil
+}
+
+// lintFiles validates the well known configuration files converting any failures into findings
+func (o *Options) lintFiles() error {
+	for _, l := range o.Linters {
+		path := filepath.Join(o.Dir, l.Path)
+		exists, err := files.FileExists(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check if file exists %s", path)
+		}
+		if !exists {
+			continue
+		}
+		test := &linter.Test{
+			File: l.Path,
+		}
+		o.Tests = append(o.Tests, test)
+
+		err = l.Linter(path, test)
+		if err != nil {
+			return errors.Wrapf(err, "failed to lint %s", path)
+		}
+		if test.Error != nil {
+			o.Findings = append(o.Findings, lintpolicy.Finding{
+				RuleID:   "schema",
+				Severity: v1alpha1.LintSeverityError,
+				Path:     filepath.ToSlash(l.Path),
+				Message:  test.Error.Error(),
+			})
+		}
+	}
+	return nil
+}
+
+func (o *Options) writeResults() error {
+	if o.Format == linter.FormatTap {
+		o.addFindingTests()
+		return o.LogResults()
+	}
+
+	w := o.Out
+	if o.OutFile != "" {
+		f, err := os.Create(o.OutFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create file %s", o.OutFile)
+		}
+		defer f.Close()
+		w = f
+	}
+	var err error
+	switch o.Format {
+	case FormatJSON:
+		err = lintpolicy.WriteJSON(w, o.Findings)
+	case FormatSARIF:
+		err = lintpolicy.WriteSARIF(w, o.Engine.Rules, o.Findings)
+	default:
+		o.writeTable(w)
+	}
+	if err != nil {
+		return err
+	}
+	if o.OutFile != "" {
+		log.Logger().Infof("saved file %s", info(o.OutFile))
+	}
+	return nil
+}
+
+func (o *Options) writeTable(w io.Writer) {
+	if len(o.Findings) == 0 {
+		log.Logger().Infof("no lint findings in %s", info(o.ConfigRoot))
+		return
+	}
+	t := table.CreateTable(w)
+	t.AddRow("SEVERITY", "RULE", "FILE", "RESOURCE", "MESSAGE")
+	for i := range o.Findings {
+		f := &o.Findings[i]
+		severity := string(f.Severity)
+		if f.Severity == v1alpha1.LintSeverityError {
+			severity = termcolor.ColorWarning(severity)
+		} else if f.Severity == v1alpha1.LintSeverityInfo {
+			severity = info(severity)
+		}
+		t.AddRow(severity, f.RuleID, f.Path, f.Resource, f.Message)
+	}
+	t.Render()
+}
+
+// addFindingTests adds a failed test for each file with findings which is not already a linter test
+// so that the TAP output includes the rule findings
+func (o *Options) addFindingTests() {
+	tests := map[string]*linter.Test{}
+	for _, test := range o.Tests {
+		tests[filepath.ToSlash(test.File)] = test
+	}
+	messages := map[string][]string{}
+	var paths []string
+	for i := range o.Findings {
+		f := &o.Findings[i]
+		if tests[f.Path] != nil {
+			continue
+		}
+		if messages[f.Path] == nil {
+			paths = append(paths, f.Path)
+		}
+		messages[f.Path] = append(messages[f.Path], fmt.Sprintf("%s %s: %s", f.Severity, f.RuleID, f.Message))
+	}
+	for _, path := range paths {
+		o.Tests = append(o.Tests, &linter.Test{
+			File:  path,
+			Error: errors.New(strings.Join(messages[path], "; ")),
+		})
+	}
 }
@@ -1,16 +1,49 @@
 package lint_test
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/lint"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/linter"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestLint(t *testing.T) {
 	_, o := lint.NewCmdLint()
 	o.Dir = "testdata"
+	buf := &bytes.Buffer{}
+	o.Out = buf
 
 	err := o.Run()
 	require.NoError(t, err, "failed to run")
+
+	text := buf.String()
+	t.Logf("%s\n", text)
+	assert.Equal(t, 1, strings.Count(text, "SEVERITY"), "should render a single findings table")
+	assert.Contains(t, text, "no-latest-image-tag")
+}
+
+func TestLintTap(t *testing.T) {
+	_, o := lint.NewCmdLint()
+	o.Dir = "testdata"
+	o.Format = linter.FormatTap
+	o.OutFile = filepath.Join(t.TempDir(), "lint.tap")
+	buf := &bytes.Buffer{}
+	o.Out = buf
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run")
+	assert.Empty(t, buf.String(), "should only write the TAP file")
+
+	data, err := os.ReadFile(o.OutFile)
+	require.NoError(t, err, "failed to read %s", o.OutFile)
+	text := string(data)
+	t.Logf("%s\n", text)
+	assert.Contains(t, text, "ok 1 - extensions/pipeline-catalog.yaml")
+	assert.Contains(t, text, "not ok 4 - config-root/namespaces/jx/myapp-deploy.yaml")
 }
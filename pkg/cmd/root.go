@@ -27,6 +27,7 @@ import (
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/requirement"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/sa"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/scheduler"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/secrets"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/split"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/upgrade"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/variables"
@@ -66,6 +67,7 @@ func Main() *cobra.Command {
 	cmd.AddCommand(requirement.NewCmdRequirement())
 	cmd.AddCommand(repository.NewCmdRepository())
 	cmd.AddCommand(sa.NewCmdServiceAccount())
+	cmd.AddCommand(secrets.NewCmdSecrets())
 	cmd.AddCommand(webhook.NewCmdWebhook())
 
 	cmd.AddCommand(cobras.SplitCommand(annotate.NewCmdUpdateAnnotate()))
@@ -0,0 +1,172 @@
+package convert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/extsecrets"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Converts the Secret resources in the config-root directory into ExternalSecret resources using the secret mapping rules
+
+		The rules in .jx/secret/mapping/secret-mappings.yaml are used to find the backend and the key and property of each data entry of the Secret. Data entries which have no mapping use the default naming convention of the backend and are reported.
+
+		The supported backend types are: vault, gcpSecretsManager, secretsManager (AWS), azureKeyVault and local
+`)
+
+	cmdExample = templates.Examples(`
+		# converts all the secrets in config-root into external secrets
+		%s secrets convert
+
+		# reports what would be converted without modifying any files
+		%s secrets convert --dry-run
+	`)
+)
+
+// Options the options for the command
+type Options struct {
+	Dir         string
+	MappingFile string
+	BackendType string
+	DryRun      bool
+	Results     []*extsecrets.Result
+}
+
+// NewCmdSecretsConvert creates a command object for the command
+func NewCmdSecretsConvert() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "convert",
+		Short:   "Converts the Secret resources in the config-root directory into ExternalSecret resources",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	cmd.Flags().StringVarP(&o.Dir, "dir", "d", "config-root", "the directory to recursively look for the Secret resources")
+	cmd.Flags().StringVarP(&o.MappingFile, "mapping-file", "m", extsecrets.MappingFile, "the secret mappings file")
+	cmd.Flags().StringVarP(&o.BackendType, "backend-type", "b", "", "the backend type to use for secrets which have no backend type in the secret mappings file")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "reports the secrets which would be converted without modifying any files")
+	return cmd, o
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	mapping, err := extsecrets.LoadSecretMapping(o.MappingFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load secret mappings")
+	}
+	if o.BackendType != "" {
+		if extsecrets.Backends[v1alpha1.BackendType(o.BackendType)] == nil {
+			return errors.Errorf("unsupported backend type %s", o.BackendType)
+		}
+	}
+	converter := &extsecrets.Converter{
+		Mapping:     mapping,
+		BackendType: v1alpha1.BackendType(o.BackendType),
+	}
+
+	filter := kyamls.Filter{
+		Kinds: []string{"Secret"},
+	}
+
+	// lets check all the secrets before we modify any files
+	err = kyamls.ModifyFiles(o.Dir, o.convertFn(converter, false), filter)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert secrets in dir %s", o.Dir)
+	}
+
+	var missing []string
+	for _, r := range o.Results {
+		if r.Mandatory && len(r.UnmappedKeys) > 0 {
+			missing = append(missing, fmt.Sprintf("%s/%s keys %s", r.Namespace, r.Name, strings.Join(r.UnmappedKeys, ", ")))
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("mandatory secrets have no mapping for: %s", strings.Join(missing, "; "))
+	}
+
+	if !o.DryRun {
+		err = kyamls.ModifyFiles(o.Dir, o.convertFn(converter, true), filter)
+		if err != nil {
+			return errors.Wrapf(err, "failed to convert secrets in dir %s", o.Dir)
+		}
+	}
+	o.logResults()
+	return nil
+}
+
+// convertFn returns the function to convert each Secret. If write is false the results are collected
+// without modifying the node
+func (o *Options) convertFn(converter *extsecrets.Converter, write bool) func(node *yaml.RNode, path string) (bool, error) {
+	return func(node *yaml.RNode, path string) (bool, error) {
+		obj, err := node.Map()
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to convert node to a map")
+		}
+		extSecret, result, err := converter.Convert(obj, path)
+		if err != nil {
+			return false, err
+		}
+		if extSecret == nil {
+			return false, nil
+		}
+		if !write {
+			o.Results = append(o.Results, result)
+			return false, nil
+		}
+		newNode, err := yaml.FromMap(extSecret)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to create ExternalSecret node")
+		}
+		node.SetYNode(newNode.YNode())
+		return true, nil
+	}
+}
+
+func (o *Options) logResults() {
+	if len(o.Results) == 0 {
+		log.Logger().Infof("no Secret resources found in %s", info(o.Dir))
+		return
+	}
+	t := table.CreateTable(os.Stdout)
+	t.AddRow("NAMESPACE", "NAME", "BACKEND", "RULE", "UNMAPPED KEYS")
+	for _, r := range o.Results {
+		rule := "mapping"
+		if r.DefaultRule {
+			rule = "defaults"
+		}
+		unmapped := strings.Join(r.UnmappedKeys, ", ")
+		if unmapped != "" {
+			unmapped = termcolor.ColorWarning(unmapped)
+		}
+		t.AddRow(r.Namespace, r.Name, string(r.BackendType), rule, unmapped)
+	}
+	t.Render()
+
+	if o.DryRun {
+		log.Logger().Infof("dry run so not modifying %d secrets", len(o.Results))
+		return
+	}
+	log.Logger().Infof("converted %d secrets in %s to ExternalSecrets", len(o.Results), info(filepath.Clean(o.Dir)))
+}
@@ -0,0 +1,80 @@
+package convert_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/secrets/convert"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretsConvert(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join("testdata", "config-root"), tmpDir)
+	require.NoError(t, err, "failed to copy config-root")
+
+	_, o := convert.NewCmdSecretsConvert()
+	o.Dir = tmpDir
+	o.MappingFile = filepath.Join("testdata", "secret-mappings.yaml")
+	err = o.Run()
+	require.NoError(t, err, "failed to run")
+
+	require.Len(t, o.Results, 3, "results")
+	for _, r := range o.Results {
+		if r.Name == "mysecret" {
+			assert.True(t, r.DefaultRule, "should use the default rule for %s", r.Name)
+			assert.Equal(t, []string{"password", "username"}, r.UnmappedKeys, "unmapped keys for %s", r.Name)
+		}
+	}
+
+	expectedDir := filepath.Join("testdata", "expected", "namespaces", "jx")
+	fileNames, err := os.ReadDir(expectedDir)
+	require.NoError(t, err, "failed to read dir %s", expectedDir)
+	for _, f := range fileNames {
+		expectedFile := filepath.Join(expectedDir, f.Name())
+		resultFile := filepath.Join(tmpDir, "namespaces", "jx", f.Name())
+		expected, err := os.ReadFile(expectedFile)
+		require.NoError(t, err, "failed to load %s", expectedFile)
+		result, err := os.ReadFile(resultFile)
+		require.NoError(t, err, "failed to load %s", resultFile)
+		assert.Equal(t, string(expected), string(result), "converted file %s", f.Name())
+	}
+}
+
+func TestSecretsConvertMandatoryMissingMapping(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join("testdata", "config-root"), tmpDir)
+	require.NoError(t, err, "failed to copy config-root")
+
+	path := filepath.Join(tmpDir, "namespaces", "jx", "lighthouse-oauth-token-secret.yaml")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to load %s", path)
+	data = append(data, []byte("  hmac: \"\"\n")...)
+	err = os.WriteFile(path, data, files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to save %s", path)
+
+	_, o := convert.NewCmdSecretsConvert()
+	o.Dir = tmpDir
+	o.MappingFile = filepath.Join("testdata", "secret-mappings.yaml")
+	err = o.Run()
+	require.Error(t, err, "should fail as the mandatory secret has an unmapped key")
+	assert.Contains(t, err.Error(), "jx/lighthouse-oauth-token keys hmac")
+
+	// no secrets should have been converted
+	inputDir := filepath.Join("testdata", "config-root", "namespaces", "jx")
+	fileNames, err := os.ReadDir(inputDir)
+	require.NoError(t, err, "failed to read dir %s", inputDir)
+	for _, f := range fileNames {
+		if f.Name() == "lighthouse-oauth-token-secret.yaml" {
+			continue
+		}
+		expected, err := os.ReadFile(filepath.Join(inputDir, f.Name()))
+		require.NoError(t, err, "failed to load %s", f.Name())
+		result, err := os.ReadFile(filepath.Join(tmpDir, "namespaces", "jx", f.Name()))
+		require.NoError(t, err, "failed to load %s", f.Name())
+		assert.Equal(t, string(expected), string(result), "file %s should not be modified", f.Name())
+	}
+}
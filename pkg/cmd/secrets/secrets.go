@@ -0,0 +1,24 @@
+package secrets
+
+import (
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/secrets/convert"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSecrets creates the new command
+func NewCmdSecrets() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "secrets",
+		Short: "Commands for working with Secrets in the cluster git repository",
+		Run: func(command *cobra.Command, _ []string) {
+			err := command.Help()
+			if err != nil {
+				log.Logger().Error(err.Error())
+			}
+		},
+	}
+	command.AddCommand(cobras.SplitCommand(convert.NewCmdSecretsConvert()))
+	return command
+}
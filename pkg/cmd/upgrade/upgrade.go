@@ -12,6 +12,7 @@ import (
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/plugins"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/tfupgrade"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/pkg/errors"
@@ -90,9 +91,25 @@ func (o *Options) doHelmfileUpgrade() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to update the helmfile versions")
 	}
+	o.summariseHeldBackReleases()
 	return nil
 }
 
+// summariseHeldBackReleases displays the releases which were not upgraded due to their update labels
+func (o *Options) summariseHeldBackReleases() {
+	heldBack := o.HelmfileResolve.Results.HeldBack
+	if len(heldBack) == 0 {
+		return
+	}
+	log.Logger().Infof("the following releases were held back by their update labels:\n")
+	t := table.CreateTable(os.Stdout)
+	t.AddRow("RELEASE", "CHART", "CURRENT", "AVAILABLE", "REASON")
+	for _, r := range heldBack {
+		t.AddRow(r.Release, r.Chart, r.Version, r.AvailableVersion, r.Reason)
+	}
+	t.Render()
+}
+
 func (o *Options) doTerraformUpgrade() error {
 	if o.Options.Dir != "" {
 		o.TerraformUpgrade.Dir = o.Options.Dir
@@ -0,0 +1,145 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FormatTable displays the changes as a table
+	FormatTable = "table"
+	// FormatJSON displays the changes as JSON
+	FormatJSON = "json"
+	// FormatMarkdown displays the changes as a markdown table
+	FormatMarkdown = "markdown"
+)
+
+var (
+	cmdLong = templates.LongDesc(`
+		Displays the charts, images, git repositories and packages whose versions changed between two git refs of the version stream
+
+		The refs are of the git repository which contains the version stream directory such as the cluster git repository.
+`)
+
+	cmdExample = templates.Examples(`
+		# displays the version changes since the previous commit
+		%s versionstream diff --from HEAD~1
+
+		# displays the changes between two refs as markdown to paste into a pull request
+		%[1]s versionstream diff --from v1.2.0 --to v1.3.0 --format markdown
+	`)
+
+	formats = []string{FormatTable, FormatJSON, FormatMarkdown}
+)
+
+// Options the options for the command
+type Options struct {
+	versionstreamer.Options
+	From   string
+	To     string
+	Format string
+	Gitter gitclient.Interface
+
+	// Changes the changed entries
+	Changes []*versionstreamer.EntryChange
+}
+
+// NewCmdVersionStreamDiff creates a command object for the command
+func NewCmdVersionStreamDiff() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "diff",
+		Short:   "Displays the version changes between two git refs of the version stream",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.Options.AddFlags(cmd)
+	cmd.Flags().StringVarP(&o.From, "from", "", "", "the git ref to compare from")
+	cmd.Flags().StringVarP(&o.To, "to", "", "HEAD", "the git ref to compare to")
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatTable, "the output format. Supported values: "+strings.Join(formats, ", "))
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	if o.From == "" {
+		return options.MissingOption("from")
+	}
+	if o.To == "" {
+		o.To = "HEAD"
+	}
+	if stringhelpers.StringArrayIndex(formats, o.Format) < 0 {
+		return options.InvalidOption("format", o.Format, formats)
+	}
+	err := o.Options.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+	if o.Gitter == nil {
+		o.Gitter = cli.NewCLIClient("", o.QuietCommandRunner)
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	o.Changes, err = versionstreamer.DiffEntries(o.Gitter, o.VersionStreamDir, o.From, o.To)
+	if err != nil {
+		return errors.Wrapf(err, "failed to compare the version stream between %s and %s", o.From, o.To)
+	}
+
+	switch o.Format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(o.Changes, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal changes to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	case FormatMarkdown:
+		_, err := fmt.Fprint(o.Out, ToMarkdown(o.Changes))
+		return err
+	default:
+		t := table.CreateTable(o.Out)
+		t.AddRow("KIND", "NAME", "FROM", "TO", "CHANGE")
+		for _, c := range o.Changes {
+			t.AddRow(c.Kind, c.Name, c.From, c.To, c.Change)
+		}
+		t.Render()
+		return nil
+	}
+}
+
+// ToMarkdown converts the changes to a markdown table
+func ToMarkdown(changes []*versionstreamer.EntryChange) string {
+	sb := strings.Builder{}
+	sb.WriteString("| Kind | Name | From | To | Change |\n")
+	sb.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, c := range changes {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", c.Kind, c.Name, c.From, c.To, c.Change))
+	}
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package diff_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/diff"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionStreamDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	versionStreamDir := filepath.Join(tmpDir, "versionStream")
+	err := files.CopyDirOverwrite(filepath.Join("..", "testdata", "entries"), tmpDir)
+	require.NoError(t, err, "failed to copy the cluster repository")
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	err = gitclient.Init(g, tmpDir)
+	require.NoError(t, err, "failed to git init")
+	_, _, err = gitclient.EnsureUserAndEmailSetup(g, tmpDir, "", "")
+	require.NoError(t, err, "failed to ensure user and email are setup for git")
+
+	writeFile(t, tmpDir, "helmfile.yaml", "helmfiles: []\n")
+	_, err = gitclient.AddAndCommitFiles(g, tmpDir, "initial version stream")
+	require.NoError(t, err, "failed to commit")
+
+	writeFile(t, versionStreamDir, "charts/jxgh/lighthouse/defaults.yaml", "gitUrl: https://github.com/jenkins-x/lighthouse\nnamespace: jx\nversion: 1.17.0\n")
+	writeFile(t, versionStreamDir, "charts/jxgh/lighthouse/values.yaml.gotmpl", "cluster: {}\n")
+	writeFile(t, versionStreamDir, "charts/jetstack/cert-manager/defaults.yaml", "namespace: cert-manager\nversion: v1.14.4\nlabels:\n  foo: bar\n")
+	writeFile(t, versionStreamDir, "docker/ghcr.io/jenkins-x/jx-preview.yml", "version: 0.3.1\n")
+	writeFile(t, tmpDir, "helmfile.yaml", "helmfiles:\n- path: helmfiles/jx/helmfile.yaml\n")
+	err = os.Remove(filepath.Join(versionStreamDir, "packages", "kubectl.yml"))
+	require.NoError(t, err, "failed to remove package")
+	_, err = gitclient.AddAndCommitFiles(g, tmpDir, "upgrade version stream")
+	require.NoError(t, err, "failed to commit")
+
+	_, o := diff.NewCmdVersionStreamDiff()
+	o.Dir = tmpDir
+	o.From = "HEAD~1"
+	o.Format = diff.FormatMarkdown
+	buf := &bytes.Buffer{}
+	o.Out = buf
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run diff")
+
+	expected := []*versionstreamer.EntryChange{
+		{Kind: "chart", Name: "jxgh/lighthouse", From: "1.16.2", To: "1.17.0", Change: versionstreamer.ChangeUpdated},
+		{Kind: "image", Name: "ghcr.io/jenkins-x/jx-preview", To: "0.3.1", Change: versionstreamer.ChangeAdded},
+		{Kind: "package", Name: "kubectl", From: "1.29.2", Change: versionstreamer.ChangeRemoved},
+	}
+	assert.Equal(t, expected, o.Changes, "changes")
+	assert.Contains(t, buf.String(), "| chart | jxgh/lighthouse | 1.16.2 | 1.17.0 | updated |", "markdown output")
+	t.Logf("diff:\n%s\n", buf.String())
+}
+
+func writeFile(t *testing.T, dir, name, text string) {
+	path := filepath.Join(dir, name)
+	err := os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create dir for %s", path)
+	err = os.WriteFile(path, []byte(text), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to write %s", path)
+}
@@ -0,0 +1,154 @@
+package get
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FormatText displays the entry as a table of fields
+	FormatText = "text"
+	// FormatJSON displays the entry as JSON
+	FormatJSON = "json"
+)
+
+var (
+	cmdLong = templates.LongDesc(`
+		Displays the version stream entry of a chart, image, git repository or package
+
+		The resolved version is displayed along with any replacement chart and prefix, the default namespace and labels.
+`)
+
+	cmdExample = templates.Examples(`
+		# displays the version stream entry of a chart
+		%s versionstream get chart jxgh/lighthouse
+
+		# displays the version of a container image
+		%[1]s versionstream get image ghcr.io/jenkins-x/jx-boot
+
+		# displays the version of a git repository as JSON
+		%[1]s versionstream get git github.com/jenkins-x/jx3-pipeline-catalog --format json
+	`)
+
+	formats = []string{FormatText, FormatJSON}
+)
+
+// Options the options for the command
+type Options struct {
+	versionstreamer.Options
+	Kind   string
+	Name   string
+	Format string
+
+	// Entry the entry found in the version stream
+	Entry *versionstreamer.Entry
+}
+
+// NewCmdVersionStreamGet creates a command object for the command
+func NewCmdVersionStreamGet() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "get chart|image|git|package <name>",
+		Short:   "Displays the version stream entry of a chart, image, git repository or package",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, args []string) {
+			if len(args) > 0 {
+				o.Kind = args[0]
+			}
+			if len(args) > 1 {
+				o.Name = args[1]
+			}
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.Options.AddFlags(cmd)
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatText, "the output format. Supported values: "+strings.Join(formats, ", "))
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	if o.Kind == "" {
+		return options.MissingOption("kind")
+	}
+	if _, ok := versionstreamer.ToVersionKind(o.Kind); !ok {
+		return options.InvalidOption("kind", o.Kind, versionstreamer.KindNames)
+	}
+	if o.Name == "" {
+		return options.MissingOption("name")
+	}
+	if stringhelpers.StringArrayIndex(formats, o.Format) < 0 {
+		return options.InvalidOption("format", o.Format, formats)
+	}
+	err := o.Options.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	kind, _ := versionstreamer.ToVersionKind(o.Kind)
+	sv, err := o.Resolver.StableVersion(kind, o.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the version stream entry of %s %s", o.Kind, o.Name)
+	}
+	if sv.Missing() {
+		return errors.Errorf("there is no %s %s in the version stream %s", o.Kind, o.Name, o.VersionStreamDir)
+	}
+	o.Entry = versionstreamer.NewEntry(o.Kind, o.Name, sv)
+
+	if o.Format == FormatJSON {
+		data, err := json.MarshalIndent(o.Entry, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal entry to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	}
+
+	e := o.Entry
+	t := table.CreateTable(o.Out)
+	t.AddRow("KIND", e.Kind)
+	t.AddRow("NAME", e.Name)
+	t.AddRow("VERSION", e.Version)
+	addOptionalRow(&t, "REPLACEMENT CHART", e.ReplacementChart)
+	addOptionalRow(&t, "REPLACEMENT PREFIX", e.ReplacementChartPrefix)
+	addOptionalRow(&t, "NAMESPACE", e.Namespace)
+	addOptionalRow(&t, "GIT URL", e.GitURL)
+	var labels []string
+	for k, v := range e.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	addOptionalRow(&t, "LABELS", strings.Join(labels, ","))
+	t.Render()
+	return nil
+}
+
+func addOptionalRow(t *table.Table, name, value string) {
+	if value != "" {
+		t.AddRow(name, value)
+	}
+}
@@ -0,0 +1,90 @@
+package get_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/get"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionStreamGet(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		name     string
+		expected versionstreamer.Entry
+		hasError bool
+	}{
+		{
+			kind: "chart",
+			name: "jxgh/lighthouse",
+			expected: versionstreamer.Entry{
+				Kind:      "chart",
+				Name:      "jxgh/lighthouse",
+				Version:   "1.16.2",
+				Namespace: "jx",
+				GitURL:    "https://github.com/jenkins-x/lighthouse",
+				Labels:    map[string]string{"values.jenkins-x.io": "no-jx-values"},
+			},
+		},
+		{
+			kind: "chart",
+			name: "jxgh/jx-kh-check",
+			expected: versionstreamer.Entry{
+				Kind:                   "chart",
+				Name:                   "jxgh/jx-kh-check",
+				Version:                "0.0.76",
+				ReplacementChart:       "jx-health-checks",
+				ReplacementChartPrefix: "jxgh",
+			},
+		},
+		{
+			kind:     "image",
+			name:     "ghcr.io/jenkins-x/jx-boot",
+			expected: versionstreamer.Entry{Kind: "image", Name: "ghcr.io/jenkins-x/jx-boot", Version: "3.10.150"},
+		},
+		{
+			kind: "git",
+			name: "https://github.com/jenkins-x/jx3-pipeline-catalog.git",
+			expected: versionstreamer.Entry{
+				Kind:    "git",
+				Name:    "https://github.com/jenkins-x/jx3-pipeline-catalog.git",
+				Version: "1.2.3",
+				GitURL:  "https://github.com/jenkins-x/jx3-pipeline-catalog.git",
+			},
+		},
+		{
+			kind:     "chart",
+			name:     "jxgh/does-not-exist",
+			hasError: true,
+		},
+		{
+			kind:     "cheese",
+			name:     "jxgh/lighthouse",
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, o := get.NewCmdVersionStreamGet()
+		o.Dir = filepath.Join("..", "testdata", "entries")
+		o.Kind = tc.kind
+		o.Name = tc.name
+		buf := &bytes.Buffer{}
+		o.Out = buf
+
+		err := o.Run()
+		if tc.hasError {
+			require.Error(t, err, "expected error for %s %s", tc.kind, tc.name)
+			t.Logf("got expected error %s\n", err.Error())
+			continue
+		}
+		require.NoError(t, err, "failed to get %s %s", tc.kind, tc.name)
+		require.NotNil(t, o.Entry, "no entry for %s %s", tc.kind, tc.name)
+		assert.Equal(t, tc.expected, *o.Entry, "entry for %s %s", tc.kind, tc.name)
+		assert.Contains(t, buf.String(), tc.expected.Version, "output for %s %s", tc.kind, tc.name)
+	}
+}
@@ -0,0 +1,120 @@
+package list
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FormatTable displays the entries as a table
+	FormatTable = "table"
+	// FormatJSON displays the entries as JSON
+	FormatJSON = "json"
+)
+
+var (
+	cmdLong = templates.LongDesc(`
+		Lists the entries in the version stream
+
+		By default the charts, images, git repositories and packages are all listed.
+`)
+
+	cmdExample = templates.Examples(`
+		# lists all the entries in the version stream
+		%s versionstream list
+
+		# lists the charts and images in the version stream
+		%[1]s versionstream list --kind chart --kind image
+	`)
+
+	formats = []string{FormatTable, FormatJSON}
+)
+
+// Options the options for the command
+type Options struct {
+	versionstreamer.Options
+	Kinds  []string
+	Format string
+
+	// Entries the entries found in the version stream
+	Entries []*versionstreamer.Entry
+}
+
+// NewCmdVersionStreamList creates a command object for the command
+func NewCmdVersionStreamList() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "Lists the entries in the version stream",
+		Aliases: []string{"ls"},
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.Options.AddFlags(cmd)
+	cmd.Flags().StringArrayVarP(&o.Kinds, "kind", "k", nil, "the kinds of entries to list. Supported values: "+strings.Join(versionstreamer.KindNames, ", "))
+	cmd.Flags().StringVarP(&o.Format, "format", "f", FormatTable, "the output format. Supported values: "+strings.Join(formats, ", "))
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	for _, kind := range o.Kinds {
+		if stringhelpers.StringArrayIndex(versionstreamer.KindNames, kind) < 0 {
+			return options.InvalidOption("kind", kind, versionstreamer.KindNames)
+		}
+	}
+	if stringhelpers.StringArrayIndex(formats, o.Format) < 0 {
+		return options.InvalidOption("format", o.Format, formats)
+	}
+	err := o.Options.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate")
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	o.Entries, err = versionstreamer.LoadEntries(o.VersionStreamDir, o.Kinds...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load the version stream entries")
+	}
+
+	if o.Format == FormatJSON {
+		data, err := json.MarshalIndent(o.Entries, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal entries to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		return err
+	}
+
+	t := table.CreateTable(o.Out)
+	t.AddRow("KIND", "NAME", "VERSION")
+	for _, e := range o.Entries {
+		t.AddRow(e.Kind, e.Name, e.Version)
+	}
+	t.Render()
+	return nil
+}
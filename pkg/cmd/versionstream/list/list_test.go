@@ -0,0 +1,52 @@
+package list_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/list"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionStreamList(t *testing.T) {
+	testCases := []struct {
+		kinds    []string
+		expected []string
+	}{
+		{
+			expected: []string{
+				"chart jetstack/cert-manager v1.14.4",
+				"chart jxgh/jx-kh-check 0.0.76",
+				"chart jxgh/lighthouse 1.16.2",
+				"git github.com/jenkins-x/jx3-pipeline-catalog 1.2.3",
+				"image ghcr.io/jenkins-x/jx-boot 3.10.150",
+				"package kubectl 1.29.2",
+			},
+		},
+		{
+			kinds: []string{"image", "package"},
+			expected: []string{
+				"image ghcr.io/jenkins-x/jx-boot 3.10.150",
+				"package kubectl 1.29.2",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, o := list.NewCmdVersionStreamList()
+		o.Dir = filepath.Join("..", "testdata", "entries")
+		o.Kinds = tc.kinds
+		o.Out = &bytes.Buffer{}
+
+		err := o.Run()
+		require.NoError(t, err, "failed to list kinds %v", tc.kinds)
+
+		var got []string
+		for _, e := range o.Entries {
+			got = append(got, e.Kind+" "+e.Name+" "+e.Version)
+		}
+		assert.Equal(t, tc.expected, got, "entries for kinds %v", tc.kinds)
+	}
+}
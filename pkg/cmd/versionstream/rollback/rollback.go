@@ -0,0 +1,172 @@
+package rollback
+
+import (
+	"fmt"
+	"path/filepath"
+
+	kptupdate "github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/kpt/update"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+var (
+	cmdLong = templates.LongDesc(`
+		Rolls back the cluster version stream to the version stream used before the last switch
+
+		The previous version stream is taken from the .jx/gitops/versionstream-history.yaml file which is populated by 'jx gitops versionstream'. The upstream of the versionStream/Kptfile is restored, the change is committed and then the version stream files are updated via 'kpt' to the commit SHA recorded in the history.
+`)
+
+	cmdExample = templates.Examples(`
+		# rolls back to the previous version stream
+		%s versionstream rollback
+	`)
+
+	info = termcolor.ColorInfo
+)
+
+// Options the options for the command
+type Options struct {
+	options.BaseOptions
+	Dir           string
+	KptBinary     string
+	Strategy      string
+	NoUpdate      bool
+	GitClient     gitclient.Interface
+	CommandRunner cmdrunner.CommandRunner
+}
+
+// NewCmdVersionStreamRollback creates a command object for the command
+func NewCmdVersionStreamRollback() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "rollback",
+		Short:   "Rolls back the cluster version stream to the version stream used before the last switch",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, rootcmd.BinaryName),
+		Run: func(_ *cobra.Command, _ []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	o.BaseOptions.AddBaseFlags(cmd)
+	cmd.Flags().StringVarP(&o.Dir, "dir", "", ".", "the directory of the cluster git repository")
+	cmd.Flags().StringVarP(&o.KptBinary, "bin", "", "", "the 'kpt' binary name to use. If not specified this command will download the jx binary plugin into ~/.jx3/plugins/bin and use that")
+	cmd.Flags().StringVarP(&o.Strategy, "strategy", "s", "resource-merge", "the 'kpt' strategy to use if there is no override for the version stream. To see available strategies type 'kpt pkg update --help'")
+	cmd.Flags().BoolVarP(&o.NoUpdate, "no-update", "", false, "only restores and commits the Kptfile without updating the version stream files via 'kpt'")
+	return cmd, o
+}
+
+// Validate validates the options and populates any missing values
+func (o *Options) Validate() error {
+	if o.Dir == "" {
+		o.Dir = "."
+	}
+	if o.CommandRunner == nil {
+		o.CommandRunner = cmdrunner.QuietCommandRunner
+	}
+	if o.GitClient == nil {
+		o.GitClient = cli.NewCLIClient("", o.CommandRunner)
+	}
+	return nil
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate options")
+	}
+
+	historyFile := filepath.Join(o.Dir, versionstreamer.HistoryFile)
+	history, err := versionstreamer.LoadHistory(historyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load version stream history")
+	}
+	count := len(history.Spec.Entries)
+	if count == 0 {
+		return errors.Errorf("there is no previous version stream to rollback to in %s", historyFile)
+	}
+	previous := history.Spec.Entries[count-1]
+
+	kptFilePath := filepath.Join(o.Dir, "versionStream", "Kptfile")
+	exists, err := files.FileExists(kptFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if %s exists", kptFilePath)
+	}
+	if !exists {
+		return errors.Errorf("failed to find %s, clone your cluster git repository and rerun command", kptFilePath)
+	}
+	node, err := yaml.ReadFile(kptFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load file %s", kptFilePath)
+	}
+	err = versionstreamer.SetKptfileUpstream(node, kptFilePath, previous.URL, previous.Ref, previous.Directory)
+	if err != nil {
+		return err
+	}
+	err = yaml.WriteFile(node, kptFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save %s", kptFilePath)
+	}
+
+	history.Spec.Entries = history.Spec.Entries[0 : count-1]
+	err = versionstreamer.SaveHistory(history, historyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save version stream history")
+	}
+
+	err = o.gitCommit(previous.URL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to commit the rolled back Kptfile")
+	}
+	log.Logger().Infof("rolled back the version stream to %s ref %s", info(previous.URL), info(previous.Ref))
+
+	if o.NoUpdate {
+		return nil
+	}
+	version := previous.SHA
+	if version == "" {
+		version = previous.Ref
+	}
+	uk := &kptupdate.Options{
+		BaseOptions:   o.BaseOptions,
+		Dir:           o.Dir,
+		Version:       version,
+		RepositoryURL: previous.URL,
+		KptBinary:     o.KptBinary,
+		Strategy:      o.Strategy,
+		GitClient:     o.GitClient,
+		CommandRunner: o.CommandRunner,
+	}
+	err = uk.Run()
+	if err != nil {
+		return errors.Wrapf(err, "failed to update the version stream to %s using kpt", version)
+	}
+	return nil
+}
+
+func (o *Options) gitCommit(gitURL string) error {
+	_, err := o.GitClient.Command(o.Dir, "add", versionstreamer.HistoryFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add %s to git", versionstreamer.HistoryFile)
+	}
+	dir := filepath.Join(o.Dir, "versionStream")
+	_, err = gitclient.AddAndCommitFiles(o.GitClient, dir, "chore: rollback versionstream to "+gitURL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to commit changes to git in dir %s", dir)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package rollback_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/rollback"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionStreamRollback(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite(filepath.Join("testdata", "cluster"), tmpDir)
+	require.NoError(t, err, "failed to copy the cluster repository")
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	err = gitclient.Init(g, tmpDir)
+	require.NoError(t, err, "failed to git init")
+	_, _, err = gitclient.EnsureUserAndEmailSetup(g, tmpDir, "", "")
+	require.NoError(t, err, "failed to ensure user and email are setup for git")
+	_, err = gitclient.AddAndCommitFiles(g, tmpDir, "initial cluster")
+	require.NoError(t, err, "failed to commit")
+
+	runner := &fakerunner.FakeRunner{}
+	_, o := rollback.NewCmdVersionStreamRollback()
+	o.Dir = tmpDir
+	o.KptBinary = "kpt"
+	o.GitClient = g
+	o.CommandRunner = runner.Run
+
+	err = o.Run()
+	require.NoError(t, err, "failed to rollback")
+
+	testhelpers.AssertTextFilesEqual(t, filepath.Join("testdata", "expected", "Kptfile"), filepath.Join(tmpDir, "versionStream", "Kptfile"), "rolled back Kptfile")
+
+	history, err := versionstreamer.LoadHistory(filepath.Join(tmpDir, versionstreamer.HistoryFile))
+	require.NoError(t, err, "failed to load history")
+	require.Len(t, history.Spec.Entries, 1, "history entries")
+	assert.Equal(t, "https://github.com/jenkins-x/jx3-lts-versions", history.Spec.Entries[0].URL, "remaining history URL")
+
+	changes, err := gitclient.HasChanges(g, tmpDir)
+	require.NoError(t, err, "failed to check for git changes")
+	assert.False(t, changes, "should have committed the rollback")
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{
+			CLI: "kpt pkg update versionStream@3c8a9cbd5c6d7aa4a65d9b5ff1fb80b30e3ef5c1 --strategy force-delete-replace",
+			Dir: tmpDir,
+		},
+	)
+
+	// lets rollback again to the oldest version stream
+	runner = &fakerunner.FakeRunner{}
+	o.CommandRunner = runner.Run
+	o.NoUpdate = true
+	err = o.Run()
+	require.NoError(t, err, "failed to rollback again")
+
+	history, err = versionstreamer.LoadHistory(filepath.Join(tmpDir, versionstreamer.HistoryFile))
+	require.NoError(t, err, "failed to load history")
+	assert.Empty(t, history.Spec.Entries, "history entries")
+
+	err = o.Run()
+	require.Error(t, err, "should fail when there is no history")
+	t.Logf("got expected error %s\n", err.Error())
+}
+
+func TestVersionStreamRollbackNoHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(tmpDir, "versionStream"), files.DefaultDirWritePermissions)
+	require.NoError(t, err, "failed to create versionStream dir")
+
+	_, o := rollback.NewCmdVersionStreamRollback()
+	o.Dir = tmpDir
+	err = o.Run()
+	require.Error(t, err, "should fail when there is no history")
+	assert.Contains(t, err.Error(), "no previous version stream", "error message")
+}
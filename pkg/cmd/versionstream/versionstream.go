@@ -9,7 +9,14 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
 
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/diff"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/get"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/list"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/cmd/versionstream/rollback"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/rootcmd"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 
@@ -30,6 +37,10 @@ import (
 var (
 	createLong = templates.LongDesc(`
 		Administer the cluster version stream settings
+
+		Each time the version stream is switched the previous version stream URL, ref, directory and commit SHA are recorded in the .jx/gitops/versionstream-history.yaml file so that the switch can be undone via 'jx gitops versionstream rollback'.
+
+		Use --pin-sha to lock the version stream to the current commit SHA of the ref rather than a moving branch.
 `)
 
 	createExample = templates.Examples(`
@@ -41,6 +52,12 @@ var (
 
 		# switch to a custom version stream
 		%[1]s versionstream --custom --url https://github.com/foo/bar.git --ref main
+
+		# switch to the current commit of the LTS version stream
+		%[1]s versionstream --lts --pin-sha
+
+		# switch back to the previous version stream
+		%[1]s versionstream rollback
 	`)
 )
 
@@ -54,6 +71,7 @@ type Options struct {
 	LTS         bool
 	Latest      bool
 	Custom      bool
+	PinSHA      bool
 	DoGitCommit bool
 	GitURL      string
 	GitRef      string
@@ -88,6 +106,12 @@ func NewCmdVersionstream() (*cobra.Command, *Options) {
 	cmd.Flags().StringVarP(&o.GitURL, "url", "", "", "The git URL to clone to fetch the initial set of files for a helm 3 / helmfile based git configuration if this command is not run inside a git clone or against a GitOps based cluster")
 	cmd.Flags().StringVarP(&o.GitRef, "ref", "", "", "The kind of git server for the development environment")
 	cmd.Flags().StringVarP(&o.GitDir, "directory", "", "/", "The directory used in the versionstream, defaults to root")
+	cmd.Flags().BoolVarP(&o.PinSHA, "pin-sha", "", false, "Pins the version stream to the current commit SHA of the ref rather than the moving branch")
+
+	cmd.AddCommand(cobras.SplitCommand(diff.NewCmdVersionStreamDiff()))
+	cmd.AddCommand(cobras.SplitCommand(get.NewCmdVersionStreamGet()))
+	cmd.AddCommand(cobras.SplitCommand(list.NewCmdVersionStreamList()))
+	cmd.AddCommand(cobras.SplitCommand(rollback.NewCmdVersionStreamRollback()))
 
 	return cmd, o
 }
@@ -149,6 +173,7 @@ func (o *Options) switchVersionStream() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to load file %s", kptFilePath)
 	}
+	previous := versionstreamer.KptfileUpstream(node, kptFilePath)
 
 	modified, err := o.modifyFn(node, kptFilePath)
 	if err != nil {
@@ -163,6 +188,21 @@ func (o *Options) switchVersionStream() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to save %s", kptFilePath)
 	}
+
+	if previous.URL == "" {
+		return nil
+	}
+	historyFile := filepath.Join(o.Dir, versionstreamer.HistoryFile)
+	history, err := versionstreamer.LoadHistory(historyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load version stream history")
+	}
+	previous.Timestamp = metav1.Now()
+	history.Spec.Entries = append(history.Spec.Entries, previous)
+	err = versionstreamer.SaveHistory(history, historyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save version stream history")
+	}
 	return nil
 }
 
@@ -185,17 +225,24 @@ func (o *Options) modifyFn(node *yaml.RNode, path string) (bool, error) {
 		directory = o.GitDir
 	}
 
-	err := node.PipeE(yaml.LookupCreate(yaml.ScalarNode, "upstream", "git", "repo"), yaml.FieldSetter{StringValue: repo})
-	if err != nil {
-		return false, errors.Wrapf(err, "failed to set the git source repository to %s for %s", repo, path)
+	if o.PinSHA {
+		sha, err := versionstreamer.ResolveSHA(o.Git(), o.Dir, repo, ref)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to pin the version stream %s", repo)
+		}
+		log.Logger().Infof("pinning version stream %s ref %s to commit %s", termcolor.ColorInfo(repo), termcolor.ColorInfo(ref), termcolor.ColorInfo(sha))
+		ref = sha
 	}
-	err = node.PipeE(yaml.LookupCreate(yaml.ScalarNode, "upstream", "git", "ref"), yaml.FieldSetter{StringValue: ref})
-	if err != nil {
-		return false, errors.Wrapf(err, "failed to set the git source ref to %s for %s", ref, path)
+
+	current := versionstreamer.KptfileUpstream(node, path)
+	if current.URL == repo && current.Ref == ref && current.Directory == directory {
+		log.Logger().Infof("the version stream is already %s ref %s", termcolor.ColorInfo(repo), termcolor.ColorInfo(ref))
+		return false, nil
 	}
-	err = node.PipeE(yaml.LookupCreate(yaml.ScalarNode, "upstream", "git", "directory"), yaml.FieldSetter{StringValue: directory})
+
+	err := versionstreamer.SetKptfileUpstream(node, path, repo, ref, directory)
 	if err != nil {
-		return false, errors.Wrapf(err, "failed to set the git directory to %s for %s", ref, path)
+		return false, err
 	}
 	return true, nil
 }
@@ -221,8 +268,19 @@ func (o *Options) GitCommit() error {
 	}
 
 	gitter := o.Git()
-	dir := "versionStream"
-	_, err := gitclient.AddAndCommitFiles(gitter, dir, message)
+	historyFile := filepath.Join(o.Dir, versionstreamer.HistoryFile)
+	exists, err := files.FileExists(historyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", historyFile)
+	}
+	if exists {
+		_, err = gitter.Command(o.Dir, "add", versionstreamer.HistoryFile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add %s to git", historyFile)
+		}
+	}
+	dir := filepath.Join(o.Dir, "versionStream")
+	_, err = gitclient.AddAndCommitFiles(gitter, dir, message)
 	if err != nil {
 		return errors.Wrapf(err, "failed to commit changes to git in dir %s", dir)
 	}
@@ -1,10 +1,19 @@
 package versionstream
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 
@@ -89,3 +98,59 @@ func TestOptions_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestSwitchRecordsHistoryAndPinsSHA(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDir(filepath.Join("testdata", "lts"), tmpDir, true)
+	require.NoError(t, err, "failed to copy testdata")
+
+	// lets create a local version stream git repository to pin to
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	repoDir := t.TempDir()
+	err = gitclient.Init(g, repoDir)
+	require.NoError(t, err, "failed to git init")
+	_, _, err = gitclient.EnsureUserAndEmailSetup(g, repoDir, "", "")
+	require.NoError(t, err, "failed to ensure user and email are setup for git")
+	err = os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# versions\n"), files.DefaultFileWritePermissions)
+	require.NoError(t, err, "failed to write README.md")
+	_, err = gitclient.AddAndCommitFiles(g, repoDir, "initial versions")
+	require.NoError(t, err, "failed to commit")
+	sha, err := g.Command(repoDir, "rev-parse", "HEAD")
+	require.NoError(t, err, "failed to get the commit SHA")
+	sha = strings.TrimSpace(sha)
+
+	o := &Options{
+		Custom: true,
+		GitURL: repoDir,
+		GitRef: "HEAD",
+		GitDir: "/",
+		PinSHA: true,
+		Dir:    tmpDir,
+		Gitter: g,
+	}
+	err = o.switchVersionStream()
+	require.NoError(t, err, "failed to switch version stream")
+
+	kptFilePath := filepath.Join(tmpDir, "versionStream", "Kptfile")
+	node, err := yaml.ReadFile(kptFilePath)
+	require.NoError(t, err, "failed to load %s", kptFilePath)
+	assert.Equal(t, repoDir, kyamls.GetStringField(node, kptFilePath, "upstream", "git", "repo"), "Kptfile repo")
+	assert.Equal(t, sha, kyamls.GetStringField(node, kptFilePath, "upstream", "git", "ref"), "Kptfile ref")
+
+	history, err := versionstreamer.LoadHistory(filepath.Join(tmpDir, versionstreamer.HistoryFile))
+	require.NoError(t, err, "failed to load history")
+	require.Len(t, history.Spec.Entries, 1, "history entries")
+	entry := history.Spec.Entries[0]
+	assert.Equal(t, "https://github.com/jenkins-x/jxr-versions", entry.URL, "history URL")
+	assert.Equal(t, "master", entry.Ref, "history ref")
+	assert.Equal(t, "/", entry.Directory, "history directory")
+	assert.Equal(t, "49c9579ed07f43569939a6a90f65e1f1e98337be", entry.SHA, "history SHA")
+	assert.False(t, entry.Timestamp.IsZero(), "history timestamp")
+
+	// switching to the same version stream again should not record any more history
+	err = o.switchVersionStream()
+	require.NoError(t, err, "failed to switch version stream again")
+	history, err = versionstreamer.LoadHistory(filepath.Join(tmpDir, versionstreamer.HistoryFile))
+	require.NoError(t, err, "failed to load history")
+	assert.Len(t, history.Spec.Entries, 1, "history entries after switching to the same version stream")
+}
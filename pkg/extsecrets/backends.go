@@ -0,0 +1,155 @@
+package extsecrets
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+)
+
+var azureInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9-]`)
+
+// Backend generates the backend specific parts of an ExternalSecret
+type Backend interface {
+	// Spec populates the backend specific properties of the ExternalSecret spec
+	Spec(spec map[string]interface{}, rule *v1alpha1.SecretRule)
+
+	// DefaultMapping returns the mapping to use for a data key which has no explicit mapping
+	DefaultMapping(namespace, secretName, dataKey string, rule *v1alpha1.SecretRule) v1alpha1.Mapping
+
+	// Data returns the ExternalSecret data entry for the given mapping
+	Data(mapping *v1alpha1.Mapping, rule *v1alpha1.SecretRule) map[string]interface{}
+}
+
+// Backends the supported backends indexed by backend type
+var Backends = map[v1alpha1.BackendType]Backend{
+	v1alpha1.BackendTypeVault: &vaultBackend{},
+	v1alpha1.BackendTypeGSM:   &gsmBackend{},
+	v1alpha1.BackendTypeAWS:   &awsBackend{},
+	v1alpha1.BackendTypeAzure: &azureBackend{},
+	v1alpha1.BackendTypeLocal: &localBackend{},
+}
+
+type vaultBackend struct{}
+
+func (b *vaultBackend) Spec(spec map[string]interface{}, rule *v1alpha1.SecretRule) {
+	spec["vaultMountPoint"] = defaultString(rule.Vault.MountPoint, "kubernetes")
+	spec["vaultRole"] = defaultString(rule.Vault.Role, "vault-infra")
+}
+
+func (b *vaultBackend) DefaultMapping(namespace, secretName, dataKey string, rule *v1alpha1.SecretRule) v1alpha1.Mapping {
+	return v1alpha1.Mapping{
+		Name:     dataKey,
+		Key:      path.Join(defaultString(rule.Vault.KeyPrefix, "secret/data"), namespace, secretName),
+		Property: dataKey,
+	}
+}
+
+func (b *vaultBackend) Data(mapping *v1alpha1.Mapping, _ *v1alpha1.SecretRule) map[string]interface{} {
+	return keyPropertyData(mapping)
+}
+
+type gsmBackend struct{}
+
+func (b *gsmBackend) Spec(spec map[string]interface{}, rule *v1alpha1.SecretRule) {
+	if rule.GcpSecretsManager.ProjectID != "" {
+		spec["projectId"] = rule.GcpSecretsManager.ProjectID
+	}
+}
+
+func (b *gsmBackend) DefaultMapping(_, secretName, dataKey string, rule *v1alpha1.SecretRule) v1alpha1.Mapping {
+	key := secretName
+	if rule.GcpSecretsManager.UniquePrefix != "" {
+		key = rule.GcpSecretsManager.UniquePrefix + "-" + secretName
+	}
+	return v1alpha1.Mapping{
+		Name:     dataKey,
+		Key:      key,
+		Property: dataKey,
+	}
+}
+
+func (b *gsmBackend) Data(mapping *v1alpha1.Mapping, rule *v1alpha1.SecretRule) map[string]interface{} {
+	answer := keyPropertyData(mapping)
+	answer["version"] = defaultString(rule.GcpSecretsManager.Version, "latest")
+	return answer
+}
+
+type awsBackend struct{}
+
+func (b *awsBackend) Spec(spec map[string]interface{}, rule *v1alpha1.SecretRule) {
+	if rule.AwsSecretsManager.Region != "" {
+		spec["region"] = rule.AwsSecretsManager.Region
+	}
+	if rule.AwsSecretsManager.RoleArn != "" {
+		spec["roleArn"] = rule.AwsSecretsManager.RoleArn
+	}
+}
+
+func (b *awsBackend) DefaultMapping(namespace, secretName, dataKey string, _ *v1alpha1.SecretRule) v1alpha1.Mapping {
+	return v1alpha1.Mapping{
+		Name:     dataKey,
+		Key:      path.Join(namespace, secretName),
+		Property: dataKey,
+	}
+}
+
+func (b *awsBackend) Data(mapping *v1alpha1.Mapping, _ *v1alpha1.SecretRule) map[string]interface{} {
+	return keyPropertyData(mapping)
+}
+
+type azureBackend struct{}
+
+func (b *azureBackend) Spec(spec map[string]interface{}, rule *v1alpha1.SecretRule) {
+	if rule.AzureKeyVault.KeyVaultName != "" {
+		spec["keyVaultName"] = rule.AzureKeyVault.KeyVaultName
+	}
+}
+
+// DefaultMapping uses a secret per data key as Azure Key Vault secret names may only contain alphanumerics and dashes
+func (b *azureBackend) DefaultMapping(_, secretName, dataKey string, _ *v1alpha1.SecretRule) v1alpha1.Mapping {
+	return v1alpha1.Mapping{
+		Name: dataKey,
+		Key:  azureInvalidChars.ReplaceAllString(secretName+"-"+dataKey, "-"),
+	}
+}
+
+func (b *azureBackend) Data(mapping *v1alpha1.Mapping, _ *v1alpha1.SecretRule) map[string]interface{} {
+	return keyPropertyData(mapping)
+}
+
+type localBackend struct{}
+
+func (b *localBackend) Spec(map[string]interface{}, *v1alpha1.SecretRule) {
+}
+
+func (b *localBackend) DefaultMapping(_, secretName, dataKey string, _ *v1alpha1.SecretRule) v1alpha1.Mapping {
+	return v1alpha1.Mapping{
+		Name:     dataKey,
+		Key:      secretName,
+		Property: dataKey,
+	}
+}
+
+func (b *localBackend) Data(mapping *v1alpha1.Mapping, _ *v1alpha1.SecretRule) map[string]interface{} {
+	return keyPropertyData(mapping)
+}
+
+func keyPropertyData(mapping *v1alpha1.Mapping) map[string]interface{} {
+	answer := map[string]interface{}{
+		"name": mapping.Name,
+		"key":  mapping.Key,
+	}
+	if mapping.Property != "" {
+		answer["property"] = mapping.Property
+	}
+	return answer
+}
+
+func defaultString(value, defaultValue string) string {
+	if strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,190 @@
+package extsecrets
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIVersion the API version of the generated ExternalSecret resources
+	APIVersion = "kubernetes-client.io/v1"
+
+	// Kind the kind of the generated resources
+	Kind = "ExternalSecret"
+)
+
+var (
+	// MappingFile the default location of the secret mappings file relative to the cluster git repository
+	MappingFile = filepath.Join(".jx", "secret", "mapping", v1alpha1.SecretMappingFileName)
+
+	// skipSecretTypes secret types which are generated by kubernetes so should not be converted
+	skipSecretTypes = map[string]bool{
+		"kubernetes.io/service-account-token": true,
+	}
+)
+
+// Result the result of converting a Secret
+type Result struct {
+	Namespace   string
+	Name        string
+	Path        string
+	BackendType v1alpha1.BackendType
+	// DefaultRule the secret has no rule in the mapping file so the defaults were used
+	DefaultRule bool
+	Mandatory   bool
+	// UnmappedKeys the data keys which have no explicit mapping so the default mapping for the backend was used
+	UnmappedKeys []string
+}
+
+// Converter converts Secrets into ExternalSecrets using a SecretMapping
+type Converter struct {
+	Mapping *v1alpha1.SecretMapping
+
+	// BackendType if specified overrides the default backend type of the mapping
+	BackendType v1alpha1.BackendType
+}
+
+// LoadSecretMapping loads the secret mapping file if it exists
+func LoadSecretMapping(path string) (*v1alpha1.SecretMapping, error) {
+	mapping := &v1alpha1.SecretMapping{}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return mapping, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return mapping, nil
+	}
+	err = yamls.LoadFile(path, mapping)
+	if err != nil {
+		return mapping, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return mapping, nil
+}
+
+// Convert converts the given Secret into an ExternalSecret.
+// Returns nil if the secret should not be converted
+func (c *Converter) Convert(secret map[string]interface{}, path string) (map[string]interface{}, *Result, error) {
+	secretType, _ := secret["type"].(string)
+	if skipSecretTypes[secretType] {
+		return nil, nil, nil
+	}
+	metadata, _ := secret["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	if name == "" {
+		return nil, nil, errors.Errorf("secret has no name")
+	}
+
+	rule := c.Mapping.FindRule(namespace, name)
+	found := rule.Name != ""
+	c.populateDefaults(&rule, found)
+	result := &Result{
+		Namespace:   namespace,
+		Name:        name,
+		Path:        path,
+		BackendType: rule.BackendType,
+		DefaultRule: !found,
+		Mandatory:   rule.Mandatory,
+	}
+	if rule.BackendType == v1alpha1.BackendTypeNone {
+		return nil, result, errors.Errorf("no backend type configured for secret %s in namespace %s", name, namespace)
+	}
+	backend := Backends[rule.BackendType]
+	if backend == nil {
+		return nil, result, errors.Errorf("unsupported backend type %s for secret %s in namespace %s", rule.BackendType, name, namespace)
+	}
+
+	var data []interface{}
+	for _, key := range DataKeys(secret) {
+		m := rule.Find(key)
+		if m == nil {
+			dm := backend.DefaultMapping(namespace, name, key, &rule)
+			m = &dm
+			result.UnmappedKeys = append(result.UnmappedKeys, key)
+		}
+		data = append(data, backend.Data(m, &rule))
+	}
+
+	spec := map[string]interface{}{
+		"backendType": string(rule.BackendType),
+		"data":        data,
+	}
+	backend.Spec(spec, &rule)
+
+	template := map[string]interface{}{}
+	if secretType != "" {
+		template["type"] = secretType
+	}
+	templateMetadata := map[string]interface{}{}
+	newMetadata := map[string]interface{}{
+		"name": name,
+	}
+	if namespace != "" {
+		newMetadata["namespace"] = namespace
+	}
+	for _, k := range []string{"labels", "annotations"} {
+		if v, ok := metadata[k].(map[string]interface{}); ok && len(v) > 0 {
+			newMetadata[k] = v
+			templateMetadata[k] = v
+		}
+	}
+	if len(templateMetadata) > 0 {
+		template["metadata"] = templateMetadata
+	}
+	if len(template) > 0 {
+		spec["template"] = template
+	}
+
+	answer := map[string]interface{}{
+		"apiVersion": APIVersion,
+		"kind":       Kind,
+		"metadata":   newMetadata,
+		"spec":       spec,
+	}
+	return answer, result, nil
+}
+
+// populateDefaults populates any missing configuration of the rule from the defaults of the mapping
+func (c *Converter) populateDefaults(rule *v1alpha1.SecretRule, found bool) {
+	defaults := &c.Mapping.Spec.Defaults
+	if !found || rule.BackendType == v1alpha1.BackendTypeNone {
+		rule.BackendType = defaults.BackendType
+		if c.BackendType != v1alpha1.BackendTypeNone {
+			rule.BackendType = c.BackendType
+		}
+	}
+	if rule.GcpSecretsManager == (v1alpha1.GcpSecretsManager{}) {
+		rule.GcpSecretsManager = defaults.GcpSecretsManager
+	}
+	if rule.AwsSecretsManager == (v1alpha1.AwsSecretsManager{}) {
+		rule.AwsSecretsManager = defaults.AwsSecretsManager
+	}
+	if rule.AzureKeyVault == (v1alpha1.AzureKeyVault{}) {
+		rule.AzureKeyVault = defaults.AzureKeyVault
+	}
+	if rule.Vault == (v1alpha1.Vault{}) {
+		rule.Vault = defaults.Vault
+	}
+}
+
+// DataKeys returns the sorted keys of the data and stringData of the secret
+func DataKeys(secret map[string]interface{}) []string {
+	keys := map[string]bool{}
+	for _, field := range []string{"data", "stringData"} {
+		m, _ := secret[field].(map[string]interface{})
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	var answer []string
+	for k := range keys {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
@@ -0,0 +1,191 @@
+package fakeregistry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const (
+	// MediaTypeHelmConfig the media type of the config of a helm chart
+	MediaTypeHelmConfig = "application/vnd.cncf.helm.config.v1+json"
+	// MediaTypeHelmChart the media type of the content of a helm chart
+	MediaTypeHelmChart = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+	token = "fake-token"
+)
+
+// Registry an in-process OCI registry for use in tests which authenticates clients via bearer tokens
+type Registry struct {
+	Server *httptest.Server
+	// Host the host of the registry to use in OCI URLs
+	Host string
+	// Credentials if specified are required to get a token
+	Credentials ociregistry.Credentials
+	// Requests the paths of the requests received
+	Requests []string
+
+	lock         sync.Mutex
+	repositories map[string]*repository
+}
+
+type repository struct {
+	tags  map[string]string
+	blobs map[string][]byte
+}
+
+// New creates and starts a new registry
+func New() *Registry {
+	r := &Registry{
+		repositories: map[string]*repository{},
+	}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(r.serve))
+	r.Host = strings.TrimPrefix(r.Server.URL, "https://")
+	return r
+}
+
+// Close stops the registry
+func (r *Registry) Close() {
+	r.Server.Close()
+}
+
+// Client creates a registry client which trusts the registry
+func (r *Registry) Client() *ociregistry.Client {
+	return &ociregistry.Client{
+		HTTPClient: r.Server.Client(),
+	}
+}
+
+// URL returns the OCI URL of the repository path in the registry
+func (r *Registry) URL(path string) string {
+	return ociregistry.OCIScheme + r.Host + "/" + path
+}
+
+// AddChart adds the chart as a tag of its version in the repository
+func (r *Registry) AddChart(repositoryName string, metadata *chart.Metadata) string {
+	config, _ := json.Marshal(metadata)
+	return r.addManifest(repositoryName, metadata.Version, MediaTypeHelmConfig, config, MediaTypeHelmChart)
+}
+
+// AddImage adds an image tag to the repository returning the digest of its manifest
+func (r *Registry) AddImage(repositoryName, tag string) string {
+	return r.addManifest(repositoryName, tag, "application/vnd.oci.image.config.v1+json", []byte(`{"tag":"`+tag+`"}`), "application/vnd.oci.image.layer.v1.tar+gzip")
+}
+
+func (r *Registry) addManifest(repositoryName, tag, configType string, config []byte, layerType string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	repo := r.repositories[repositoryName]
+	if repo == nil {
+		repo = &repository{tags: map[string]string{}, blobs: map[string][]byte{}}
+		r.repositories[repositoryName] = repo
+	}
+	layer := []byte(repositoryName + ":" + tag)
+	configDigest := digest(config)
+	layerDigest := digest(layer)
+	repo.blobs[configDigest] = config
+	repo.blobs[layerDigest] = layer
+
+	manifest, _ := json.Marshal(&ociregistry.Manifest{
+		MediaType: ociregistry.MediaTypeOCIManifest,
+		Config:    ociregistry.Descriptor{MediaType: configType, Digest: configDigest, Size: int64(len(config))},
+		Layers:    []ociregistry.Descriptor{{MediaType: layerType, Digest: layerDigest, Size: int64(len(layer))}},
+	})
+	manifestDigest := digest(manifest)
+	repo.blobs[manifestDigest] = manifest
+	repo.tags[tag] = manifestDigest
+	return manifestDigest
+}
+
+func (r *Registry) serve(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	r.Requests = append(r.Requests, req.URL.Path)
+	r.lock.Unlock()
+
+	if req.URL.Path == "/token" {
+		if !r.Credentials.IsEmpty() {
+			user, password, ok := req.BasicAuth()
+			if !ok || user != r.Credentials.Username || password != r.Credentials.Password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"token":"` + token + `"}`))
+		return
+	}
+	path, ok := strings.CutPrefix(req.URL.Path, "/v2/")
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer "+token {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.Server.URL+`/token",service="`+r.Host+`"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch {
+	case strings.HasSuffix(path, "/tags/list"):
+		name := strings.TrimSuffix(path, "/tags/list")
+		repo := r.repositories[name]
+		if repo == nil {
+			http.NotFound(w, req)
+			return
+		}
+		tags := []string{}
+		for tag := range repo.tags {
+			tags = append(tags, tag)
+		}
+		data, _ := json.Marshal(map[string]interface{}{"name": name, "tags": tags})
+		_, _ = w.Write(data)
+	case strings.Contains(path, "/manifests/"):
+		i := strings.LastIndex(path, "/manifests/")
+		repo := r.repositories[path[:i]]
+		ref := path[i+len("/manifests/"):]
+		if repo == nil {
+			http.NotFound(w, req)
+			return
+		}
+		if d, ok := repo.tags[ref]; ok {
+			ref = d
+		}
+		data, ok := repo.blobs[ref]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", ociregistry.MediaTypeOCIManifest)
+		w.Header().Set(ociregistry.DigestHeader, ref)
+		_, _ = w.Write(data)
+	case strings.Contains(path, "/blobs/"):
+		i := strings.LastIndex(path, "/blobs/")
+		repo := r.repositories[path[:i]]
+		if repo == nil {
+			http.NotFound(w, req)
+			return
+		}
+		data, ok := repo.blobs[path[i+len("/blobs/"):]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(data)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,322 @@
+package gcpolicy
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/matcher"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// OrgLabel the lighthouse label for the repository owner
+	OrgLabel = "lighthouse.jenkins-x.io/refs.org"
+	// RepoLabel the lighthouse label for the repository name
+	RepoLabel = "lighthouse.jenkins-x.io/refs.repo"
+	// BranchLabel the lighthouse label for the branch name
+	BranchLabel = "lighthouse.jenkins-x.io/branch"
+	// ContextLabel the lighthouse label for the pipeline context
+	ContextLabel = "lighthouse.jenkins-x.io/context"
+
+	// NoRule the name of the rule in the summary for resources which match no rule
+	NoRule = "<none>"
+)
+
+// PolicyPath the default path of the policy file relative to the cluster repository
+var PolicyPath = filepath.Join(".jx", "gitops", v1alpha1.GCPolicyFileName)
+
+// Resource a pipeline resource which can be garbage collected
+type Resource struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	Owner      string
+	Repository string
+	Branch     string
+	Context    string
+	Labels     map[string]string
+
+	// Completed true if the resource has completed
+	Completed bool
+
+	// Timestamp the completion time or the start time if not known
+	Timestamp time.Time
+}
+
+// String returns the kind and name of the resource
+func (r *Resource) String() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// Decision whether a resource should be deleted and why
+type Decision struct {
+	Resource *Resource
+	Rule     string
+	Delete   bool
+	Reason   string
+
+	ruleIndex int
+}
+
+// SummaryRow the number of kept and deleted resources of a kind for a rule
+type SummaryRow struct {
+	Rule    string
+	Kind    string
+	Kept    int
+	Deleted int
+}
+
+// Engine evaluates the rules of a policy
+type Engine struct {
+	rules []*rule
+}
+
+type rule struct {
+	*v1alpha1.GCRule
+	owners       *matcher.Matcher
+	repositories *matcher.Matcher
+	branches     *matcher.Matcher
+	selector     labels.Selector
+}
+
+// DefaultPolicy returns the policy used if there is no policy file which is equivalent
+// to the defaults of the gc activities, jobs and pods commands
+func DefaultPolicy() *v1alpha1.GCPolicy {
+	return &v1alpha1.GCPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.APIVersion,
+			Kind:       v1alpha1.KindGCPolicy,
+		},
+		Spec: v1alpha1.GCPolicySpec{
+			Rules: []v1alpha1.GCRule{
+				{
+					Name:    "jobs",
+					Kinds:   []string{v1alpha1.GCKindJob},
+					MaxAge:  duration(time.Hour),
+					MinKeep: intPointer(1),
+				},
+				{
+					Name:   "pods",
+					Kinds:  []string{v1alpha1.GCKindPod},
+					MaxAge: duration(time.Hour),
+				},
+				{
+					Name:   "pipelineruns",
+					Kinds:  []string{v1alpha1.GCKindPipelineRun},
+					MaxAge: duration(time.Hour * 12),
+				},
+				{
+					Name:   "lighthousejobs",
+					Kinds:  []string{v1alpha1.GCKindLighthouseJob},
+					MaxAge: duration(time.Hour * 24 * 7),
+				},
+				{
+					Name:         "pull-requests",
+					Branches:     v1alpha1.GCPattern{Include: []string{"^PR-", "^batch$"}},
+					MaxAge:       duration(time.Hour * 48),
+					HistoryLimit: intPointer(2),
+				},
+				{
+					Name:         "releases",
+					MaxAge:       duration(time.Hour * 24 * 30),
+					HistoryLimit: intPointer(5),
+				},
+			},
+		},
+	}
+}
+
+// LoadPolicy loads the policy from the given file returning the default policy if it does not exist
+func LoadPolicy(path string) (*v1alpha1.GCPolicy, error) {
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return DefaultPolicy(), nil
+	}
+	policy := &v1alpha1.GCPolicy{}
+	err = yamls.LoadFile(path, policy)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return policy, nil
+}
+
+// NewEngine creates an engine for the rules of the policy
+func NewEngine(policy *v1alpha1.GCPolicy) (*Engine, error) {
+	e := &Engine{}
+	for i := range policy.Spec.Rules {
+		r := &policy.Spec.Rules[i]
+		if r.Name == "" {
+			return nil, errors.Errorf("missing name for gc rule %d", i)
+		}
+		for _, k := range r.Kinds {
+			if stringhelpers.StringArrayIndex(v1alpha1.GCKinds, k) < 0 {
+				return nil, errors.Errorf("unsupported kind %s for gc rule %s. Supported kinds: %s", k, r.Name, strings.Join(v1alpha1.GCKinds, ", "))
+			}
+		}
+		cr := &rule{GCRule: r}
+		var err error
+		cr.owners, err = r.Owners.Matcher()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid owners of gc rule %s", r.Name)
+		}
+		cr.repositories, err = r.Repositories.Matcher()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid repositories of gc rule %s", r.Name)
+		}
+		cr.branches, err = r.Branches.Matcher()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid branches of gc rule %s", r.Name)
+		}
+		cr.selector = labels.Everything()
+		if r.Selector != nil {
+			cr.selector, err = metav1.LabelSelectorAsSelector(r.Selector)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid selector of gc rule %s", r.Name)
+			}
+		}
+		e.rules = append(e.rules, cr)
+	}
+	return e, nil
+}
+
+// Namespaces returns the namespaces referenced by the rules
+func (e *Engine) Namespaces() []string {
+	var answer []string
+	for _, r := range e.rules {
+		for _, ns := range r.Namespaces {
+			if stringhelpers.StringArrayIndex(answer, ns) < 0 {
+				answer = append(answer, ns)
+			}
+		}
+	}
+	return answer
+}
+
+// Match returns the index of the first rule which matches the resource or -1 if none match
+func (e *Engine) Match(r *Resource) int {
+	for i, cr := range e.rules {
+		if cr.matches(r) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *rule) matches(res *Resource) bool {
+	if len(r.Kinds) > 0 && stringhelpers.StringArrayIndex(r.Kinds, res.Kind) < 0 {
+		return false
+	}
+	if len(r.Namespaces) > 0 && stringhelpers.StringArrayIndex(r.Namespaces, res.Namespace) < 0 {
+		return false
+	}
+	return r.owners.Matches(res.Owner) && r.repositories.Matches(res.Repository) && r.branches.Matches(res.Branch) &&
+		r.selector.Matches(labels.Set(res.Labels))
+}
+
+// Evaluate decides which of the resources should be deleted. The decisions are returned with the most recent resources first
+func (e *Engine) Evaluate(resources []*Resource, now time.Time) []*Decision {
+	sorted := append([]*Resource{}, resources...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.After(sorted[j].Timestamp)
+	})
+
+	counts := map[string]int{}
+	var answer []*Decision
+	for _, res := range sorted {
+		idx := e.Match(res)
+		d := &Decision{
+			Resource:  res,
+			Rule:      NoRule,
+			ruleIndex: idx,
+			Reason:    "no matching rule",
+		}
+		answer = append(answer, d)
+		if idx < 0 {
+			continue
+		}
+		r := e.rules[idx]
+		d.Rule = r.Name
+		if !res.Completed {
+			d.Reason = "not completed"
+			continue
+		}
+		key := strings.Join([]string{r.Name, res.Kind, res.Namespace, res.Owner, res.Repository, res.Branch, res.Context}, "/")
+		counts[key]++
+		if r.MinKeep != nil && counts[key] <= *r.MinKeep {
+			d.Reason = fmt.Sprintf("within minimum keep of %d", *r.MinKeep)
+			continue
+		}
+		age := now.Sub(res.Timestamp)
+		if r.MaxAge != nil && age > r.MaxAge.Duration {
+			d.Delete = true
+			d.Reason = fmt.Sprintf("older than %s", r.MaxAge.Duration.String())
+			continue
+		}
+		if r.HistoryLimit != nil && counts[key] > *r.HistoryLimit {
+			d.Delete = true
+			d.Reason = fmt.Sprintf("exceeds history limit of %d", *r.HistoryLimit)
+			continue
+		}
+		d.Reason = "within limits"
+	}
+	return answer
+}
+
+// Summarize returns the number of kept and deleted resources of each kind for each rule in the order of the rules
+func Summarize(decisions []*Decision) []*SummaryRow {
+	type key struct {
+		ruleIndex int
+		kind      string
+	}
+	rows := map[key]*SummaryRow{}
+	var keys []key
+	for _, d := range decisions {
+		k := key{ruleIndex: d.ruleIndex, kind: d.Resource.Kind}
+		if k.ruleIndex < 0 {
+			k.ruleIndex = math.MaxInt
+		}
+		row := rows[k]
+		if row == nil {
+			row = &SummaryRow{Rule: d.Rule, Kind: d.Resource.Kind}
+			rows[k] = row
+			keys = append(keys, k)
+		}
+		if d.Delete {
+			row.Deleted++
+		} else {
+			row.Kept++
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ruleIndex != keys[j].ruleIndex {
+			return keys[i].ruleIndex < keys[j].ruleIndex
+		}
+		return stringhelpers.StringArrayIndex(v1alpha1.GCKinds, keys[i].kind) < stringhelpers.StringArrayIndex(v1alpha1.GCKinds, keys[j].kind)
+	})
+	var answer []*SummaryRow
+	for _, k := range keys {
+		answer = append(answer, rows[k])
+	}
+	return answer
+}
+
+func duration(d time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: d}
+}
+
+func intPointer(i int) *int {
+	return &i
+}
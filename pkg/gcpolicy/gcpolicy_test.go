@@ -0,0 +1,66 @@
+package gcpolicy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/gcpolicy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy, err := gcpolicy.LoadPolicy("does/not/exist.yaml")
+	require.NoError(t, err)
+	engine, err := gcpolicy.NewEngine(policy)
+	require.NoError(t, err)
+
+	now := time.Now()
+	var resources []*gcpolicy.Resource
+	for i := 1; i <= 4; i++ {
+		resources = append(resources,
+			&gcpolicy.Resource{Kind: v1alpha1.GCKindPipelineActivity, Name: "pr", Owner: "org", Repository: "repo", Branch: "PR-1", Completed: true, Timestamp: now.Add(-time.Duration(i) * time.Hour)},
+			&gcpolicy.Resource{Kind: v1alpha1.GCKindPipelineActivity, Name: "release", Owner: "org", Repository: "repo", Branch: "main", Completed: true, Timestamp: now.Add(-time.Duration(i) * time.Hour)},
+		)
+	}
+	resources = append(resources,
+		&gcpolicy.Resource{Kind: v1alpha1.GCKindPipelineRun, Name: "old-run", Completed: true, Timestamp: now.Add(-13 * time.Hour)},
+		&gcpolicy.Resource{Kind: v1alpha1.GCKindPipelineRun, Name: "running", Completed: false, Timestamp: now.Add(-13 * time.Hour)},
+		&gcpolicy.Resource{Kind: v1alpha1.GCKindJob, Name: "old-job", Completed: true, Timestamp: now.Add(-3 * time.Hour)},
+		&gcpolicy.Resource{Kind: v1alpha1.GCKindJob, Name: "older-job", Completed: true, Timestamp: now.Add(-4 * time.Hour)},
+		&gcpolicy.Resource{Kind: v1alpha1.GCKindPod, Name: "old-pod", Completed: true, Timestamp: now.Add(-3 * time.Hour)},
+	)
+
+	decisions := engine.Evaluate(resources, now)
+	require.Len(t, decisions, len(resources))
+
+	rows := gcpolicy.Summarize(decisions)
+	var got []gcpolicy.SummaryRow
+	for _, r := range rows {
+		got = append(got, *r)
+	}
+	assert.Equal(t, []gcpolicy.SummaryRow{
+		{Rule: "jobs", Kind: v1alpha1.GCKindJob, Kept: 1, Deleted: 1},
+		{Rule: "pods", Kind: v1alpha1.GCKindPod, Kept: 0, Deleted: 1},
+		{Rule: "pipelineruns", Kind: v1alpha1.GCKindPipelineRun, Kept: 1, Deleted: 1},
+		{Rule: "pull-requests", Kind: v1alpha1.GCKindPipelineActivity, Kept: 2, Deleted: 2},
+		{Rule: "releases", Kind: v1alpha1.GCKindPipelineActivity, Kept: 4, Deleted: 0},
+	}, got)
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	_, err := gcpolicy.NewEngine(&v1alpha1.GCPolicy{
+		Spec: v1alpha1.GCPolicySpec{
+			Rules: []v1alpha1.GCRule{{Name: "bad", Kinds: []string{"Deployment"}}},
+		},
+	})
+	require.Error(t, err)
+
+	_, err = gcpolicy.NewEngine(&v1alpha1.GCPolicy{
+		Spec: v1alpha1.GCPolicySpec{
+			Rules: []v1alpha1.GCRule{{Name: "bad", Branches: v1alpha1.GCPattern{Include: []string{"("}}}},
+		},
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,140 @@
+package gcpolicy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	v1 "github.com/jenkins-x/jx-api/v4/pkg/apis/jenkins.io/v1"
+	lhv1alpha1 "github.com/jenkins-x/lighthouse-client/pkg/apis/lighthouse/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// FromPipelineActivity creates the resource for a PipelineActivity
+func FromPipelineActivity(a *v1.PipelineActivity) *Resource {
+	r := newResource(v1alpha1.GCKindPipelineActivity, &a.ObjectMeta)
+	r.Owner = firstString(a.RepositoryOwner(), r.Owner)
+	r.Repository = firstString(a.RepositoryName(), r.Repository)
+	r.Branch = firstString(a.BranchName(), a.Spec.GitBranch, r.Branch)
+	r.Context = firstString(a.Spec.Context, r.Context)
+	r.Completed = a.Spec.Status.IsTerminated()
+	r.Timestamp = firstTime(a.Spec.CompletedTimestamp, a.Spec.StartedTimestamp, &a.CreationTimestamp)
+	return r
+}
+
+// FromLighthouseJob creates the resource for a LighthouseJob
+func FromLighthouseJob(j *lhv1alpha1.LighthouseJob) *Resource {
+	r := newResource(v1alpha1.GCKindLighthouseJob, &j.ObjectMeta)
+	if refs := j.Spec.Refs; refs != nil {
+		if r.Owner == "" {
+			r.Owner = refs.Org
+		}
+		if r.Repository == "" {
+			r.Repository = refs.Repo
+		}
+		if r.Branch == "" {
+			r.Branch = refs.BaseRef
+		}
+	}
+	if r.Context == "" {
+		r.Context = j.Spec.Context
+	}
+	r.Completed = j.Status.CompletionTime != nil
+	r.Timestamp = firstTime(j.Status.CompletionTime, &j.Status.StartTime, &j.CreationTimestamp)
+	return r
+}
+
+// FromPipelineRun creates the resource for a tekton PipelineRun or TaskRun of the given kind
+func FromPipelineRun(kind string, u *unstructured.Unstructured) *Resource {
+	r := &Resource{
+		Kind:      kind,
+		Namespace: u.GetNamespace(),
+		Name:      u.GetName(),
+		Labels:    u.GetLabels(),
+	}
+	populateFromLabels(r)
+	completionTime := unstructuredTime(u, "status", "completionTime")
+	r.Completed = completionTime != nil
+	created := u.GetCreationTimestamp()
+	r.Timestamp = firstTime(completionTime, unstructuredTime(u, "status", "startTime"), &created)
+	return r
+}
+
+// FromJob creates the resource for a batch Job
+func FromJob(j *batchv1.Job) *Resource {
+	r := newResource(v1alpha1.GCKindJob, &j.ObjectMeta)
+	r.Completed = j.Status.Active == 0 && (j.Status.CompletionTime != nil || j.Status.Failed > 0)
+	r.Timestamp = firstTime(j.Status.CompletionTime, j.Status.StartTime, &j.CreationTimestamp)
+	return r
+}
+
+// FromPod creates the resource for a Pod using the time its last container terminated
+func FromPod(pod *corev1.Pod) *Resource {
+	r := newResource(v1alpha1.GCKindPod, &pod.ObjectMeta)
+	phase := pod.Status.Phase
+	r.Completed = phase == corev1.PodSucceeded || phase == corev1.PodFailed
+
+	var finished *metav1.Time
+	for k := range pod.Status.ContainerStatuses {
+		terminated := pod.Status.ContainerStatuses[k].State.Terminated
+		if terminated != nil && (finished == nil || terminated.FinishedAt.After(finished.Time)) {
+			finished = &terminated.FinishedAt
+		}
+	}
+	r.Timestamp = firstTime(finished, pod.Status.StartTime, &pod.CreationTimestamp)
+	return r
+}
+
+func newResource(kind string, m *metav1.ObjectMeta) *Resource {
+	r := &Resource{
+		Kind:      kind,
+		Namespace: m.Namespace,
+		Name:      m.Name,
+		Labels:    m.Labels,
+	}
+	populateFromLabels(r)
+	return r
+}
+
+func populateFromLabels(r *Resource) {
+	if r.Labels == nil {
+		return
+	}
+	r.Owner = r.Labels[OrgLabel]
+	r.Repository = r.Labels[RepoLabel]
+	r.Branch = r.Labels[BranchLabel]
+	r.Context = r.Labels[ContextLabel]
+}
+
+func firstString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstTime(times ...*metav1.Time) time.Time {
+	for _, t := range times {
+		if t != nil && !t.IsZero() {
+			return t.Time
+		}
+	}
+	return time.Time{}
+}
+
+func unstructuredTime(u *unstructured.Unstructured, fields ...string) *metav1.Time {
+	text, _, _ := unstructured.NestedString(u.Object, fields...)
+	if text == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: t}
+}
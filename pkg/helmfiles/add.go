@@ -16,6 +16,7 @@ type ChartDetails struct {
 	Version     string
 	ReleaseName string
 	Values      []string
+	Labels      map[string]string
 	UpdateOnly  bool
 	Prefixes    *versionstream.RepositoryPrefixes
 }
@@ -30,6 +31,12 @@ func NewChartDetails(helmState *state.HelmState, rel *state.ReleaseSpec, prefixe
 		Values:      nil,
 		Prefixes:    prefixes,
 	}
+	for k, v := range rel.Labels {
+		if a.Labels == nil {
+			a.Labels = map[string]string{}
+		}
+		a.Labels[k] = v
+	}
 	if a.Namespace == "" {
 		a.Namespace = helmState.OverrideNamespace
 	}
@@ -97,6 +104,16 @@ func (o *ChartDetails) Add(helmStates []*state.HelmState) (bool, error) {
 					modified = true
 				}
 
+				for k, v := range o.Labels {
+					if release.Labels[k] != v {
+						if release.Labels == nil {
+							release.Labels = map[string]string{}
+						}
+						release.Labels[k] = v
+						modified = true
+					}
+				}
+
 				// lets add any missing values
 				for _, v := range o.Values {
 					foundValue := false
@@ -130,6 +147,12 @@ func (o *ChartDetails) Add(helmStates []*state.HelmState) (bool, error) {
 		for _, v := range o.Values {
 			release.Values = append(release.Values, v)
 		}
+		for k, v := range o.Labels {
+			if release.Labels == nil {
+				release.Labels = map[string]string{}
+			}
+			release.Labels[k] = v
+		}
 		lastHelmState.Releases = append(lastHelmState.Releases, release)
 		modified = true
 	}
@@ -3,6 +3,7 @@ package helmfiles
 import (
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/helmfile/helmfile/pkg/state"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
@@ -62,13 +63,13 @@ func (e *Editor) AddChart(opts *ChartDetails) error {
 		return errors.Errorf("no namespace")
 	}
 
-	rel := filepath.Join("helmfiles", ns, "helmfile.yaml")
-	path := e.namespaceToPath[ns]
-	if path == "" {
-		// lets create a new path
-		path = filepath.Join(e.dir, rel)
-		e.namespaceToPath[ns] = path
+	path := e.HelmfilePath(ns)
+	rel, err := filepath.Rel(e.dir, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find path of %s relative to %s", path, e.dir)
 	}
+	rel = filepath.ToSlash(rel)
+	e.namespaceToPath[ns] = path
 	hf := e.getOrCreateState(path)
 	lastHelmState := hf[len(hf)-1]
 	lastHelmState.OverrideNamespace = ns
@@ -105,6 +106,36 @@ func (e *Editor) AddChart(opts *ChartDetails) error {
 	return nil
 }
 
+// HelmfilePath returns the path of the helmfile for the given namespace which may not exist yet
+func (e *Editor) HelmfilePath(ns string) string {
+	path := e.namespaceToPath[ns]
+	if path == "" {
+		path = NamespaceHelmfile(e.dir, ns)
+	}
+	return path
+}
+
+// FindRelease returns the release with the given name in the helmfile for the given namespace or nil if it does not exist
+func (e *Editor) FindRelease(ns, name string) (*state.HelmState, *state.ReleaseSpec) {
+	path := e.namespaceToPath[ns]
+	if path == "" {
+		return nil, nil
+	}
+	for _, helmState := range e.pathToState[path] {
+		for i := range helmState.Releases {
+			release := &helmState.Releases[i]
+			releaseName := release.Name
+			if releaseName == "" {
+				_, releaseName = SpitChartName(release.Chart)
+			}
+			if releaseName == name && (release.Namespace == "" || release.Namespace == ns) {
+				return helmState, release
+			}
+		}
+	}
+	return nil, nil
+}
+
 // DeleteChart adds a chart to the right helmfile for the given namespace
 func (e *Editor) DeleteChart(opts *ChartDetails) error {
 	for ns, path := range e.namespaceToPath {
@@ -130,8 +161,10 @@ func (e *Editor) DeleteChart(opts *ChartDetails) error {
 	return nil
 }
 
-// Save saves any modified files
+// Save saves any modified files. Templated helmfiles which cannot be rewritten without losing the template are
+// reported in the returned error after the other files have been saved
 func (e *Editor) Save() error {
+	var readOnly []string
 	for path, f := range e.modified {
 		if !f {
 			continue
@@ -142,11 +175,20 @@ func (e *Editor) Save() error {
 		}
 
 		err := SaveHelmfile(path, state)
+		if IsReadOnly(err) {
+			log.Logger().Warnf("not saving changes: %s", err.Error())
+			readOnly = append(readOnly, path)
+			continue
+		}
 		if err != nil {
 			return errors.Wrapf(err, "failed to save file %s", path)
 		}
 
 		log.Logger().Infof("saved %s", info(path))
 	}
+	if len(readOnly) > 0 {
+		sort.Strings(readOnly)
+		return errors.Errorf("the following templated helmfiles could only be read and not safely rewritten so need to be updated by hand: %s", strings.Join(readOnly, ", "))
+	}
 	return nil
 }
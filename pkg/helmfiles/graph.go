@@ -0,0 +1,348 @@
+package helmfiles
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EdgeNeeds the release declares it needs the other release
+	EdgeNeeds = "needs"
+
+	// EdgeCRD the release consumes a custom resource defined by the other release without needing it
+	EdgeCRD = "crd"
+)
+
+// ReleaseNode a release in the dependency graph
+type ReleaseNode struct {
+	// Key the namespace/name of the release
+	Key       string
+	Namespace string
+	Name      string
+	Chart     string
+
+	// Helmfile the path of the helmfile which defines the release
+	Helmfile string
+
+	// Missing is true if the release is needed by another release but is not defined in any helmfile
+	Missing bool
+}
+
+// Edge an edge from a release to a release it depends on
+type Edge struct {
+	From string
+	To   string
+	Type string
+}
+
+// DanglingNeed a need of a release which does not refer to a known release
+type DanglingNeed struct {
+	Release string
+	Need    string
+}
+
+// ReleaseGraph the dependency graph of the releases across all the helmfiles
+type ReleaseGraph struct {
+	Nodes    map[string]*ReleaseNode
+	Edges    []Edge
+	Dangling []DanglingNeed
+
+	keys  []string
+	needs map[string][]string
+}
+
+// NewReleaseGraph creates the dependency graph of the releases in the given helmfiles from their needs. Releases without
+// a namespace default to the folder of their nested helmfile such as 'helmfiles/jx/helmfile.yaml' or the given namespace
+func NewReleaseGraph(helmfiles []Helmfile, namespace string) (*ReleaseGraph, error) {
+	g := &ReleaseGraph{}
+	for _, hf := range helmfiles {
+		helmStates, err := LoadHelmfile(hf.Filepath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load helmfile %s", hf.Filepath)
+		}
+		defaultNamespace := namespace
+		dir := filepath.Dir(hf.Filepath)
+		if filepath.Base(filepath.Dir(dir)) == "helmfiles" {
+			defaultNamespace = filepath.Base(dir)
+		}
+		for _, helmState := range helmStates {
+			for i := range helmState.Releases {
+				release := &helmState.Releases[i]
+				ns := release.Namespace
+				if ns == "" {
+					ns = helmState.OverrideNamespace
+				}
+				if ns == "" {
+					ns = defaultNamespace
+				}
+				name := release.Name
+				if name == "" {
+					_, name = chartrepos.SplitChartName(release.Chart)
+				}
+				var needs []string
+				for _, need := range release.Needs {
+					needs = append(needs, NeedKey(ns, need))
+				}
+				g.AddRelease(&ReleaseNode{
+					Key:       ns + "/" + name,
+					Namespace: ns,
+					Name:      name,
+					Chart:     release.Chart,
+					Helmfile:  hf.Filepath,
+				}, needs)
+			}
+		}
+	}
+	g.LinkNeeds()
+	return g, nil
+}
+
+// AddRelease adds a release with the keys of the releases it needs returning false if the release is already in the graph.
+// LinkNeeds must be called after all the releases have been added to create the edges of the needs
+func (g *ReleaseGraph) AddRelease(node *ReleaseNode, needs []string) bool {
+	if g.Nodes == nil {
+		g.Nodes = map[string]*ReleaseNode{}
+	}
+	if g.Nodes[node.Key] != nil {
+		return false
+	}
+	if g.needs == nil {
+		g.needs = map[string][]string{}
+	}
+	g.Nodes[node.Key] = node
+	g.keys = append(g.keys, node.Key)
+	g.needs[node.Key] = append(g.needs[node.Key], needs...)
+	return true
+}
+
+// LinkNeeds creates the edges for the needs of the added releases. Any needs which do not refer to a known release are
+// added to the dangling needs along with a missing node
+func (g *ReleaseGraph) LinkNeeds() {
+	for _, key := range g.keys {
+		for _, need := range g.needs[key] {
+			if g.Nodes[need] == nil {
+				g.Dangling = append(g.Dangling, DanglingNeed{Release: key, Need: need})
+				continue
+			}
+			g.AddEdge(key, need, EdgeNeeds)
+		}
+	}
+	for _, d := range g.Dangling {
+		if g.Nodes[d.Need] == nil {
+			ns, name, _ := strings.Cut(d.Need, "/")
+			g.Nodes[d.Need] = &ReleaseNode{Key: d.Need, Namespace: ns, Name: name, Missing: true}
+		}
+		g.AddEdge(d.Release, d.Need, EdgeNeeds)
+	}
+	g.keys = nil
+	g.needs = nil
+}
+
+// NeedKey returns the namespace/name key of a need which may be 'name', 'namespace/name' or 'kubecontext/namespace/name'
+func NeedKey(ns, need string) string {
+	parts := strings.Split(need, "/")
+	switch len(parts) {
+	case 1:
+		return ns + "/" + need
+	case 2:
+		return need
+	default:
+		return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+	}
+}
+
+// AddEdge adds an edge to the graph if it does not already exist
+func (g *ReleaseGraph) AddEdge(from, to, edgeType string) {
+	for _, e := range g.Edges {
+		if e.From == from && e.To == to && e.Type == edgeType {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, Edge{From: from, To: to, Type: edgeType})
+}
+
+// Keys returns the sorted keys of the releases
+func (g *ReleaseGraph) Keys() []string {
+	var keys []string
+	for k := range g.Nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Needs returns the keys of the releases the release directly needs
+func (g *ReleaseGraph) Needs(key string) []string {
+	var answer []string
+	for _, e := range g.Edges {
+		if e.From == key && e.Type == EdgeNeeds {
+			answer = append(answer, e.To)
+		}
+	}
+	return answer
+}
+
+// DependsOn returns true if the release needs the other release directly or transitively
+func (g *ReleaseGraph) DependsOn(from, to string) bool {
+	visited := map[string]bool{}
+	var visit func(key string) bool
+	visit = func(key string) bool {
+		if visited[key] {
+			return false
+		}
+		visited[key] = true
+		for _, need := range g.Needs(key) {
+			if need == to || visit(need) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(from)
+}
+
+// Cycles returns the cycles of needs in the graph with the first release repeated at the end of each cycle
+func (g *ReleaseGraph) Cycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	var cycles [][]string
+	states := map[string]int{}
+	var stack []string
+	var visit func(key string)
+	visit = func(key string) {
+		states[key] = visiting
+		stack = append(stack, key)
+		for _, next := range g.Needs(key) {
+			switch states[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				i := len(stack) - 1
+				for i > 0 && stack[i] != next {
+					i--
+				}
+				cycles = append(cycles, append(append([]string{}, stack[i:]...), next))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[key] = visited
+	}
+	for _, key := range g.Keys() {
+		if states[key] == unvisited {
+			visit(key)
+		}
+	}
+	return cycles
+}
+
+// sortedEdges returns the edges sorted by release so the output is stable
+func (g *ReleaseGraph) sortedEdges() []Edge {
+	edges := append([]Edge{}, g.Edges...)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		if edges[i].To != edges[j].To {
+			return edges[i].To < edges[j].To
+		}
+		return edges[i].Type < edges[j].Type
+	})
+	return edges
+}
+
+// WriteDOT writes the graph in the graphviz DOT format grouping the releases by namespace
+func (g *ReleaseGraph) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph releases {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	namespace := ""
+	for _, key := range g.Keys() {
+		n := g.Nodes[key]
+		if n.Namespace != namespace {
+			if namespace != "" {
+				sb.WriteString("  }\n")
+			}
+			namespace = n.Namespace
+			fmt.Fprintf(&sb, "  subgraph %q {\n", "cluster_"+namespace)
+			fmt.Fprintf(&sb, "    label=%q;\n", namespace)
+		}
+		attrs := fmt.Sprintf("label=%q", n.Name)
+		if n.Missing {
+			attrs += ", style=dashed, color=red"
+		}
+		fmt.Fprintf(&sb, "    %q [%s];\n", key, attrs)
+	}
+	if namespace != "" {
+		sb.WriteString("  }\n")
+	}
+	for _, e := range g.sortedEdges() {
+		attrs := ""
+		if e.Type == EdgeCRD {
+			attrs = " [label=\"crd\", style=dashed, color=red]"
+		}
+		fmt.Fprintf(&sb, "  %q -> %q%s;\n", e.From, e.To, attrs)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteMermaid writes the graph as a mermaid flowchart grouping the releases by namespace
+func (g *ReleaseGraph) WriteMermaid(w io.Writer) error {
+	keys := g.Keys()
+	ids := map[string]string{}
+	for i, key := range keys {
+		ids[key] = fmt.Sprintf("r%d", i)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	namespace := ""
+	for _, key := range keys {
+		n := g.Nodes[key]
+		if n.Namespace != namespace {
+			if namespace != "" {
+				sb.WriteString("  end\n")
+			}
+			namespace = n.Namespace
+			fmt.Fprintf(&sb, "  subgraph %s[\"%s\"]\n", "ns_"+mermaidID(namespace), namespace)
+		}
+		fmt.Fprintf(&sb, "    %s[\"%s\"]\n", ids[key], n.Name)
+	}
+	if namespace != "" {
+		sb.WriteString("  end\n")
+	}
+	for _, e := range g.sortedEdges() {
+		arrow := "-->"
+		if e.Type == EdgeCRD {
+			arrow = "-. crd .->"
+		}
+		fmt.Fprintf(&sb, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+	for _, key := range keys {
+		if g.Nodes[key].Missing {
+			fmt.Fprintf(&sb, "  style %s stroke:#f00,stroke-dasharray:5 5\n", ids[key])
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// mermaidID converts the text into a valid mermaid identifier
+func mermaidID(text string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, text)
+}
@@ -1,15 +1,15 @@
 package helmfiles
 
 import (
+	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
 
-	"github.com/goccy/go-yaml"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/chartrepos"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/versionstreamer"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
 
@@ -20,6 +20,10 @@ import (
 type Helmfile struct {
 	Filepath           string
 	RelativePathToRoot string
+
+	// Templated if the helmfile is a go template which is rendered before it is parsed so it may not be possible to
+	// save changes to it
+	Templated bool
 }
 
 var pathSeparator = string(os.PathSeparator)
@@ -40,26 +44,21 @@ func GatherHelmfiles(helmfile, dir string) ([]Helmfile, error) {
 
 	helmfile = filepath.Join(dir, helmfile)
 
-	file, err := os.Open(helmfile)
+	data, err := os.ReadFile(helmfile)
 	if err != nil {
 		return nil, err
 	}
 	relativePath := strings.Repeat("../", parentHelmfileDepth)
 
 	helmfiles := []Helmfile{
-		{helmfile, relativePath},
+		{helmfile, relativePath, IsTemplated(helmfile, data)},
 	}
 
-	dec := yaml.NewDecoder(file)
-	for {
-		helmState := state.HelmState{}
-		if err := dec.Decode(&helmState); err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			return nil, fmt.Errorf("failed to load helmfile %s: %w", helmfile, err)
-		}
-
+	helmStates, err := parseHelmfile(helmfile, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load helmfile %s: %w", helmfile, err)
+	}
+	for _, helmState := range helmStates {
 		for _, nested := range helmState.Helmfiles {
 			// lets ignore remote helmfiles
 			if strings.HasPrefix(nested.Path, "git::") {
@@ -81,6 +80,17 @@ func GatherHelmfiles(helmfile, dir string) ([]Helmfile, error) {
 	return helmfiles, nil
 }
 
+// NamespaceHelmfile returns the path of the helmfile for the given namespace in the directory. An existing
+// templated helmfile is used in preference to the default helmfile.yaml
+func NamespaceHelmfile(dir, ns string) string {
+	path := filepath.Join(dir, "helmfiles", ns, "helmfile.yaml")
+	templatePath := path + TemplateSuffix
+	if exists, _ := files.FileExists(templatePath); exists {
+		return templatePath
+	}
+	return path
+}
+
 // AddRepository ensures that the helm repository for the prefix exists in the helmstate.
 // For it to succeed either repositoryUrl needs to be set or the prefix exists in prefixes.
 func AddRepository(helmStates []*state.HelmState, prefix, repositoryURL string, prefixes *versionstream.RepositoryPrefixes) (string, error) {
@@ -109,11 +119,9 @@ func AddRepository(helmStates []*state.HelmState, prefix, repositoryURL string,
 		return "", errors.Wrapf(err, "failed to find repository URL, not defined in helmfile.yaml or versionstream")
 	}
 	if repositoryURL != "" && prefix != "" {
-		ociPrefix := strings.HasPrefix(repositoryURL, "oci://")
-		if ociPrefix {
-			repositoryURL = repositoryURL[len("oci://"):]
-			oci = true
-		}
+		repo := chartrepos.NewRepository(prefix, repositoryURL, oci)
+		repositoryURL = repo.URL
+		oci = repo.OCI
 		// lets ensure we've got a repository for this URL in the apps file
 		found := false
 		for _, helmState := range helmStates {
@@ -142,45 +150,67 @@ func AddRepository(helmStates []*state.HelmState, prefix, repositoryURL string,
 	return repositoryURL, nil
 }
 
-// LoadHelmfile loads helmfile from a path
+// LoadHelmfile loads helmfile from a path. Templated helmfiles are rendered using the values of the default environment first
 func LoadHelmfile(path string) ([]*state.HelmState, error) {
-	var helmStates []*state.HelmState
-
-	file, err := os.Open(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	helmStates, err := parseHelmfile(path, data)
 	if err != nil {
 		return nil, err
 	}
+	// Make sure at least an empty helmstate is returned
+	if len(helmStates) == 0 {
+		helmStates = append(helmStates, &state.HelmState{})
+	}
+	return helmStates, nil
+}
 
-	dec := yaml.NewDecoder(file)
-	for {
-		helmState := state.HelmState{}
-		if err := dec.Decode(&helmState); err != nil {
-			if errors.Is(err, io.EOF) {
-				// Make sure at least an empty helmstate is returned
-				if helmStates != nil {
-					break
-				}
-			} else {
-				return nil, err
-			}
-		}
-		helmStates = append(helmStates, &helmState)
+func parseHelmfile(path string, data []byte) ([]*state.HelmState, error) {
+	if !IsTemplated(path, data) {
+		return decodeHelmStates(bytes.NewReader(data))
 	}
-	err = file.Close()
+	parts, err := renderHelmfile(path, data)
 	if err != nil {
 		return nil, err
 	}
-
+	var helmStates []*state.HelmState
+	for _, part := range parts {
+		helmStates = append(helmStates, part.states...)
+	}
 	return helmStates, nil
 }
 
-// SaveHelmfile saves helmfile to a path, overwriting if file exists
+// SaveHelmfile saves helmfile to a path, overwriting if file exists.
+// If the file is a templated helmfile then the template is preserved or a ReadOnlyError is returned if that is not possible
 func SaveHelmfile(path string, helmStates []*state.HelmState) error {
 	helmDir := filepath.Dir(path)
 	err := os.MkdirAll(helmDir, files.DefaultDirWritePermissions)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create directory %s", helmDir)
 	}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if exists {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read file %s", path)
+		}
+		if IsTemplated(path, data) {
+			data, err = renderPreservingTemplate(path, data, helmStates)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(path, data, files.DefaultFileWritePermissions)
+			if err != nil {
+				return errors.Wrapf(err, "failed to save file %s", path)
+			}
+			return nil
+		}
+	}
 	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
@@ -203,19 +233,11 @@ func SaveNewHelmfile(path string, helmStates []*state.HelmState) error {
 }
 
 func saveToFile(path string, helmStates []*state.HelmState, file *os.File) error {
-	env := yaml.NewEncoder(
-		file,
-		yaml.OmitEmpty(),
-		yaml.UseLiteralStyleIfMultiline(true),
-		yaml.UseSingleQuote(true),
-	)
-	for i := range helmStates {
-		err := env.Encode(*helmStates[i])
-		if err != nil {
-			return fmt.Errorf("failed to save file %s: %w", path, err)
-		}
+	err := encodeHelmStates(file, helmStates)
+	if err != nil {
+		return fmt.Errorf("failed to save file %s: %w", path, err)
 	}
-	err := file.Sync()
+	err = file.Sync()
 	if err != nil {
 		return err
 	}
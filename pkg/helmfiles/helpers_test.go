@@ -3,8 +3,11 @@ package helmfiles_test
 import (
 	"testing"
 
+	"github.com/helmfile/helmfile/pkg/state"
 	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGatherHelmfiles(t *testing.T) {
@@ -35,3 +38,20 @@ func TestGatherHelmfiles(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
 }
+
+func TestAddRepositoryOCI(t *testing.T) {
+	prefixes := &versionstream.RepositoryPrefixes{
+		Repositories: []versionstream.RepositoryURLs{
+			{
+				Prefix: "myoci",
+				URLs:   []string{"oci://ghcr.io/myorg/charts"},
+			},
+		},
+	}
+	helmState := &state.HelmState{}
+	u, err := helmfiles.AddRepository([]*state.HelmState{helmState}, "myoci", "", prefixes)
+	require.NoError(t, err, "failed to add repository")
+	assert.Equal(t, "ghcr.io/myorg/charts", u)
+	require.Len(t, helmState.Repositories, 1)
+	assert.Equal(t, state.RepositorySpec{Name: "myoci", URL: "ghcr.io/myorg/charts", OCI: true}, helmState.Repositories[0])
+}
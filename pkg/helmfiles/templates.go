@@ -0,0 +1,255 @@
+package helmfiles
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/helmfile/helmfile/pkg/environment"
+	"github.com/helmfile/helmfile/pkg/filesystem"
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/helmfile/helmfile/pkg/tmpl"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TemplateSuffix the file name suffix of go templated helmfiles
+	TemplateSuffix = ".gotmpl"
+
+	// DefaultEnvironment the helmfile environment used to render templated helmfiles
+	DefaultEnvironment = "default"
+)
+
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*\r?\n`)
+
+// ReadOnlyError is returned when saving changes to a templated helmfile which cannot be written without
+// losing the template
+type ReadOnlyError struct {
+	Path   string
+	Reason string
+}
+
+// Error returns the error message
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("templated helmfile %s can only be read and not safely rewritten: %s", e.Path, e.Reason)
+}
+
+// IsReadOnly returns true if the error is due to a templated helmfile which cannot be safely rewritten
+func IsReadOnly(err error) bool {
+	var readOnly *ReadOnlyError
+	return errors.As(err, &readOnly)
+}
+
+// IsTemplated returns true if the helmfile is a go template which needs to be rendered before it can be parsed
+func IsTemplated(path string, data []byte) bool {
+	return strings.HasSuffix(path, TemplateSuffix) || bytes.Contains(data, []byte("{{"))
+}
+
+// templatePart a part of a helmfile separated by '---' along with the helm states it renders
+type templatePart struct {
+	text      string
+	templated bool
+	states    []*state.HelmState
+}
+
+// renderHelmfile splits the helmfile into parts and renders any templated parts in order using the values of the
+// default environment from the previous parts like helmfile does
+func renderHelmfile(path string, data []byte) ([]*templatePart, error) {
+	dir := filepath.Dir(path)
+	values := map[string]interface{}{}
+	var parts []*templatePart
+	for _, text := range documentSeparator.Split(string(data), -1) {
+		part := &templatePart{
+			text:      text,
+			templated: strings.Contains(text, "{{"),
+		}
+		rendered := text
+		if part.templated {
+			var err error
+			rendered, err = renderTemplate(dir, text, values)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to render helmfile template %s", path)
+			}
+		}
+		states, err := decodeHelmStates(strings.NewReader(rendered))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse helmfile %s", path)
+		}
+		part.states = states
+		parts = append(parts, part)
+
+		for _, helmState := range states {
+			env, ok := helmState.Environments[DefaultEnvironment]
+			if !ok {
+				continue
+			}
+			err = loadEnvironmentValues(dir, env.Values, values)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to load environment values of helmfile %s", path)
+			}
+		}
+	}
+	return parts, nil
+}
+
+func renderTemplate(dir, text string, values map[string]interface{}) (string, error) {
+	data := state.NewEnvironmentTemplateData(environment.Environment{Name: DefaultEnvironment, Values: values}, "", values)
+	renderer := tmpl.NewFileRenderer(filesystem.DefaultFileSystem(), dir, data)
+	buf, err := renderer.RenderTemplateContentToBuffer([]byte(text))
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// loadEnvironmentValues merges the environment values files or inline values into the values.
+// Missing values files are ignored as files like jx-values.yaml are generated by 'helmfile resolve'
+func loadEnvironmentValues(dir string, envValues []interface{}, values map[string]interface{}) error {
+	for _, v := range envValues {
+		switch value := v.(type) {
+		case string:
+			path := filepath.Join(dir, value)
+			exists, err := files.FileExists(path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to check if file exists %s", path)
+			}
+			if !exists {
+				log.Logger().Debugf("ignoring missing environment values file %s", path)
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read file %s", path)
+			}
+			if strings.HasSuffix(path, TemplateSuffix) {
+				text, err := renderTemplate(dir, string(data), values)
+				if err != nil {
+					return errors.Wrapf(err, "failed to render values file %s", path)
+				}
+				data = []byte(text)
+			}
+			m := map[string]interface{}{}
+			err = yaml.Unmarshal(data, &m)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse values file %s", path)
+			}
+			mergeValues(values, m)
+		case map[string]interface{}:
+			mergeValues(values, value)
+		}
+	}
+	return nil
+}
+
+// mergeValues deeply merges the source values into the destination
+func mergeValues(dest, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, ok := v.(map[string]interface{})
+		if ok {
+			destMap, ok := dest[k].(map[string]interface{})
+			if ok {
+				mergeValues(destMap, srcMap)
+				continue
+			}
+		}
+		dest[k] = v
+	}
+}
+
+// renderPreservingTemplate returns the content of the templated helmfile with the given helm states. Parts which are
+// not templated are written from the helm states whereas templated parts are kept as they are which is only possible
+// if the helm states they render have not been modified
+func renderPreservingTemplate(path string, data []byte, helmStates []*state.HelmState) ([]byte, error) {
+	parts, err := renderHelmfile(path, data)
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	for _, part := range parts {
+		count += len(part.states)
+	}
+	if count != len(helmStates) {
+		return nil, &ReadOnlyError{
+			Path:   path,
+			Reason: fmt.Sprintf("the template renders %d documents but there are now %d", count, len(helmStates)),
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	idx := 0
+	for i, part := range parts {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		modified := helmStates[idx : idx+len(part.states)]
+		idx += len(part.states)
+		if len(part.states) == 0 {
+			buf.WriteString(part.text)
+			continue
+		}
+		if !part.templated {
+			err = encodeHelmStates(buf, modified)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to encode helmfile %s", path)
+			}
+			continue
+		}
+		original := &bytes.Buffer{}
+		err = encodeHelmStates(original, part.states)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode helmfile %s", path)
+		}
+		changed := &bytes.Buffer{}
+		err = encodeHelmStates(changed, modified)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to encode helmfile %s", path)
+		}
+		if !bytes.Equal(original.Bytes(), changed.Bytes()) {
+			return nil, &ReadOnlyError{
+				Path:   path,
+				Reason: fmt.Sprintf("templated part %d has been modified", i+1),
+			}
+		}
+		buf.WriteString(part.text)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeHelmStates(r io.Reader) ([]*state.HelmState, error) {
+	var helmStates []*state.HelmState
+	dec := yaml.NewDecoder(r)
+	for {
+		helmState := state.HelmState{}
+		err := dec.Decode(&helmState)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return helmStates, nil
+			}
+			return nil, err
+		}
+		helmStates = append(helmStates, &helmState)
+	}
+}
+
+func encodeHelmStates(w io.Writer, helmStates []*state.HelmState) error {
+	enc := yaml.NewEncoder(
+		w,
+		yaml.OmitEmpty(),
+		yaml.UseLiteralStyleIfMultiline(true),
+		yaml.UseSingleQuote(true),
+	)
+	for i := range helmStates {
+		err := enc.Encode(*helmStates[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package helmfiles_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherTemplatedHelmfiles(t *testing.T) {
+	expected := []helmfiles.Helmfile{
+		{
+			Filepath:           "templates_test_data/helmfile.yaml",
+			RelativePathToRoot: "",
+		},
+		{
+			Filepath:           "templates_test_data/helmfiles/jx-staging/helmfile.yaml.gotmpl",
+			RelativePathToRoot: "../../",
+			Templated:          true,
+		},
+	}
+
+	actual, err := helmfiles.GatherHelmfiles("helmfile.yaml", "templates_test_data")
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestLoadAndSaveTemplatedHelmfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite("templates_test_data", tmpDir)
+	require.NoError(t, err, "failed to copy testdata")
+
+	path := filepath.Join(tmpDir, "helmfiles", "jx-staging", "helmfile.yaml.gotmpl")
+	original, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read %s", path)
+
+	helmStates, err := helmfiles.LoadHelmfile(path)
+	require.NoError(t, err, "failed to load %s", path)
+	require.Len(t, helmStates, 2)
+	assert.Equal(t, "jx-staging", helmStates[1].OverrideNamespace)
+	require.Len(t, helmStates[1].Releases, 2)
+	assert.Equal(t, "1.2.3", helmStates[1].Releases[0].Version)
+	assert.Equal(t, "cheese", helmStates[1].Releases[1].Name)
+
+	// saving without changes preserves the template
+	err = helmfiles.SaveHelmfile(path, helmStates)
+	require.NoError(t, err, "failed to save %s", path)
+	assertFileContent(t, path, string(original))
+
+	// modifying the templated part cannot be saved safely
+	helmStates[1].Releases[0].Version = "1.2.4"
+	err = helmfiles.SaveHelmfile(path, helmStates)
+	require.Error(t, err, "should not be able to save a modified templated part")
+	assert.True(t, helmfiles.IsReadOnly(err), "should be a read only error but was %s", err.Error())
+	assertFileContent(t, path, string(original))
+
+	// modifying a part which is not templated preserves the templated part
+	helmStates[1].Releases[0].Version = "1.2.3"
+	env := helmStates[0].Environments["default"]
+	env.Values = append(env.Values, "extra-values.yaml")
+	helmStates[0].Environments["default"] = env
+	err = helmfiles.SaveHelmfile(path, helmStates)
+	require.NoError(t, err, "failed to save %s", path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read %s", path)
+	assert.Contains(t, string(data), "- extra-values.yaml")
+	assert.Contains(t, string(data), "version: {{ .Values.myapp.version }}")
+
+	helmStates, err = helmfiles.LoadHelmfile(path)
+	require.NoError(t, err, "failed to reload %s", path)
+	assert.Equal(t, []interface{}{"jx-values.yaml", "extra-values.yaml"}, helmStates[0].Environments["default"].Values)
+	assert.Equal(t, "1.2.3", helmStates[1].Releases[0].Version)
+}
+
+func assertFileContent(t *testing.T, path, expected string) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read %s", path)
+	assert.Equal(t, expected, string(data), "content of %s", path)
+}
+
+func TestEditorAddChartToTemplatedHelmfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := files.CopyDirOverwrite("templates_test_data", tmpDir)
+	require.NoError(t, err, "failed to copy testdata")
+
+	assert.Equal(t, filepath.Join(tmpDir, "helmfiles", "jx-staging", "helmfile.yaml.gotmpl"), helmfiles.NamespaceHelmfile(tmpDir, "jx-staging"))
+	assert.Equal(t, filepath.Join(tmpDir, "helmfiles", "jx-production", "helmfile.yaml"), helmfiles.NamespaceHelmfile(tmpDir, "jx-production"))
+
+	hfs, err := helmfiles.GatherHelmfiles("helmfile.yaml", tmpDir)
+	require.NoError(t, err, "failed to gather helmfiles")
+	editor, err := helmfiles.NewEditor(tmpDir, hfs)
+	require.NoError(t, err, "failed to create editor")
+
+	for _, ns := range []string{"jx-staging", "jx-production"} {
+		err = editor.AddChart(&helmfiles.ChartDetails{
+			Namespace: ns,
+			Chart:     "wine",
+		})
+		require.NoError(t, err, "failed to add chart to namespace %s", ns)
+	}
+
+	// the templated helmfile cannot be saved but the other files are
+	err = editor.Save()
+	require.Error(t, err, "should not be able to save the templated helmfile")
+
+	root, err := helmfiles.LoadHelmfile(filepath.Join(tmpDir, "helmfile.yaml"))
+	require.NoError(t, err, "failed to load root helmfile")
+	var paths []string
+	for _, hf := range root[0].Helmfiles {
+		paths = append(paths, hf.Path)
+	}
+	assert.Equal(t, []string{"helmfiles/jx-production/helmfile.yaml", "helmfiles/jx-staging/helmfile.yaml.gotmpl"}, paths)
+}
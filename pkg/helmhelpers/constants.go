@@ -15,4 +15,13 @@ const (
 
 	// NoRequirementsLabelValue the value of the ValuesLabel to not add jx-values.yaml as to values
 	NoRequirementsLabelValue = "no-jx-values"
+
+	// UpdateScopeLabel the label on helmfile releases to limit which version stream updates are adopted: patch, minor or major
+	UpdateScopeLabel = "update.jenkins-x.io/scope"
+
+	// UpdateConstraintLabel the label on helmfile releases with a semver constraint such as '>=1.4 <2' which updated versions must satisfy
+	UpdateConstraintLabel = "update.jenkins-x.io/constraint"
+
+	// UpdateMinAgeLabel the label on helmfile releases with the minimum age such as '72h' or '7d' of a chart version before it is adopted
+	UpdateMinAgeLabel = "update.jenkins-x.io/min-age"
 )
@@ -0,0 +1,145 @@
+package imagepaths
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ConfigFile the location of the image paths file relative to the cluster git repository
+	ConfigFile = filepath.Join(".jx", "gitops", v1alpha1.ImagePathsFileName)
+
+	podSpecPaths = []string{
+		"initContainers.image",
+		"containers.image",
+		"ephemeralContainers.image",
+	}
+)
+
+// Table the image paths of each kind of resource
+type Table struct {
+	Kinds []v1alpha1.ImagePathsKind
+}
+
+// DefaultKinds returns the built in image paths for the core workload kinds, Knative Services,
+// Argo Rollouts and the Tekton pipeline kinds
+func DefaultKinds() []v1alpha1.ImagePathsKind {
+	return []v1alpha1.ImagePathsKind{
+		{Kind: "Pod", Paths: prefixPaths("spec")},
+		{Kind: "Deployment", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "StatefulSet", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "DaemonSet", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "ReplicaSet", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "ReplicationController", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "Job", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "CronJob", Paths: prefixPaths("spec.jobTemplate.spec.template.spec")},
+		{Kind: "Service", APIVersion: "serving.knative.dev/v1", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "Rollout", Paths: prefixPaths("spec.template.spec")},
+		{Kind: "Pipeline", Paths: []string{"spec.tasks.taskSpec.steps.image"}},
+		{Kind: "PipelineRun", Paths: []string{"spec.pipelineSpec.tasks.taskSpec.steps.image"}},
+		{Kind: "Task", Paths: []string{"spec.steps.image"}},
+		{Kind: "TaskRun", Paths: []string{"spec.taskSpec.steps.image"}},
+	}
+}
+
+// LoadImagePaths loads the image paths from the given file if it exists
+func LoadImagePaths(path string) (*v1alpha1.ImagePaths, error) {
+	answer := &v1alpha1.ImagePaths{}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return answer, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return answer, nil
+	}
+	err = yamls.LoadFile(path, answer)
+	if err != nil {
+		return answer, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return answer, nil
+}
+
+// LoadTable creates a table from the built in defaults overridden by the image paths file in the version stream
+// and then the image paths file in the cluster git repository
+func LoadTable(dir, versionStreamDir string) (*Table, error) {
+	var paths []string
+	if versionStreamDir != "" {
+		paths = append(paths, filepath.Join(versionStreamDir, v1alpha1.ImagePathsFileName))
+	}
+	paths = append(paths, filepath.Join(dir, ConfigFile))
+
+	t := NewTable()
+	for _, path := range paths {
+		config, err := LoadImagePaths(path)
+		if err != nil {
+			return nil, err
+		}
+		for i := range config.Spec.Kinds {
+			k := config.Spec.Kinds[i]
+			if k.Kind == "" {
+				return nil, errors.Errorf("missing kind for entry %d in file %s", i, path)
+			}
+			t.Add(k)
+		}
+	}
+	return t, nil
+}
+
+// NewTable creates a table with the built in defaults
+func NewTable() *Table {
+	return &Table{Kinds: DefaultKinds()}
+}
+
+// Add adds the image paths of a kind replacing any existing paths of the same kind and API version
+func (t *Table) Add(k v1alpha1.ImagePathsKind) {
+	for i := range t.Kinds {
+		if t.Kinds[i].Kind == k.Kind && t.Kinds[i].APIVersion == k.APIVersion {
+			t.Kinds[i] = k
+			return
+		}
+	}
+	t.Kinds = append(t.Kinds, k)
+}
+
+// Paths returns the image paths for the given API version and kind split into their field names.
+// An entry for the exact API version is used in preference to an entry for any API version
+func (t *Table) Paths(apiVersion, kind string) [][]string {
+	var found *v1alpha1.ImagePathsKind
+	for i := range t.Kinds {
+		k := &t.Kinds[i]
+		if k.Kind != kind {
+			continue
+		}
+		if k.APIVersion == apiVersion {
+			found = k
+			break
+		}
+		if k.APIVersion == "" {
+			found = k
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	var answer [][]string
+	for _, p := range found.Paths {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			answer = append(answer, strings.Split(p, "."))
+		}
+	}
+	return answer
+}
+
+func prefixPaths(prefix string) []string {
+	var answer []string
+	for _, p := range podSpecPaths {
+		answer = append(answer, prefix+"."+p)
+	}
+	return answer
+}
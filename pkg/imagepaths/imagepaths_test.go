@@ -0,0 +1,63 @@
+package imagepaths_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/imagepaths"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImagePathsTable(t *testing.T) {
+	tbl := imagepaths.NewTable()
+	tbl.Add(v1alpha1.ImagePathsKind{Kind: "Deployment", Paths: []string{"spec.template.spec.containers.image"}})
+	tbl.Add(v1alpha1.ImagePathsKind{Kind: "Workflow", APIVersion: "argoproj.io/v1alpha1", Paths: []string{"spec.templates.container.image"}})
+
+	testCases := []struct {
+		apiVersion string
+		kind       string
+		expected   [][]string
+	}{
+		{
+			apiVersion: "batch/v1",
+			kind:       "CronJob",
+			expected: [][]string{
+				{"spec", "jobTemplate", "spec", "template", "spec", "initContainers", "image"},
+				{"spec", "jobTemplate", "spec", "template", "spec", "containers", "image"},
+				{"spec", "jobTemplate", "spec", "template", "spec", "ephemeralContainers", "image"},
+			},
+		},
+		{
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			expected:   [][]string{{"spec", "template", "spec", "containers", "image"}},
+		},
+		{
+			apiVersion: "serving.knative.dev/v1",
+			kind:       "Service",
+			expected: [][]string{
+				{"spec", "template", "spec", "initContainers", "image"},
+				{"spec", "template", "spec", "containers", "image"},
+				{"spec", "template", "spec", "ephemeralContainers", "image"},
+			},
+		},
+		{
+			apiVersion: "v1",
+			kind:       "Service",
+		},
+		{
+			apiVersion: "argoproj.io/v1alpha1",
+			kind:       "Workflow",
+			expected:   [][]string{{"spec", "templates", "container", "image"}},
+		},
+		{
+			apiVersion: "argoproj.io/v1alpha2",
+			kind:       "Workflow",
+		},
+	}
+
+	for _, tc := range testCases {
+		got := tbl.Paths(tc.apiVersion, tc.kind)
+		assert.Equal(t, tc.expected, got, "paths for %s %s", tc.apiVersion, tc.kind)
+	}
+}
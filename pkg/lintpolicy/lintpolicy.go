@@ -0,0 +1,283 @@
+package lintpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/resourcediff"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+)
+
+var (
+	// PolicyFile the location of the lint policy relative to the cluster git repository
+	PolicyFile = filepath.Join(".jx", "gitops", v1alpha1.LintPolicyFileName)
+
+	fileIgnoreRegex = regexp.MustCompile(`^#\s*` + regexp.QuoteMeta(v1alpha1.LintIgnoreAnnotation) + `:\s*(.+)$`)
+)
+
+// Context the context used when evaluating rules
+type Context struct {
+	Dir          string
+	Requirements *jxcore.RequirementsConfig
+	// ConfigRoot the directory of the kubernetes resources relative to Dir. Defaults to config-root
+	ConfigRoot string
+	// NamespacedKinds whether each known kind is namespaced
+	NamespacedKinds map[string]bool
+}
+
+// Rule a rule evaluated against each resource
+type Rule struct {
+	ID          string
+	Description string
+	Severity    v1alpha1.LintSeverity
+	Kinds       []string
+	// Check returns the messages for any violations of the rule
+	Check func(ctx *Context, r *resourcediff.Resource) []string
+}
+
+// Finding a violation of a rule
+type Finding struct {
+	RuleID   string                `json:"ruleId"`
+	Severity v1alpha1.LintSeverity `json:"severity"`
+	Path     string                `json:"path"`
+	Resource string                `json:"resource,omitempty"`
+	Message  string                `json:"message"`
+}
+
+// Engine evaluates the rules against the resources in a directory
+type Engine struct {
+	Context *Context
+	Rules   []Rule
+	Exclude []string
+}
+
+// LoadPolicy loads the lint policy from the given file if it exists
+func LoadPolicy(path string) (*v1alpha1.LintPolicy, error) {
+	policy := &v1alpha1.LintPolicy{}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return policy, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return policy, nil
+	}
+	err = yamls.LoadFile(path, policy)
+	if err != nil {
+		return policy, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return policy, nil
+}
+
+// NewEngine creates an engine from the built in rules configured with the given policy
+func NewEngine(ctx *Context, policy *v1alpha1.LintPolicy) (*Engine, error) {
+	rules := BuiltInRules()
+	for i := range policy.Spec.Rules {
+		pr := &policy.Spec.Rules[i]
+		if pr.ID == "" {
+			return nil, errors.Errorf("missing id for lint rule %d", i)
+		}
+		idx := -1
+		for j := range rules {
+			if rules[j].ID == pr.ID {
+				idx = j
+				break
+			}
+		}
+		if idx >= 0 {
+			if pr.Disabled {
+				rules = append(rules[:idx], rules[idx+1:]...)
+				continue
+			}
+			if pr.Severity != "" {
+				rules[idx].Severity = pr.Severity
+			}
+			if len(pr.Kinds) > 0 {
+				rules[idx].Kinds = pr.Kinds
+			}
+			continue
+		}
+		if pr.Disabled {
+			continue
+		}
+		rule, err := NewCustomRule(pr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid lint rule %s", pr.ID)
+		}
+		rules = append(rules, rule)
+	}
+	return &Engine{
+		Context: ctx,
+		Rules:   rules,
+		Exclude: policy.Spec.Exclude,
+	}, nil
+}
+
+// Lint evaluates the rules against every resource in the given directory of the cluster repository
+func (e *Engine) Lint(dir string) ([]Finding, error) {
+	var answer []Finding
+	root := filepath.Join(e.Context.Dir, dir)
+	exists, err := files.DirExists(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if dir exists %s", root)
+	}
+	if !exists {
+		return nil, nil
+	}
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || (!strings.HasSuffix(path, ".yaml") && !strings.HasSuffix(path, ".yml")) {
+			return nil
+		}
+		rel, err := filepath.Rel(e.Context.Dir, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find relative path of %s", path)
+		}
+		rel = filepath.ToSlash(rel)
+		if e.excluded(rel) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read file %s", path)
+		}
+		answer = append(answer, e.LintFile(rel, data)...)
+		return nil
+	})
+	if err != nil {
+		return answer, errors.Wrapf(err, "failed to lint files in %s", root)
+	}
+	sort.SliceStable(answer, func(i, j int) bool {
+		return answer[i].Path < answer[j].Path
+	})
+	return answer, nil
+}
+
+// LintFile evaluates the rules against the resources in the given file
+func (e *Engine) LintFile(path string, data []byte) []Finding {
+	fileIgnores := parseFileIgnores(data)
+	if fileIgnores["*"] {
+		return nil
+	}
+	resources, err := resourcediff.ParseResources(path, data)
+	if err != nil {
+		return []Finding{
+			{
+				RuleID:   "invalid-yaml",
+				Severity: v1alpha1.LintSeverityError,
+				Path:     path,
+				Message:  err.Error(),
+			},
+		}
+	}
+
+	var answer []Finding
+	for _, r := range resources {
+		ignores := resourceIgnores(r)
+		if ignores["*"] {
+			continue
+		}
+		for i := range e.Rules {
+			rule := &e.Rules[i]
+			if fileIgnores[rule.ID] || ignores[rule.ID] || !matchesKind(rule.Kinds, r.Key.Kind) {
+				continue
+			}
+			for _, msg := range rule.Check(e.Context, r) {
+				answer = append(answer, Finding{
+					RuleID:   rule.ID,
+					Severity: rule.Severity,
+					Path:     path,
+					Resource: r.Key.String(),
+					Message:  msg,
+				})
+			}
+		}
+	}
+	return answer
+}
+
+func (e *Engine) excluded(path string) bool {
+	for _, pattern := range e.Exclude {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) {
+			return true
+		}
+		matched, err := filepath.Match(pattern, path)
+		if err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// SeverityLevel returns a number for the severity so severities can be compared
+func SeverityLevel(severity v1alpha1.LintSeverity) int {
+	switch severity {
+	case v1alpha1.LintSeverityError:
+		return 3
+	case v1alpha1.LintSeverityWarning:
+		return 2
+	case v1alpha1.LintSeverityInfo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseFileIgnores(data []byte) map[string]bool {
+	answer := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		m := fileIgnoreRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if len(m) > 1 {
+			addIgnores(answer, m[1])
+		}
+	}
+	return answer
+}
+
+func resourceIgnores(r *resourcediff.Resource) map[string]bool {
+	answer := map[string]bool{}
+	text, _ := getValue(r.Object, "metadata", "annotations", v1alpha1.LintIgnoreAnnotation).(string)
+	addIgnores(answer, text)
+	return answer
+}
+
+func addIgnores(ignores map[string]bool, text string) {
+	for _, id := range strings.Split(text, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ignores[id] = true
+		}
+	}
+}
+
+func matchesKind(kinds []string, kind string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func getValue(obj map[string]interface{}, fields ...string) interface{} {
+	var value interface{} = obj
+	for _, f := range fields {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[f]
+	}
+	return value
+}
@@ -0,0 +1,103 @@
+package lintpolicy_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apiresources"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/lintpolicy"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintPolicy(t *testing.T) {
+	dir := "testdata"
+	policy, err := lintpolicy.LoadPolicy(filepath.Join(dir, lintpolicy.PolicyFile))
+	require.NoError(t, err, "failed to load policy")
+
+	requirementsResource, _, err := jxcore.LoadRequirementsConfig(dir, false)
+	require.NoError(t, err, "failed to load requirements")
+
+	namespacedKinds := map[string]bool{}
+	err = apiresources.AddCustomResourceDefinitions(namespacedKinds, filepath.Join(dir, "config-root"))
+	require.NoError(t, err, "failed to find CRDs")
+
+	engine, err := lintpolicy.NewEngine(&lintpolicy.Context{
+		Dir:             dir,
+		Requirements:    &requirementsResource.Spec,
+		NamespacedKinds: namespacedKinds,
+	}, policy)
+	require.NoError(t, err, "failed to create engine")
+
+	findings, err := engine.Lint("config-root")
+	require.NoError(t, err, "failed to lint")
+
+	type result struct {
+		rule     string
+		severity v1alpha1.LintSeverity
+		path     string
+	}
+	var results []result
+	for i := range findings {
+		f := &findings[i]
+		t.Logf("%s %s %s %s %s\n", f.Severity, f.RuleID, f.Path, f.Resource, f.Message)
+		results = append(results, result{rule: f.RuleID, severity: f.Severity, path: f.Path})
+	}
+
+	assert.ElementsMatch(t, []result{
+		{rule: "namespaced-kind-location", severity: v1alpha1.LintSeverityError, path: "config-root/cluster/wrong-cm.yaml"},
+		{rule: "namespaced-kind-location", severity: v1alpha1.LintSeverityError, path: "config-root/namespaces/jx/default-globalpolicy.yaml"},
+		{rule: "ingress-host-domain", severity: v1alpha1.LintSeverityError, path: "config-root/namespaces/jx/myapp-ing.yaml"},
+		{rule: "no-latest-image-tag", severity: v1alpha1.LintSeverityError, path: "config-root/namespaces/jx/myapp-deploy.yaml"},
+		{rule: "deployment-resource-requests", severity: v1alpha1.LintSeverityInfo, path: "config-root/namespaces/jx/myapp-deploy.yaml"},
+		{rule: "team-label", severity: v1alpha1.LintSeverityWarning, path: "config-root/namespaces/jx/myapp-deploy.yaml"},
+		{rule: "no-plain-secrets", severity: v1alpha1.LintSeverityError, path: "config-root/namespaces/jx/mysecret-secret.yaml"},
+	}, results, "findings")
+
+	buf := &bytes.Buffer{}
+	err = lintpolicy.WriteSARIF(buf, engine.Rules, findings)
+	require.NoError(t, err, "failed to write SARIF")
+
+	sarif := map[string]interface{}{}
+	err = json.Unmarshal(buf.Bytes(), &sarif)
+	require.NoError(t, err, "failed to parse SARIF")
+	assert.Equal(t, "2.1.0", sarif["version"], "SARIF version")
+	runs := sarif["runs"].([]interface{})
+	require.Len(t, runs, 1, "SARIF runs")
+	results2 := runs[0].(map[string]interface{})["results"].([]interface{})
+	assert.Len(t, results2, len(findings), "SARIF results")
+}
+
+func TestNamespacedKindLocationConfigRoot(t *testing.T) {
+	engine, err := lintpolicy.NewEngine(&lintpolicy.Context{
+		ConfigRoot: "manifests",
+	}, &v1alpha1.LintPolicy{})
+	require.NoError(t, err, "failed to create engine")
+
+	cm := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cheese\n  namespace: jx\n")
+	findings := engine.LintFile("manifests/cluster/cheese-cm.yaml", cm)
+	require.Len(t, findings, 1, "findings for a namespaced resource in the cluster dir")
+	assert.Equal(t, "namespaced-kind-location", findings[0].RuleID)
+	assert.Equal(t, "resource in namespace jx should be in manifests/namespaces/jx", findings[0].Message)
+
+	findings = engine.LintFile("manifests/namespaces/jx/cheese-cm.yaml", cm)
+	assert.Empty(t, findings, "findings for a namespaced resource in its namespace dir")
+}
+
+func TestIsLatestImage(t *testing.T) {
+	testCases := map[string]bool{
+		"nginx":                          true,
+		"nginx:latest":                   true,
+		"localhost:5000/nginx":           true,
+		"localhost:5000/nginx:1.2.3":     false,
+		"gcr.io/myorg/nginx:1.2.3":       false,
+		"gcr.io/myorg/nginx@sha256:1234": false,
+	}
+	for image, expected := range testCases {
+		assert.Equal(t, expected, lintpolicy.IsLatestImage(image), "IsLatestImage for %s", image)
+	}
+}
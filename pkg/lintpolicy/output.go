@@ -0,0 +1,145 @@
+package lintpolicy
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/pkg/errors"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "jx-gitops"
+)
+
+// WriteJSON writes the findings as JSON
+func WriteJSON(w io.Writer, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal findings to JSON")
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteSARIF writes the findings as a SARIF 2.1.0 log so they can be uploaded to code scanning tools
+func WriteSARIF(w io.Writer, rules []Rule, findings []Finding) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:  toolName,
+				Rules: []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+	ruleIDs := map[string]bool{}
+	addRule := func(id, description string) {
+		if ruleIDs[id] {
+			return
+		}
+		ruleIDs[id] = true
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: description},
+		})
+	}
+	for i := range rules {
+		addRule(rules[i].ID, rules[i].Description)
+	}
+	for i := range findings {
+		f := &findings[i]
+		addRule(f.RuleID, f.RuleID)
+		message := f.Message
+		if f.Resource != "" {
+			message = f.Resource + ": " + message
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  f.RuleID,
+			Level:   sarifLevel(f.Severity),
+			Message: sarifMessage{Text: message},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: f.Path},
+					},
+				},
+			},
+		})
+	}
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal findings to SARIF")
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func sarifLevel(severity v1alpha1.LintSeverity) string {
+	switch severity {
+	case v1alpha1.LintSeverityError:
+		return "error"
+	case v1alpha1.LintSeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
@@ -0,0 +1,264 @@
+package lintpolicy
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apiresources"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/resourcediff"
+	"github.com/pkg/errors"
+)
+
+// BuiltInRules returns the built in rules
+func BuiltInRules() []Rule {
+	return []Rule{
+		{
+			ID:          "no-latest-image-tag",
+			Description: "container images must use a fixed tag or digest rather than latest",
+			Severity:    v1alpha1.LintSeverityError,
+			Check: func(_ *Context, r *resourcediff.Resource) []string {
+				var answer []string
+				for _, c := range Containers(r.Object) {
+					image, _ := c["image"].(string)
+					if image != "" && IsLatestImage(image) {
+						answer = append(answer, fmt.Sprintf("container %v uses image %s without a fixed tag", c["name"], image))
+					}
+				}
+				return answer
+			},
+		},
+		{
+			ID:          "deployment-resource-requests",
+			Description: "every container of a Deployment must specify resource requests",
+			Severity:    v1alpha1.LintSeverityWarning,
+			Kinds:       []string{"Deployment"},
+			Check: func(_ *Context, r *resourcediff.Resource) []string {
+				var answer []string
+				for _, c := range Containers(r.Object) {
+					requests, _ := getValue(c, "resources", "requests").(map[string]interface{})
+					if len(requests) == 0 {
+						answer = append(answer, fmt.Sprintf("container %v has no resource requests", c["name"]))
+					}
+				}
+				return answer
+			},
+		},
+		{
+			ID:          "no-plain-secrets",
+			Description: "secrets must not be stored in git and should be an ExternalSecret instead",
+			Severity:    v1alpha1.LintSeverityError,
+			Kinds:       []string{"Secret"},
+			Check: func(_ *Context, r *resourcediff.Resource) []string {
+				data, _ := r.Object["data"].(map[string]interface{})
+				stringData, _ := r.Object["stringData"].(map[string]interface{})
+				if len(data) == 0 && len(stringData) == 0 {
+					return nil
+				}
+				return []string{"Secret contains data in plain text so should be converted to an ExternalSecret"}
+			},
+		},
+		{
+			ID:          "ingress-host-domain",
+			Description: "ingress hosts must end with the domain in jx-requirements.yml",
+			Severity:    v1alpha1.LintSeverityError,
+			Kinds:       []string{"Ingress"},
+			Check: func(ctx *Context, r *resourcediff.Resource) []string {
+				if ctx.Requirements == nil {
+					return nil
+				}
+				domain := ctx.Requirements.Ingress.Domain
+				if domain == "" || domain == v1alpha1.DomainPlaceholder {
+					return nil
+				}
+				var hosts []string
+				for _, v := range FieldValues(r.Object, "spec.rules[*].host") {
+					if s, ok := v.(string); ok {
+						hosts = append(hosts, s)
+					}
+				}
+				for _, v := range FieldValues(r.Object, "spec.tls[*].hosts[*]") {
+					if s, ok := v.(string); ok {
+						hosts = append(hosts, s)
+					}
+				}
+				var answer []string
+				for _, host := range hosts {
+					if host != domain && !strings.HasSuffix(host, "."+domain) {
+						answer = append(answer, fmt.Sprintf("host %s does not end with the domain %s", host, domain))
+					}
+				}
+				return answer
+			},
+		},
+		{
+			ID:          "namespaced-kind-location",
+			Description: "namespaced resources must live in <config-root>/namespaces/<namespace> and cluster resources in <config-root>/cluster",
+			Severity:    v1alpha1.LintSeverityError,
+			Check: func(ctx *Context, r *resourcediff.Resource) []string {
+				configRoot := "config-root"
+				if ctx.ConfigRoot != "" {
+					configRoot = path.Clean(filepath.ToSlash(ctx.ConfigRoot))
+				}
+				namespacesDir := configRoot + "/namespaces/"
+				clusterDir := configRoot + "/cluster"
+
+				ns := r.Key.Namespace
+				folderNS := ""
+				if strings.HasPrefix(r.Path, namespacesDir) {
+					folderNS = strings.SplitN(strings.TrimPrefix(r.Path, namespacesDir), "/", 2)[0]
+				}
+				inCluster := strings.HasPrefix(r.Path, clusterDir+"/")
+				switch {
+				case ns != "" && inCluster:
+					return []string{fmt.Sprintf("resource in namespace %s should be in %s%s", ns, namespacesDir, ns)}
+				case ns != "" && folderNS != "" && ns != folderNS:
+					return []string{fmt.Sprintf("resource in namespace %s should be in %s%s", ns, namespacesDir, ns)}
+				case ns == "" && folderNS != "" && !apiresources.IsNamespaced(ctx.NamespacedKinds, r.Key.Kind):
+					return []string{fmt.Sprintf("cluster scoped %s should be in %s", r.Key.Kind, clusterDir)}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// NewCustomRule creates a rule from the given rule configuration
+func NewCustomRule(pr *v1alpha1.LintRule) (Rule, error) {
+	if pr.Field == "" {
+		return Rule{}, errors.Errorf("missing field")
+	}
+	var pattern, notPattern *regexp.Regexp
+	var err error
+	if pr.Pattern != "" {
+		pattern, err = regexp.Compile(pr.Pattern)
+		if err != nil {
+			return Rule{}, errors.Wrapf(err, "failed to parse pattern %s", pr.Pattern)
+		}
+	}
+	if pr.NotPattern != "" {
+		notPattern, err = regexp.Compile(pr.NotPattern)
+		if err != nil {
+			return Rule{}, errors.Wrapf(err, "failed to parse notPattern %s", pr.NotPattern)
+		}
+	}
+	severity := pr.Severity
+	if severity == "" {
+		severity = v1alpha1.LintSeverityError
+	}
+	field := pr.Field
+	message := pr.Message
+	return Rule{
+		ID:          pr.ID,
+		Description: pr.Description,
+		Severity:    severity,
+		Kinds:       pr.Kinds,
+		Check: func(_ *Context, r *resourcediff.Resource) []string {
+			values := FieldValues(r.Object, field)
+			if len(values) == 0 {
+				if pr.Required {
+					return []string{customMessage(message, fmt.Sprintf("missing field %s", field))}
+				}
+				return nil
+			}
+			var answer []string
+			for _, v := range values {
+				text := fmt.Sprintf("%v", v)
+				if pattern != nil && !pattern.MatchString(text) {
+					answer = append(answer, customMessage(message, fmt.Sprintf("field %s value %s does not match %s", field, text, pattern.String())))
+				}
+				if notPattern != nil && notPattern.MatchString(text) {
+					answer = append(answer, customMessage(message, fmt.Sprintf("field %s value %s matches %s", field, text, notPattern.String())))
+				}
+			}
+			return answer
+		},
+	}, nil
+}
+
+func customMessage(message, defaultMessage string) string {
+	if message != "" {
+		return message
+	}
+	return defaultMessage
+}
+
+// FieldValues returns the values of the given dot separated field path where '[*]' matches all elements of an array
+// and '[n]' matches a single element
+func FieldValues(obj map[string]interface{}, field string) []interface{} {
+	values := []interface{}{obj}
+	for _, segment := range strings.Split(field, ".") {
+		name := segment
+		index := ""
+		if i := strings.Index(segment, "["); i >= 0 && strings.HasSuffix(segment, "]") {
+			name = segment[:i]
+			index = segment[i+1 : len(segment)-1]
+		}
+		var next []interface{}
+		for _, v := range values {
+			if name != "" {
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				v, ok = m[name]
+				if !ok || v == nil {
+					continue
+				}
+			}
+			if index == "" {
+				next = append(next, v)
+				continue
+			}
+			items, ok := v.([]interface{})
+			if !ok {
+				continue
+			}
+			if index == "*" {
+				next = append(next, items...)
+				continue
+			}
+			n, err := strconv.Atoi(index)
+			if err == nil && n >= 0 && n < len(items) {
+				next = append(next, items[n])
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// Containers returns the containers and init containers of any pod spec in the resource
+func Containers(obj map[string]interface{}) []map[string]interface{} {
+	var answer []map[string]interface{}
+	for _, podSpec := range []string{"spec", "spec.template.spec", "spec.jobTemplate.spec.template.spec"} {
+		for _, name := range []string{"containers", "initContainers"} {
+			for _, v := range FieldValues(obj, podSpec+"."+name+"[*]") {
+				if c, ok := v.(map[string]interface{}); ok {
+					answer = append(answer, c)
+				}
+			}
+		}
+	}
+	return answer
+}
+
+// IsLatestImage returns true if the image has no tag or digest or uses the latest tag
+func IsLatestImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		name = image[i+1:]
+	}
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return true
+	}
+	return name[i+1:] == "latest"
+}
@@ -0,0 +1,356 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/helmfiles"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/plugins"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/quickstarthelpers"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	jxghRepositoryURL = "https://jenkins-x-charts.github.io/repo"
+)
+
+// BuiltInMigrations returns the migrations compiled into the binary in the order they are applied
+func BuiltInMigrations() []Migration {
+	return []Migration{
+		{
+			ID:          "quickstarts-yaml-extension",
+			Description: "use the .yaml extension for quickstart imports",
+			Precondition: func(ctx *Context, _ *state.HelmState) (bool, error) {
+				qs, _, err := quickstarthelpers.LoadQuickstarts(ctx.Dir)
+				if err != nil {
+					return false, errors.Wrapf(err, "failed to load quickstarts")
+				}
+				for i := range qs.Spec.Imports {
+					if strings.HasSuffix(qs.Spec.Imports[i].File, ".yml") {
+						return true, nil
+					}
+				}
+				return false, nil
+			},
+			Apply: migrateQuickstartsFile,
+		},
+		{
+			ID:          "remove-top-level-jx-values",
+			Description: "remove the top level jx-values.yaml file as nested helmfiles generate their own",
+			Precondition: func(ctx *Context, helmState *state.HelmState) (bool, error) {
+				if helmState.OverrideNamespace == "" {
+					return false, nil
+				}
+				return files.FileExists(filepath.Join(ctx.Dir, "jx-values.yaml"))
+			},
+			Apply: func(ctx *Context, _ *state.HelmState) error {
+				path := filepath.Join(ctx.Dir, "jx-values.yaml")
+				err := os.Remove(path)
+				if err != nil {
+					return errors.Wrapf(err, "failed to remove old file %s", path)
+				}
+				return nil
+			},
+		},
+		declarative(&v1alpha1.DeclarativeMigration{
+			ID:          "helm-repository-urls",
+			Description: "replace the URLs of helm repositories which have moved",
+			Repositories: []v1alpha1.RepositoryURLRewrite{
+				{
+					From: "https://kubernetes-charts.storage.googleapis.com",
+					To:   "https://charts.helm.sh/stable",
+				},
+				{
+					From: "https://comcast.github.io/kuberhealthy/helm-repos",
+					To:   "https://kuberhealthy.github.io/kuberhealthy/helm-repos",
+				},
+				{
+					From: "https://godaddy.github.io/kubernetes-external-secrets",
+					To:   "https://external-secrets.github.io/kubernetes-external-secrets",
+				},
+				{
+					From: "https://chrismellard.github.io/kubernetes-external-secrets",
+					To:   "https://external-secrets.github.io/kubernetes-external-secrets",
+				},
+				{
+					From: "https://storage.googleapis.com/jenkinsxio/charts",
+					To:   "https://external-secrets.github.io/kubernetes-external-secrets",
+					Name: "external-secrets",
+				},
+				{
+					From: "http://chartmuseum.jenkins-x.io",
+					To:   "https://storage.googleapis.com/chartmuseum.jenkins-x.io",
+				},
+			},
+		}),
+		declarative(&v1alpha1.DeclarativeMigration{
+			ID:          "tekton-pipeline-chart",
+			Description: "replace the jenkins-x/tekton chart with cdf/tekton-pipeline",
+			Charts: []v1alpha1.ChartReplacement{
+				{
+					From:          "jenkins-x/tekton",
+					To:            "cdf/tekton-pipeline",
+					Namespace:     "tekton-pipelines",
+					RepositoryURL: "https://cdfoundation.github.io/tekton-helm-chart",
+				},
+			},
+		}),
+		declarative(&v1alpha1.DeclarativeMigration{
+			ID:          "jx3-charts",
+			Description: "replace the jx3 charts with jxgh charts",
+			Charts: []v1alpha1.ChartReplacement{
+				{
+					From:          "jx3/*",
+					To:            "jxgh/*",
+					RepositoryURL: jxghRepositoryURL,
+					ReplaceValues: true,
+				},
+			},
+		}),
+		declarative(&v1alpha1.DeclarativeMigration{
+			ID:          "jenkins-x-charts",
+			Description: "replace the jenkins-x charts with jxgh charts",
+			Charts: jxghReplacements("jenkins-x", []string{"jxboot-helmfile-resources", "bucketrepo", "nexus", "lighthouse"}, func(cr *v1alpha1.ChartReplacement) {
+				cr.ReplaceValues = true
+			}),
+		}),
+		{
+			ID:          "terraform-vault-warning",
+			Description: "warn if the vault charts are still used when vault is installed via terraform",
+			Repeatable:  true,
+			Precondition: func(ctx *Context, helmState *state.HelmState) (bool, error) {
+				requirements := ctx.Requirements
+				if requirements == nil || requirements.SecretStorage != jxcore.SecretStorageTypeVault || !requirements.TerraformVault {
+					return false, nil
+				}
+				return hasChart(helmState, "jxgh/vault-instance") || hasChart(helmState, "banzaicloud-stable/vault-operator"), nil
+			},
+			Apply: func(_ *Context, helmState *state.HelmState) error {
+				for i := range helmState.Releases {
+					release := &helmState.Releases[i]
+					if release.Chart == "jxgh/vault-instance" || release.Chart == "banzaicloud-stable/vault-operator" {
+						log.Logger().Infof("Terraform installed detected and Vault chart %s still present in helmfile. Please migrate secrets as necessary and remove this chart from your helmfile", release.Chart)
+					}
+				}
+				return nil
+			},
+		},
+		declarative(&v1alpha1.DeclarativeMigration{
+			ID:          "chartmuseum-chart",
+			Description: "replace the jenkins-x/chartmuseum chart with stable/chartmuseum",
+			Charts: []v1alpha1.ChartReplacement{
+				{
+					From:           "jenkins-x/chartmuseum",
+					To:             "stable/chartmuseum",
+					RepositoryURL:  "https://charts.helm.sh/stable",
+					Values:         []string{"charts/stable/chartmuseum/values.yaml.gotmpl"},
+					ResolveVersion: true,
+				},
+			},
+		}),
+		declarative(&v1alpha1.DeclarativeMigration{
+			ID:          "nginx-ingress-chart",
+			Description: "replace the stable/nginx-ingress chart with ingress-nginx/ingress-nginx",
+			Charts: []v1alpha1.ChartReplacement{
+				{
+					From:           "stable/nginx-ingress",
+					To:             "ingress-nginx/ingress-nginx",
+					RepositoryURL:  "https://kubernetes.github.io/ingress-nginx",
+					Values:         []string{"charts/ingress-nginx/ingress-nginx/values.yaml.gotmpl"},
+					ResolveVersion: true,
+				},
+			},
+		}),
+		{
+			ID:          "ingress-nginx-values-path",
+			Description: "use the ingress-nginx values file inside the chart repository folder of the version stream",
+			Precondition: func(_ *Context, helmState *state.HelmState) (bool, error) {
+				return hasChart(helmState, "ingress-nginx/ingress-nginx"), nil
+			},
+			Apply: func(_ *Context, helmState *state.HelmState) error {
+				versionStreamPath := VersionStreamPath(helmState)
+				for i := range helmState.Releases {
+					release := &helmState.Releases[i]
+					if release.Chart != "ingress-nginx/ingress-nginx" {
+						continue
+					}
+					for j := range release.Values {
+						s, ok := release.Values[j].(string)
+						if ok && s == fmt.Sprintf("%s/charts/ingress-nginx/values.yaml.gotmpl", versionStreamPath) {
+							release.Values[j] = fmt.Sprintf("%s/charts/ingress-nginx/ingress-nginx/values.yaml.gotmpl", versionStreamPath)
+							break
+						}
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "local-external-secrets",
+			Description: "ensure the local-external-secrets chart is installed when using local secret storage",
+			Repeatable:  true,
+			Precondition: func(ctx *Context, helmState *state.HelmState) (bool, error) {
+				requirements := ctx.Requirements
+				if requirements == nil || requirements.SecretStorage != jxcore.SecretStorageTypeLocal || helmState.OverrideNamespace != jxcore.DefaultNamespace {
+					return false, nil
+				}
+				return !hasChart(helmState, "jxgh/local-external-secrets"), nil
+			},
+			Apply: func(ctx *Context, helmState *state.HelmState) error {
+				release := state.ReleaseSpec{
+					Chart: "jxgh/local-external-secrets",
+				}
+				UpdateVersionFromVersionStream(ctx, &release)
+				helmState.Releases = append(helmState.Releases, release)
+				EnsureRepository(helmState, "jxgh", jxghRepositoryURL)
+				return nil
+			},
+		},
+		declarative(&v1alpha1.DeclarativeMigration{
+			ID:          "jx-labs-charts",
+			Description: "replace the jx-labs charts with jxgh charts",
+			Charts: jxghReplacements("jx-labs", []string{"jenkins-x-crds", "pusher-wave", "vault-instance"}, func(cr *v1alpha1.ChartReplacement) {
+				cr.ResolveVersion = true
+				if cr.To == "jxgh/jenkins-x-crds" {
+					cr.Values = []string{"charts/jxgh/jenkins-x-crds/values.yaml.gotmpl"}
+				}
+			}),
+		}),
+		{
+			ID:          "jx-build-controller",
+			Description: "ensure the jx-build-controller chart is installed in the development cluster",
+			Repeatable:  true,
+			Precondition: func(_ *Context, helmState *state.HelmState) (bool, error) {
+				return helmState.OverrideNamespace == jxcore.DefaultNamespace && isDevCluster(helmState) && !hasChart(helmState, "jxgh/jx-build-controller"), nil
+			},
+			Apply: func(_ *Context, helmState *state.HelmState) error {
+				helmState.Releases = append(helmState.Releases, state.ReleaseSpec{
+					Chart: "jxgh/jx-build-controller",
+				})
+				EnsureRepository(helmState, "jxgh", jxghRepositoryURL)
+				return nil
+			},
+		},
+		{
+			ID:          "environment-pipelines",
+			Description: "add the environment tekton pipelines to the .lighthouse folder if enabled",
+			Repeatable:  true,
+			Precondition: func(ctx *Context, _ *state.HelmState) (bool, error) {
+				if !ctx.AddEnvironmentPipelines {
+					return false, nil
+				}
+				exists, err := files.FileExists(lighthouseTriggerFile(ctx))
+				return !exists, err
+			},
+			Apply: addEnvironmentPipelines,
+		},
+	}
+}
+
+func declarative(dm *v1alpha1.DeclarativeMigration) Migration {
+	return NewDeclarativeMigration(dm, SourceBuiltIn)
+}
+
+func jxghReplacements(prefix string, names []string, fn func(cr *v1alpha1.ChartReplacement)) []v1alpha1.ChartReplacement {
+	var answer []v1alpha1.ChartReplacement
+	for _, name := range names {
+		cr := v1alpha1.ChartReplacement{
+			From:          prefix + "/" + name,
+			To:            "jxgh/" + name,
+			RepositoryURL: jxghRepositoryURL,
+		}
+		fn(&cr)
+		answer = append(answer, cr)
+	}
+	return answer
+}
+
+func hasChart(helmState *state.HelmState, chart string) bool {
+	for i := range helmState.Releases {
+		if helmState.Releases[i].Chart == chart {
+			return true
+		}
+	}
+	return false
+}
+
+func isDevCluster(helmState *state.HelmState) bool {
+	for k := range helmState.Releases {
+		release := helmState.Releases[k]
+		_, local := helmfiles.SpitChartName(release.Chart)
+		if local == "jxboot-helmfile-resources" || local == "lighthouse" {
+			return true
+		}
+	}
+	return false
+}
+
+func migrateQuickstartsFile(ctx *Context, _ *state.HelmState) error {
+	qs, path, err := quickstarthelpers.LoadQuickstarts(ctx.Dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load quickstarts")
+	}
+
+	modified := false
+	for i := range qs.Spec.Imports {
+		ip := &qs.Spec.Imports[i]
+		if strings.HasSuffix(ip.File, ".yml") {
+			ip.File = strings.TrimSuffix(ip.File, ".yml") + ".yaml"
+			modified = true
+		}
+	}
+	if !modified {
+		return nil
+	}
+
+	err = yamls.SaveFile(qs, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save file %s", path)
+	}
+	log.Logger().Infof("patched %s to use correct .yaml extension", info(path))
+	return nil
+}
+
+func lighthouseTriggerFile(ctx *Context) string {
+	return filepath.Join(ctx.Dir, ".lighthouse", "jenkins-x", "triggers.yaml")
+}
+
+func addEnvironmentPipelines(ctx *Context, _ *state.HelmState) error {
+	var err error
+	bin := ctx.KptBinary
+	if bin == "" {
+		bin, err = plugins.GetKptBinary(plugins.KptVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := []string{"pkg", "get", "https://github.com/jenkins-x/jx3-pipeline-catalog.git/environment/.lighthouse", ctx.Dir}
+	c := &cmdrunner.Command{
+		Name: bin,
+		Args: args,
+		Dir:  ctx.Dir,
+	}
+	_, err = ctx.CommandRunner(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get environment tekton pipeline via kpt in dir %s", ctx.Dir)
+	}
+
+	err = gitclient.Add(ctx.Gitter, ctx.Dir, ".lighthouse")
+	if err != nil {
+		return errors.Wrapf(err, "failed to add .lighthouse dir to git")
+	}
+
+	log.Logger().Infof("got tekton pipeline for envirnment at %s", lighthouseTriggerFile(ctx))
+	return nil
+}
@@ -0,0 +1,110 @@
+package migrations
+
+import (
+	"strings"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+)
+
+// NewDeclarativeMigration creates a migration from the given declarative migration
+func NewDeclarativeMigration(dm *v1alpha1.DeclarativeMigration, source string) Migration {
+	return Migration{
+		ID:          dm.ID,
+		Description: dm.Description,
+		Source:      source,
+		Precondition: func(_ *Context, helmState *state.HelmState) (bool, error) {
+			for i := range dm.Repositories {
+				for j := range helmState.Repositories {
+					if matchesRepository(&dm.Repositories[i], &helmState.Repositories[j]) {
+						return true, nil
+					}
+				}
+			}
+			for i := range dm.Charts {
+				for j := range helmState.Releases {
+					if _, ok := ReplaceChartName(dm.Charts[i].From, dm.Charts[i].To, helmState.Releases[j].Chart); ok {
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		},
+		Apply: func(ctx *Context, helmState *state.HelmState) error {
+			for i := range dm.Repositories {
+				rewrite := &dm.Repositories[i]
+				for j := range helmState.Repositories {
+					repo := &helmState.Repositories[j]
+					if matchesRepository(rewrite, repo) {
+						repo.URL = rewrite.To
+					}
+				}
+			}
+			for i := range dm.Charts {
+				replaceChart(ctx, helmState, &dm.Charts[i])
+			}
+			return nil
+		},
+	}
+}
+
+// ReplaceChartName returns the new chart name if the chart matches the from chart name.
+// The from and to names can end in '/*' to replace the repository prefix of any chart
+func ReplaceChartName(from, to, chart string) (string, bool) {
+	if strings.HasSuffix(from, "/*") {
+		prefix := strings.TrimSuffix(from, "*")
+		if strings.HasPrefix(chart, prefix) && len(chart) > len(prefix) {
+			return strings.TrimSuffix(to, "*") + chart[len(prefix):], true
+		}
+		return chart, false
+	}
+	if chart == from {
+		return to, true
+	}
+	return chart, false
+}
+
+func matchesRepository(rewrite *v1alpha1.RepositoryURLRewrite, repo *state.RepositorySpec) bool {
+	if rewrite.Name != "" && rewrite.Name != repo.Name {
+		return false
+	}
+	return strings.TrimSuffix(repo.URL, "/") == strings.TrimSuffix(rewrite.From, "/")
+}
+
+func replaceChart(ctx *Context, helmState *state.HelmState, cr *v1alpha1.ChartReplacement) {
+	versionStreamPath := VersionStreamPath(helmState)
+	fromDir := versionStreamPath + "/charts/" + strings.TrimSuffix(cr.From, "/*") + "/"
+	toDir := versionStreamPath + "/charts/" + strings.TrimSuffix(cr.To, "/*") + "/"
+
+	for i := range helmState.Releases {
+		release := &helmState.Releases[i]
+		chart, ok := ReplaceChartName(cr.From, cr.To, release.Chart)
+		if !ok {
+			continue
+		}
+		release.Chart = chart
+		if cr.Namespace != "" {
+			release.Namespace = cr.Namespace
+		}
+		if cr.ResolveVersion {
+			UpdateVersionFromVersionStream(ctx, release)
+		}
+		if len(cr.Values) > 0 {
+			release.Values = nil
+			for _, v := range cr.Values {
+				release.Values = append(release.Values, versionStreamPath+"/"+v)
+			}
+		} else if cr.ReplaceValues {
+			for j := range release.Values {
+				s, ok := release.Values[j].(string)
+				if ok && strings.HasPrefix(s, fromDir) {
+					release.Values[j] = toDir + strings.TrimPrefix(s, fromDir)
+				}
+			}
+		}
+		if cr.RepositoryURL != "" {
+			prefix := strings.SplitN(chart, "/", 2)[0]
+			EnsureRepository(helmState, prefix, cr.RepositoryURL)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/helmfile/helmfile/pkg/state"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SourceBuiltIn migrations which are compiled into the binary
+	SourceBuiltIn = "builtin"
+
+	// SourceVersionStream migrations which are declared in the version stream
+	SourceVersionStream = "versionStream"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	// MigrationsFile the location of the applied migrations file relative to the cluster git repository
+	MigrationsFile = filepath.Join(".jx", "gitops", v1alpha1.MigrationsFileName)
+)
+
+// Context the context used to evaluate and apply migrations
+type Context struct {
+	Dir                     string
+	Requirements            *jxcore.RequirementsConfig
+	Resolver                *versionstream.VersionResolver
+	CommandRunner           cmdrunner.CommandRunner
+	Gitter                  gitclient.Interface
+	KptBinary               string
+	AddEnvironmentPipelines bool
+}
+
+// Migration a named migration of the cluster git repository
+type Migration struct {
+	// ID the unique identifier of the migration
+	ID string
+	// Description a description of the migration
+	Description string
+	// Source where the migration came from
+	Source string
+	// Repeatable migrations are evaluated on every run and never recorded as applied
+	Repeatable bool
+	// Precondition returns true if the migration needs to be applied to the given helm state
+	Precondition func(ctx *Context, helmState *state.HelmState) (bool, error)
+	// Apply applies the migration to the given helm state
+	Apply func(ctx *Context, helmState *state.HelmState) error
+}
+
+// Runner applies the pending migrations and records them as applied
+type Runner struct {
+	Context    *Context
+	Migrations []Migration
+	Record     *v1alpha1.Migrations
+	RecordFile string
+	DryRun     bool
+
+	// applied the IDs of the migrations which have been applied by this runner
+	applied map[string]bool
+}
+
+// NewRunner creates a runner for the built in migrations and any declared in the version stream
+func NewRunner(ctx *Context, versionStreamDir string) (*Runner, error) {
+	recordFile := filepath.Join(ctx.Dir, MigrationsFile)
+	record, err := LoadMigrations(recordFile)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &Runner{
+		Context:    ctx,
+		Migrations: BuiltInMigrations(),
+		Record:     record,
+		RecordFile: recordFile,
+	}
+	if versionStreamDir == "" {
+		return answer, nil
+	}
+
+	path := filepath.Join(versionStreamDir, v1alpha1.MigrationsFileName)
+	vs, err := LoadMigrations(path)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]bool{}
+	for i := range answer.Migrations {
+		ids[answer.Migrations[i].ID] = true
+	}
+	for i := range vs.Spec.Migrations {
+		dm := vs.Spec.Migrations[i]
+		if dm.ID == "" {
+			return nil, errors.Errorf("missing id for migration %d in file %s", i, path)
+		}
+		if ids[dm.ID] {
+			return nil, errors.Errorf("duplicate migration id %s in file %s", dm.ID, path)
+		}
+		ids[dm.ID] = true
+		answer.Migrations = append(answer.Migrations, NewDeclarativeMigration(&dm, SourceVersionStream))
+	}
+	return answer, nil
+}
+
+// LoadMigrations loads the migrations file if it exists
+func LoadMigrations(path string) (*v1alpha1.Migrations, error) {
+	answer := &v1alpha1.Migrations{}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return answer, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return answer, nil
+	}
+	err = yamls.LoadFile(path, answer)
+	if err != nil {
+		return answer, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return answer, nil
+}
+
+// Pending returns the migrations which have not yet been applied
+func (r *Runner) Pending() []Migration {
+	var answer []Migration
+	for i := range r.Migrations {
+		m := r.Migrations[i]
+		if m.Repeatable || !r.Record.IsApplied(m.ID) {
+			answer = append(answer, m)
+		}
+	}
+	return answer
+}
+
+// Run evaluates the pending migrations against the given helm state returning the IDs of the matching migrations.
+// In dry run mode the matching migrations are not applied
+func (r *Runner) Run(helmState *state.HelmState) ([]string, error) {
+	if helmState.Releases == nil {
+		return nil, nil
+	}
+	var answer []string
+	for _, m := range r.Pending() {
+		if m.Precondition != nil {
+			matched, err := m.Precondition(r.Context, helmState)
+			if err != nil {
+				return answer, errors.Wrapf(err, "failed to evaluate precondition of migration %s", m.ID)
+			}
+			if !matched {
+				continue
+			}
+		}
+		answer = append(answer, m.ID)
+		if r.DryRun {
+			continue
+		}
+		err := m.Apply(r.Context, helmState)
+		if err != nil {
+			return answer, errors.Wrapf(err, "failed to apply migration %s", m.ID)
+		}
+		log.Logger().Debugf("applied migration %s", info(m.ID))
+		if !m.Repeatable {
+			if r.applied == nil {
+				r.applied = map[string]bool{}
+			}
+			r.applied[m.ID] = true
+		}
+	}
+	return answer, nil
+}
+
+// Save records the migrations which have been applied by Run
+func (r *Runner) Save() error {
+	if r.DryRun {
+		return nil
+	}
+	modified := false
+	now := metav1.Now()
+	for _, m := range r.Pending() {
+		if !r.applied[m.ID] {
+			continue
+		}
+		r.Record.Spec.Applied = append(r.Record.Spec.Applied, v1alpha1.AppliedMigration{
+			ID:        m.ID,
+			Timestamp: now,
+		})
+		modified = true
+	}
+	if !modified {
+		return nil
+	}
+	if r.Record.APIVersion == "" {
+		r.Record.APIVersion = v1alpha1.APIVersion
+	}
+	if r.Record.Kind == "" {
+		r.Record.Kind = v1alpha1.KindMigrations
+	}
+	err := os.MkdirAll(filepath.Dir(r.RecordFile), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make directory %s", filepath.Dir(r.RecordFile))
+	}
+	err = yamls.SaveFile(r.Record, r.RecordFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save %s", r.RecordFile)
+	}
+	return nil
+}
+
+// VersionStreamPath returns the path to the version stream relative to the helmfile of the given state
+func VersionStreamPath(helmState *state.HelmState) string {
+	if helmState.OverrideNamespace == "" {
+		return "versionStream"
+	}
+	return "../../versionStream"
+}
+
+// EnsureRepository adds the repository with the given name and URL if there is not one with the same name
+func EnsureRepository(helmState *state.HelmState, name, url string) {
+	for k := range helmState.Repositories {
+		if helmState.Repositories[k].Name == name {
+			return
+		}
+	}
+	helmState.Repositories = append(helmState.Repositories, state.RepositorySpec{
+		Name: name,
+		URL:  url,
+	})
+}
+
+// UpdateVersionFromVersionStream updates the version of the release to the version in the version stream
+func UpdateVersionFromVersionStream(ctx *Context, release *state.ReleaseSpec) {
+	versionProperties, err := ctx.Resolver.StableVersion(versionstream.KindChart, release.Chart)
+	if err != nil {
+		log.Logger().Warnf("failed to find version number for chart %s", release.Chart)
+		release.Version = ""
+	}
+
+	if versionProperties == nil {
+		log.Logger().Warnf("failed to find version number for chart %s", release.Chart)
+		release.Version = ""
+		return
+	}
+
+	release.Version = versionProperties.Version
+}
@@ -0,0 +1,266 @@
+package ociregistry
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/httphelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/pkg/errors"
+)
+
+const (
+	// OCIScheme the URL scheme of OCI registries
+	OCIScheme = "oci://"
+
+	// MediaTypeOCIManifest the media type of OCI image manifests
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeDockerManifest the media type of docker v2 image manifests
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// DigestHeader the header containing the digest of a manifest
+	DigestHeader = "Docker-Content-Digest"
+)
+
+// Client accesses registries using the v2 registry API authenticating via the registry credentials
+type Client struct {
+	HTTPClient   *http.Client
+	DockerConfig *DockerConfig
+	lock         sync.Mutex
+	credentials  map[string]Credentials
+	tokens       map[string]string
+}
+
+// NewClient creates a new client using the credentials in the docker config file which is ignored if it does not exist
+func NewClient(registryConfigFile string) (*Client, error) {
+	config, err := LoadDockerConfig(registryConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		HTTPClient:   httphelpers.GetClient(),
+		DockerConfig: config,
+	}, nil
+}
+
+// Manifest the content of an image manifest
+type Manifest struct {
+	MediaType string       `json:"mediaType,omitempty"`
+	Config    Descriptor   `json:"config"`
+	Layers    []Descriptor `json:"layers,omitempty"`
+}
+
+// Descriptor describes the content of a blob
+type Descriptor struct {
+	MediaType string `json:"mediaType,omitempty"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size,omitempty"`
+}
+
+// SetCredentials sets the credentials to use for a registry host instead of those in the docker config
+func (c *Client) SetCredentials(host string, credentials Credentials) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.credentials == nil {
+		c.credentials = map[string]Credentials{}
+	}
+	c.credentials[host] = credentials
+}
+
+// Tags returns the tags of the repository in the registry host
+func (c *Client) Tags(host, repository string) ([]string, error) {
+	data, _, err := c.Get(host, repository, "tags/list")
+	if err != nil {
+		return nil, err
+	}
+	tags := struct {
+		Tags []string `json:"tags"`
+	}{}
+	err = json.Unmarshal(data, &tags)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse tags of %s/%s", host, repository)
+	}
+	return tags.Tags, nil
+}
+
+// Manifest returns the manifest and its digest for the tag or digest of the repository in the registry host
+func (c *Client) Manifest(host, repository, reference string) (*Manifest, string, error) {
+	data, header, err := c.Get(host, repository, "manifests/"+reference, MediaTypeOCIManifest, MediaTypeDockerManifest)
+	if err != nil {
+		return nil, "", err
+	}
+	answer := &Manifest{}
+	err = json.Unmarshal(data, answer)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to parse manifest of %s/%s:%s", host, repository, reference)
+	}
+	return answer, header.Get(DigestHeader), nil
+}
+
+// Blob returns the blob with the digest in the repository of the registry host
+func (c *Client) Blob(host, repository, digest string) ([]byte, error) {
+	data, _, err := c.Get(host, repository, "blobs/"+digest)
+	return data, err
+}
+
+// Get performs a GET of the path within the repository of the v2 API of the registry host, such as 'tags/list',
+// authenticating if the registry requests it
+func (c *Client) Get(host, repository, path string, accept ...string) ([]byte, http.Header, error) {
+	apiHost := host
+	if host == dockerHubHost {
+		apiHost = dockerHubAPIHost
+	}
+	u := "https://" + apiHost + "/v2/" + strings.Trim(repository, "/") + "/" + path
+	scope := "repository:" + strings.Trim(repository, "/") + ":pull"
+	tokenKey := host + " " + scope
+
+	c.lock.Lock()
+	token := c.tokens[tokenKey]
+	c.lock.Unlock()
+
+	resp, err := c.do(u, "Bearer", token, accept)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		challenge := resp.Header.Get("WWW-Authenticate")
+		credentials, err := c.Credentials(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		scheme, params := ParseChallenge(challenge)
+		if strings.EqualFold(scheme, "basic") {
+			if credentials.IsEmpty() {
+				return nil, nil, errors.Errorf("no credentials for registry %s", host)
+			}
+			resp, err = c.do(u, "Basic", basicAuth(credentials), accept)
+		} else {
+			if params["scope"] == "" {
+				params["scope"] = scope
+			}
+			token, err = c.token(params, credentials)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to get token for %s", u)
+			}
+			c.lock.Lock()
+			if c.tokens == nil {
+				c.tokens = map[string]string{}
+			}
+			c.tokens[tokenKey] = token
+			c.lock.Unlock()
+			resp, err = c.do(u, "Bearer", token, accept)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, nil, errors.Errorf("failed to GET %s with status %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read response from %s", u)
+	}
+	return data, resp.Header, nil
+}
+
+// Credentials returns the credentials for the registry host
+func (c *Client) Credentials(host string) (Credentials, error) {
+	c.lock.Lock()
+	credentials, ok := c.credentials[host]
+	c.lock.Unlock()
+	if ok {
+		return credentials, nil
+	}
+	return c.DockerConfig.Credentials(host)
+}
+
+func (c *Client) token(params map[string]string, credentials Credentials) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", errors.Errorf("no realm in the authentication challenge")
+	}
+	values := url.Values{}
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			values.Set(k, params[k])
+		}
+	}
+	u := realm + "?" + values.Encode()
+	authScheme := ""
+	auth := ""
+	if !credentials.IsEmpty() {
+		authScheme = "Basic"
+		auth = basicAuth(credentials)
+	}
+	resp, err := c.do(u, authScheme, auth, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", errors.Errorf("failed to GET %s with status %s", realm, resp.Status)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse token from %s", realm)
+	}
+	return stringhelpers.FirstNotEmptyString(token.Token, token.AccessToken), nil
+}
+
+func (c *Client) do(u, authScheme, auth string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create request for %s", u)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", authScheme+" "+auth)
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = httphelpers.GetClient()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to GET %s", u)
+	}
+	return resp, nil
+}
+
+func basicAuth(credentials Credentials) string {
+	return base64Encode(credentials.Username + ":" + credentials.Password)
+}
+
+// SplitURL splits an OCI URL such as oci://ghcr.io/myorg/charts into the host and repository path
+func SplitURL(u string) (string, string) {
+	u = strings.TrimPrefix(u, OCIScheme)
+	u = strings.TrimSuffix(u, "/")
+	host, path, _ := strings.Cut(u, "/")
+	return host, path
+}
+
+// ParseChallenge parses the scheme and parameters of a WWW-Authenticate header such as: Bearer realm="...",service="..."
+func ParseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	for _, part := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[k] = strings.Trim(v, `"`)
+		}
+	}
+	return scheme, params
+}
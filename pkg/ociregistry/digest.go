@@ -0,0 +1,142 @@
+package ociregistry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MediaTypeOCIIndex the media type of OCI image indexes used for multi platform images
+	MediaTypeOCIIndex = "application/vnd.oci.image.index.v1+json"
+	// MediaTypeDockerManifestList the media type of docker v2 manifest lists used for multi platform images
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	dockerHubAPIHost = "registry-1.docker.io"
+)
+
+// DigestResolver resolves the digest of the manifest of an image tag
+type DigestResolver interface {
+	// Digest returns the digest of the manifest of the tag of the repository in the registry host
+	Digest(host, repository, tag string) (string, error)
+}
+
+// Digest returns the digest of the manifest of the tag of the repository in the registry host.
+// Multi platform images resolve to the digest of their index so that the image can be pulled on any platform
+func (c *Client) Digest(host, repository, tag string) (string, error) {
+	data, header, err := c.Get(host, repository, "manifests/"+tag, MediaTypeOCIIndex, MediaTypeDockerManifestList, MediaTypeOCIManifest, MediaTypeDockerManifest)
+	if err != nil {
+		return "", err
+	}
+	digest := header.Get(DigestHeader)
+	if digest == "" {
+		sum := sha256.Sum256(data)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return digest, nil
+}
+
+// ParseImage parses a container image such as 'ghcr.io/myorg/myapp:1.2.3' into the registry host, repository, tag
+// and any digest. Images without a registry host are from Docker Hub
+func ParseImage(image string) (host, repository, tag, digest string) {
+	name, digest, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		tag = name[i+1:]
+		name = name[:i]
+	}
+	first, rest, ok := strings.Cut(name, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		host = first
+		repository = rest
+	} else {
+		host = dockerHubHost
+		repository = name
+	}
+	if host == dockerHubHost && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return host, repository, tag, digest
+}
+
+// DigestCache a DigestResolver which caches the digests in a file so that repeated lookups do not access the registry
+type DigestCache struct {
+	Path     string
+	Resolver DigestResolver
+	lock     sync.Mutex
+	digests  v1alpha1.ImageDigests
+	modified bool
+}
+
+// LoadDigestCache loads the digest cache file if it exists using the resolver to lookup any missing digests
+func LoadDigestCache(path string, resolver DigestResolver) (*DigestCache, error) {
+	answer := &DigestCache{
+		Path:     path,
+		Resolver: resolver,
+	}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if exists {
+		err = yamls.LoadFile(path, &answer.digests)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load file %s", path)
+		}
+	}
+	if answer.digests.Spec.Digests == nil {
+		answer.digests.Spec.Digests = map[string]string{}
+	}
+	return answer, nil
+}
+
+// Digest returns the cached digest of the tag or looks it up via the resolver
+func (c *DigestCache) Digest(host, repository, tag string) (string, error) {
+	key := host + "/" + repository + ":" + tag
+	c.lock.Lock()
+	digest := c.digests.Spec.Digests[key]
+	c.lock.Unlock()
+	if digest != "" {
+		return digest, nil
+	}
+	if c.Resolver == nil {
+		return "", errors.Errorf("no digest cached for %s", key)
+	}
+	digest, err := c.Resolver.Digest(host, repository, tag)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find digest of %s", key)
+	}
+	c.lock.Lock()
+	c.digests.Spec.Digests[key] = digest
+	c.modified = true
+	c.lock.Unlock()
+	return digest, nil
+}
+
+// Save saves the cache file if any digests have been added
+func (c *DigestCache) Save() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.modified {
+		return nil
+	}
+	c.digests.APIVersion = v1alpha1.APIVersion
+	c.digests.Kind = v1alpha1.KindImageDigests
+	err := os.MkdirAll(filepath.Dir(c.Path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make directory %s", filepath.Dir(c.Path))
+	}
+	err = yamls.SaveFile(&c.digests, c.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save %s", c.Path)
+	}
+	c.modified = false
+	return nil
+}
@@ -0,0 +1,57 @@
+package ociregistry_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/fakeregistry"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImage(t *testing.T) {
+	testCases := map[string][]string{
+		"ubuntu":                               {"docker.io", "library/ubuntu", "", ""},
+		"ubuntu:22.04":                         {"docker.io", "library/ubuntu", "22.04", ""},
+		"bitnami/redis:7.2":                    {"docker.io", "bitnami/redis", "7.2", ""},
+		"ghcr.io/jenkins-x/jx-boot:3.10.150":   {"ghcr.io", "jenkins-x/jx-boot", "3.10.150", ""},
+		"localhost:5000/myapp":                 {"localhost:5000", "myapp", "", ""},
+		"localhost/myorg/myapp:1.0.0@sha256:1": {"localhost", "myorg/myapp", "1.0.0", "sha256:1"},
+	}
+	for image, expected := range testCases {
+		host, repository, tag, digest := ociregistry.ParseImage(image)
+		assert.Equal(t, expected, []string{host, repository, tag, digest}, "parsed image %s", image)
+	}
+}
+
+func TestDigestCache(t *testing.T) {
+	registry := fakeregistry.New()
+	defer registry.Close()
+	registry.Credentials = ociregistry.Credentials{Username: "myuser", Password: "mypassword"}
+	expected := registry.AddImage("myorg/myapp", "1.2.3")
+
+	client := registry.Client()
+	client.SetCredentials(registry.Host, registry.Credentials)
+
+	path := filepath.Join(t.TempDir(), "image-digests.yaml")
+	cache, err := ociregistry.LoadDigestCache(path, client)
+	require.NoError(t, err, "failed to load digest cache")
+
+	digest, err := cache.Digest(registry.Host, "myorg/myapp", "1.2.3")
+	require.NoError(t, err, "failed to get digest")
+	assert.Equal(t, expected, digest, "digest")
+
+	_, err = cache.Digest(registry.Host, "myorg/myapp", "9.9.9")
+	require.Error(t, err, "should fail for a missing tag")
+
+	err = cache.Save()
+	require.NoError(t, err, "failed to save digest cache")
+
+	// lets verify the saved cache is used without a registry
+	cache, err = ociregistry.LoadDigestCache(path, nil)
+	require.NoError(t, err, "failed to load digest cache")
+	digest, err = cache.Digest(registry.Host, "myorg/myapp", "1.2.3")
+	require.NoError(t, err, "failed to get cached digest")
+	assert.Equal(t, expected, digest, "cached digest")
+}
@@ -0,0 +1,103 @@
+package ociregistry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRegistryConfigFile the default location of the docker config file with the registry credentials in a pipeline
+	DefaultRegistryConfigFile = "/tekton/creds-secrets/tekton-container-registry-auth/.dockerconfigjson"
+
+	dockerHubHost    = "docker.io"
+	dockerHubAuthKey = "https://index.docker.io/v1/"
+)
+
+// Credentials the credentials used to access a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// IsEmpty returns true if there are no credentials
+func (c *Credentials) IsEmpty() bool {
+	return c.Username == "" && c.Password == ""
+}
+
+// DockerConfig the registry credentials of a docker config file
+type DockerConfig struct {
+	Auths map[string]DockerAuth `json:"auths,omitempty"`
+}
+
+// DockerAuth the credentials for a registry in a docker config file
+type DockerAuth struct {
+	Auth     string `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// LoadDockerConfig loads the docker config file returning an empty config if the file does not exist
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	answer := &DockerConfig{}
+	if path == "" {
+		return answer, nil
+	}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return answer, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	err = json.Unmarshal(data, answer)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse docker config file %s", path)
+	}
+	return answer, nil
+}
+
+// Credentials returns the credentials for the registry host
+func (c *DockerConfig) Credentials(host string) (Credentials, error) {
+	if c == nil {
+		return Credentials{}, nil
+	}
+	for k, auth := range c.Auths {
+		if authHost(k) != host && !(host == dockerHubHost && k == dockerHubAuthKey) {
+			continue
+		}
+		if auth.Username != "" || auth.Password != "" {
+			return Credentials{Username: auth.Username, Password: auth.Password}, nil
+		}
+		if auth.Auth == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return Credentials{}, errors.Wrapf(err, "failed to decode auth for registry %s", k)
+		}
+		user, password, _ := strings.Cut(string(data), ":")
+		return Credentials{Username: user, Password: password}, nil
+	}
+	return Credentials{}, nil
+}
+
+// authHost returns the host of a key in the auths of a docker config which may be a URL
+func authHost(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	host, _, _ := strings.Cut(key, "/")
+	return host
+}
+
+func base64Encode(text string) string {
+	return base64.StdEncoding.EncodeToString([]byte(text))
+}
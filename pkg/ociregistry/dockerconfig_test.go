@@ -0,0 +1,42 @@
+package ociregistry_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/ociregistry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerConfigCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "ZG9ja2VyOmh1Yg=="},
+    "ghcr.io": {"username": "ghuser", "password": "ghtoken"},
+    "https://gcr.io/myproject": {"auth": "X2pzb25fa2V5OnNlY3JldA=="}
+  }
+}`), 0o600)
+	require.NoError(t, err, "failed to save %s", path)
+
+	config, err := ociregistry.LoadDockerConfig(path)
+	require.NoError(t, err, "failed to load %s", path)
+
+	testCases := map[string]ociregistry.Credentials{
+		"docker.io": {Username: "docker", Password: "hub"},
+		"ghcr.io":   {Username: "ghuser", Password: "ghtoken"},
+		"gcr.io":    {Username: "_json_key", Password: "secret"},
+		"quay.io":   {},
+	}
+	for host, expected := range testCases {
+		actual, err := config.Credentials(host)
+		require.NoError(t, err, "failed to get credentials for %s", host)
+		assert.Equal(t, expected, actual, "credentials for %s", host)
+	}
+
+	config, err = ociregistry.LoadDockerConfig(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	require.NoError(t, err, "should ignore missing files")
+	assert.Empty(t, config.Auths)
+}
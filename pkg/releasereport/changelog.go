@@ -0,0 +1,187 @@
+package releasereport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/httphelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxChangelogLength the maximum length of a changelog stored in the report
+	MaxChangelogLength = 4000
+
+	defaultGitHubRawURL = "https://raw.githubusercontent.com"
+	defaultGitHubAPIURL = "https://api.github.com"
+)
+
+// ChangelogFetcher fetches the changes between two versions of a chart from one of its sources
+type ChangelogFetcher interface {
+	// FetchChangelog returns the changes between the versions or an empty string if they cannot be found
+	FetchChangelog(source, fromVersion, toVersion string) (string, error)
+}
+
+// GitHubChangelogFetcher fetches the CHANGELOG.md or the release notes of a GitHub repository
+type GitHubChangelogFetcher struct {
+	Client *http.Client
+	RawURL string
+	APIURL string
+}
+
+// Changelog returns the changes between the versions from the first source which has them
+func Changelog(fetcher ChangelogFetcher, sources []string, fromVersion, toVersion string) string {
+	if fetcher == nil || fromVersion == "" || toVersion == "" || fromVersion == toVersion {
+		return ""
+	}
+	for _, source := range sources {
+		text, err := fetcher.FetchChangelog(source, fromVersion, toVersion)
+		if err != nil {
+			log.Logger().Debugf("failed to fetch changelog from %s: %s", source, err.Error())
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text != "" {
+			if len(text) > MaxChangelogLength {
+				text = text[:MaxChangelogLength] + "\n..."
+			}
+			return text
+		}
+	}
+	return ""
+}
+
+// FetchChangelog fetches the changes from the CHANGELOG.md file or the release notes of the version
+func (f *GitHubChangelogFetcher) FetchChangelog(source, fromVersion, toVersion string) (string, error) {
+	gitInfo, err := giturl.ParseGitURL(source)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse git URL %s", source)
+	}
+	if gitInfo.Host != giturl.GitHubHost {
+		return "", nil
+	}
+	rawURL := f.RawURL
+	if rawURL == "" {
+		rawURL = defaultGitHubRawURL
+	}
+	apiURL := f.APIURL
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+
+	data, err := f.get(stringhelpers.UrlJoin(rawURL, gitInfo.Organisation, gitInfo.Name, "HEAD", "CHANGELOG.md"))
+	if err != nil {
+		return "", err
+	}
+	text := ExtractChangelog(string(data), fromVersion, toVersion)
+	if text != "" {
+		return text, nil
+	}
+
+	for _, tag := range []string{"v" + strings.TrimPrefix(toVersion, "v"), strings.TrimPrefix(toVersion, "v")} {
+		data, err = f.get(stringhelpers.UrlJoin(apiURL, "repos", gitInfo.Organisation, gitInfo.Name, "releases", "tags", tag))
+		if err != nil {
+			return "", err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		release := struct {
+			Body string `json:"body"`
+		}{}
+		err = json.Unmarshal(data, &release)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse release %s", tag)
+		}
+		if release.Body != "" {
+			return release.Body, nil
+		}
+	}
+	return "", nil
+}
+
+// get returns the body of the URL or nil if it is not found
+func (f *GitHubChangelogFetcher) get(u string) ([]byte, error) {
+	client := f.Client
+	if client == nil {
+		client = httphelpers.GetClient()
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to GET %s", u)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode >= 300 {
+		return nil, errors.Errorf("failed to GET %s with status %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read response from %s", u)
+	}
+	return data, nil
+}
+
+// ExtractChangelog returns the sections of the changelog markdown from the heading of the toVersion up to the heading
+// of the fromVersion. If the fromVersion heading cannot be found only the section of the toVersion is returned
+func ExtractChangelog(text, fromVersion, toVersion string) string {
+	toRegex := versionHeadingRegex(toVersion)
+	fromRegex := versionHeadingRegex(fromVersion)
+	lines := strings.Split(text, "\n")
+
+	start := -1
+	level := 0
+	for i, line := range lines {
+		if headingLevel(line) > 0 && toRegex.MatchString(line) {
+			start = i
+			level = headingLevel(line)
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+
+	end := len(lines)
+	sectionEnd := -1
+	for i := start + 1; i < len(lines); i++ {
+		l := headingLevel(lines[i])
+		if l == 0 {
+			continue
+		}
+		if fromRegex.MatchString(lines[i]) {
+			end = i
+			sectionEnd = -1
+			break
+		}
+		if sectionEnd < 0 && l <= level {
+			sectionEnd = i
+		}
+	}
+	if end == len(lines) && sectionEnd > 0 {
+		end = sectionEnd
+	}
+	return strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+}
+
+func headingLevel(line string) int {
+	trimmed := strings.TrimLeft(line, "#")
+	n := len(line) - len(trimmed)
+	if n == 0 || !strings.HasPrefix(trimmed, " ") {
+		return 0
+	}
+	return n
+}
+
+func versionHeadingRegex(version string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(^|[^0-9.])v?%s($|[^0-9.])`, regexp.QuoteMeta(strings.TrimPrefix(version, "v"))))
+}
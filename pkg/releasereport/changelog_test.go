@@ -0,0 +1,109 @@
+package releasereport_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChangelog = `# Changelog
+
+## [1.3.0] - 2021-03-01
+### Added
+- something new
+
+## 1.2.1
+- fixed a bug
+
+## v1.2.0
+- the old release
+`
+
+func TestExtractChangelog(t *testing.T) {
+	testCases := []struct {
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			from:     "1.2.0",
+			to:       "1.3.0",
+			expected: "## [1.3.0] - 2021-03-01\n### Added\n- something new\n\n## 1.2.1\n- fixed a bug",
+		},
+		{
+			from:     "v1.2.1",
+			to:       "v1.3.0",
+			expected: "## [1.3.0] - 2021-03-01\n### Added\n- something new",
+		},
+		{
+			from:     "0.1.0",
+			to:       "1.2.1",
+			expected: "## 1.2.1\n- fixed a bug",
+		},
+		{
+			from:     "1.2.0",
+			to:       "1.3",
+			expected: "",
+		},
+	}
+	for _, tc := range testCases {
+		got := releasereport.ExtractChangelog(testChangelog, tc.from, tc.to)
+		assert.Equal(t, tc.expected, got, "changelog from %s to %s", tc.from, tc.to)
+	}
+}
+
+func TestGitHubChangelogFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/raw/myorg/withchangelog/HEAD/CHANGELOG.md":
+			_, _ = w.Write([]byte(testChangelog))
+		case "/api/repos/myorg/withreleases/releases/tags/v2.0.0":
+			_, _ = w.Write([]byte(`{"tag_name": "v2.0.0", "body": "the release notes"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := &releasereport.GitHubChangelogFetcher{
+		Client: server.Client(),
+		RawURL: server.URL + "/raw",
+		APIURL: server.URL + "/api",
+	}
+
+	got := releasereport.Changelog(fetcher, []string{"https://github.com/myorg/withchangelog"}, "1.2.1", "1.3.0")
+	assert.Equal(t, "## [1.3.0] - 2021-03-01\n### Added\n- something new", got)
+
+	got = releasereport.Changelog(fetcher, []string{"https://gitlab.com/myorg/other", "https://github.com/myorg/withreleases"}, "1.0.0", "2.0.0")
+	assert.Equal(t, "the release notes", got)
+
+	got = releasereport.Changelog(fetcher, []string{"https://github.com/myorg/missing"}, "1.0.0", "2.0.0")
+	assert.Empty(t, got)
+}
+
+func TestAddHistory(t *testing.T) {
+	ri := &releasereport.ReleaseInfo{}
+	ri.Version = "1.0.0"
+	assert.True(t, ri.AddHistory(releasereport.ReleaseVersion{Version: "1.0.0", Chart: "jx3/foo"}, 2))
+	assert.False(t, ri.AddHistory(releasereport.ReleaseVersion{Version: "1.0.0", Chart: "jx3/foo"}, 2))
+	assert.Empty(t, ri.PreviousVersion())
+
+	ri.Version = "1.1.0"
+	assert.True(t, ri.AddHistory(releasereport.ReleaseVersion{Version: "1.1.0", Chart: "jx3/foo"}, 2))
+	assert.Equal(t, "1.0.0", ri.PreviousVersion())
+
+	ri.Version = "1.2.0"
+	assert.True(t, ri.AddHistory(releasereport.ReleaseVersion{Version: "1.2.0", Chart: "jx3/foo"}, 2))
+	require.Len(t, ri.History, 2)
+	assert.Equal(t, "1.2.0", ri.History[0].Version)
+	assert.Equal(t, "1.1.0", ri.PreviousVersion())
+
+	// changing the chart without changing the version is not an upgrade
+	assert.True(t, ri.AddHistory(releasereport.ReleaseVersion{Version: "1.2.0", Chart: "jx3/bar"}, 3))
+	require.Len(t, ri.History, 3)
+	assert.Empty(t, ri.PreviousVersion())
+}
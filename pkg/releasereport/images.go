@@ -0,0 +1,52 @@
+package releasereport
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/lintpolicy"
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/resourcediff"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// DiscoverImages returns the sorted unique container images used by the resources in the given directory
+func DiscoverImages(dir string) ([]string, error) {
+	images := map[string]bool{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || (!strings.HasSuffix(path, ".yaml") && !strings.HasSuffix(path, ".yml")) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load file %s", path)
+		}
+		resources, err := resourcediff.ParseResources(path, data)
+		if err != nil {
+			log.Logger().Debugf("could not parse YAML file %s", path)
+			return nil
+		}
+		for _, r := range resources {
+			for _, c := range lintpolicy.Containers(r.Object) {
+				if image, _ := c["image"].(string); image != "" {
+					images[image] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find images in dir %s", dir)
+	}
+	var answer []string
+	for image := range images {
+		answer = append(answer, image)
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
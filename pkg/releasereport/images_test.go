@@ -0,0 +1,57 @@
+package releasereport_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/releasereport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverImages(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(tmpDir, "resources.yaml"), []byte(`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: web
+        image: nginx:1.25
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: ghcr.io/myorg/backup:1.0.0
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+  - name: debug
+    image: nginx:1.25
+`), 0o600)
+	require.NoError(t, err, "failed to save resources")
+
+	images, err := releasereport.DiscoverImages(tmpDir)
+	require.NoError(t, err, "failed to discover images")
+	assert.Equal(t, []string{"busybox:1.36", "ghcr.io/myorg/backup:1.0.0", "nginx:1.25"}, images)
+}
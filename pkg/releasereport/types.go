@@ -34,12 +34,35 @@ type ReleaseInfo struct {
 	ApplicationURL string `json:"applicationUrl,omitempty"`
 	// LogsURL the URL to browse the application logs if available
 	LogsURL string `json:"logsUrl,omitempty"`
+	// MetricsURL the URL to browse the application metrics dashboard if available
+	MetricsURL string `json:"metricsUrl,omitempty"`
 
 	// ResourcesPath the relative path to the kubernetes resources
 	ResourcesPath string `json:"resourcePath,omitempty"`
 
 	// Ingresses the ingress URLs
 	Ingresses []IngressInfo `json:"ingresses,omitempty"`
+
+	// Images the container images used by the resources of the release
+	Images []string `json:"images,omitempty"`
+
+	// History the bounded history of the versions of the release with the most recent first
+	History []ReleaseVersion `json:"history,omitempty"`
+
+	// Changelog the changes between the previous version and the current version if available
+	Changelog string `json:"changelog,omitempty"`
+}
+
+// ReleaseVersion a version of a release which was deployed
+type ReleaseVersion struct {
+	// Version the chart version
+	Version string `json:"version,omitempty"`
+	// Chart the fully qualified chart name
+	Chart string `json:"chart,omitempty"`
+	// Deployed when the version was first deployed
+	Deployed *metav1.Time `json:"deployed,omitempty"`
+	// GitSHA the git commit SHA of the cluster repository when the version was first reported
+	GitSHA string `json:"gitSha,omitempty"`
 }
 
 // IngressInfo details of an ingress
@@ -48,6 +71,32 @@ type IngressInfo struct {
 	URL  string `json:"url,omitempty"`
 }
 
+// PreviousVersion returns the version deployed immediately before the current version or an empty string if the
+// most recent change to the release did not change its version
+func (i *ReleaseInfo) PreviousVersion() string {
+	if len(i.History) < 2 || i.History[0].Version != i.Version {
+		return ""
+	}
+	previous := i.History[1].Version
+	if previous == i.Version {
+		return ""
+	}
+	return previous
+}
+
+// AddHistory adds the version to the front of the history if it is not already the most recent entry
+// keeping at most maxSize entries
+func (i *ReleaseInfo) AddHistory(v ReleaseVersion, maxSize int) bool {
+	if len(i.History) > 0 && i.History[0].Version == v.Version && i.History[0].Chart == v.Chart {
+		return false
+	}
+	i.History = append([]ReleaseVersion{v}, i.History...)
+	if maxSize > 0 && len(i.History) > maxSize {
+		i.History = i.History[:maxSize]
+	}
+	return true
+}
+
 func (i *ReleaseInfo) String() string {
 	answer := fmt.Sprintf("%s version: %s", i.Name, i.Version)
 	if i.Home != "" {
@@ -0,0 +1,317 @@
+package resourcediff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Status the kind of change to a resource
+type Status string
+
+const (
+	// StatusAdded the resource was added
+	StatusAdded Status = "added"
+	// StatusRemoved the resource was removed
+	StatusRemoved Status = "removed"
+	// StatusChanged the resource was modified
+	StatusChanged Status = "changed"
+	// StatusRenamed the resource was moved to a different file without being modified
+	StatusRenamed Status = "renamed"
+)
+
+// Key identifies a kubernetes resource independent of the file it is stored in
+type Key struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// String returns a textual representation of the key
+func (k Key) String() string {
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s/%s %s", k.APIVersion, k.Kind, k.Name)
+	}
+	return fmt.Sprintf("%s/%s %s/%s", k.APIVersion, k.Kind, k.Namespace, k.Name)
+}
+
+// Resource a resource loaded from a file in git
+type Resource struct {
+	Key    Key
+	Path   string
+	Object map[string]interface{}
+}
+
+// FieldChange a change to a single field of a resource
+type FieldChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// ResourceChange a change to a resource
+type ResourceChange struct {
+	Key      Key           `json:"resource"`
+	Status   Status        `json:"status"`
+	FromPath string        `json:"fromPath,omitempty"`
+	ToPath   string        `json:"toPath,omitempty"`
+	Fields   []FieldChange `json:"fields,omitempty"`
+}
+
+// Renamed returns true if the resource has moved to a different file
+func (c *ResourceChange) Renamed() bool {
+	return c.FromPath != "" && c.ToPath != "" && c.FromPath != c.ToPath
+}
+
+// LoadResourcesAtRef loads the resources in the paths of the git repository at the given revision
+func LoadResourcesAtRef(repo *git.Repository, ref string, paths []string) (map[Key]*Resource, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve git revision %s", ref)
+	}
+	commit, err := object.GetCommit(repo.Storer, *hash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get commit %s", hash.String())
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tree of commit %s", hash.String())
+	}
+
+	answer := map[Key]*Resource{}
+	for _, dir := range paths {
+		dirTree, err := tree.Tree(dir)
+		if err != nil {
+			if errors.Is(err, object.ErrDirectoryNotFound) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to find directory %s at %s", dir, ref)
+		}
+		err = dirTree.Files().ForEach(func(f *object.File) error {
+			name := path.Join(dir, f.Name)
+			if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+				return nil
+			}
+			reader, err := f.Reader()
+			if err != nil {
+				return errors.Wrapf(err, "failed to read file %s", name)
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read file %s", name)
+			}
+			resources, err := ParseResources(name, data)
+			if err != nil {
+				return err
+			}
+			for _, r := range resources {
+				answer[r.Key] = r
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load resources in %s at %s", dir, ref)
+		}
+	}
+	return answer, nil
+}
+
+// ParseResources parses the YAML documents in the given file
+func ParseResources(fileName string, data []byte) ([]*Resource, error) {
+	var answer []*Resource
+	for _, doc := range bytes.Split(data, []byte("\n---")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj := map[string]interface{}{}
+		err := yaml.Unmarshal(doc, &obj)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse YAML file %s", fileName)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		key := Key{
+			APIVersion: getString(obj, "apiVersion"),
+			Kind:       getString(obj, "kind"),
+		}
+		metadata, ok := obj["metadata"].(map[string]interface{})
+		if ok {
+			key.Namespace = getString(metadata, "namespace")
+			key.Name = getString(metadata, "name")
+		}
+		if key.Kind == "" || key.Name == "" {
+			continue
+		}
+		answer = append(answer, &Resource{
+			Key:    key,
+			Path:   fileName,
+			Object: obj,
+		})
+	}
+	return answer, nil
+}
+
+// Diff compares the resources returning the changes sorted by resource key
+func Diff(from, to map[Key]*Resource, ignoreAnnotations []string) []ResourceChange {
+	var answer []ResourceChange
+	for k, f := range from {
+		t := to[k]
+		if t == nil {
+			answer = append(answer, ResourceChange{
+				Key:      k,
+				Status:   StatusRemoved,
+				FromPath: f.Path,
+			})
+			continue
+		}
+		fields := diffValues("", stripAnnotations(f.Object, ignoreAnnotations), stripAnnotations(t.Object, ignoreAnnotations))
+		status := StatusChanged
+		if len(fields) == 0 {
+			if f.Path == t.Path {
+				continue
+			}
+			status = StatusRenamed
+		}
+		answer = append(answer, ResourceChange{
+			Key:      k,
+			Status:   status,
+			FromPath: f.Path,
+			ToPath:   t.Path,
+			Fields:   fields,
+		})
+	}
+	for k, t := range to {
+		if from[k] == nil {
+			answer = append(answer, ResourceChange{
+				Key:    k,
+				Status: StatusAdded,
+				ToPath: t.Path,
+			})
+		}
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		return answer[i].Key.String() < answer[j].Key.String()
+	})
+	return answer
+}
+
+// stripAnnotations returns a copy of the object without the given annotations on the resource or pod template
+func stripAnnotations(obj map[string]interface{}, annotations []string) map[string]interface{} {
+	if len(annotations) == 0 {
+		return obj
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	answer := map[string]interface{}{}
+	err = json.Unmarshal(data, &answer)
+	if err != nil {
+		return obj
+	}
+	removeAnnotations(answer, annotations, "metadata")
+	removeAnnotations(answer, annotations, "spec", "template", "metadata")
+	return answer
+}
+
+func removeAnnotations(obj map[string]interface{}, annotations []string, fields ...string) {
+	m := obj
+	for _, f := range fields {
+		child, ok := m[f].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = child
+	}
+	values, ok := m["annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, a := range annotations {
+		delete(values, a)
+	}
+	if len(values) == 0 {
+		delete(m, "annotations")
+	}
+}
+
+func diffValues(prefix string, from, to interface{}) []FieldChange {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		var keys []string
+		for k := range fromMap {
+			keys = append(keys, k)
+		}
+		for k := range toMap {
+			if _, ok := fromMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		var answer []FieldChange
+		for _, k := range keys {
+			answer = append(answer, diffValues(joinPath(prefix, k), fromMap[k], toMap[k])...)
+		}
+		return answer
+	}
+
+	fromSlice, fromIsSlice := from.([]interface{})
+	toSlice, toIsSlice := to.([]interface{})
+	if fromIsSlice && toIsSlice {
+		var answer []FieldChange
+		for i := 0; i < len(fromSlice) || i < len(toSlice); i++ {
+			var f, t interface{}
+			if i < len(fromSlice) {
+				f = fromSlice[i]
+			}
+			if i < len(toSlice) {
+				t = toSlice[i]
+			}
+			answer = append(answer, diffValues(fmt.Sprintf("%s[%d]", prefix, i), f, t)...)
+		}
+		return answer
+	}
+
+	if reflect.DeepEqual(from, to) {
+		return nil
+	}
+	return []FieldChange{
+		{
+			Path: prefix,
+			From: from,
+			To:   to,
+		},
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if strings.ContainsAny(key, "./") {
+		key = "[" + key + "]"
+		return prefix + key
+	}
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func getString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
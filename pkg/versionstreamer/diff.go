@@ -0,0 +1,102 @@
+package versionstreamer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ChangeAdded the entry was added to the version stream
+	ChangeAdded = "added"
+	// ChangeRemoved the entry was removed from the version stream
+	ChangeRemoved = "removed"
+	// ChangeUpdated the version of the entry was changed
+	ChangeUpdated = "updated"
+)
+
+// EntryChange a change to the version of an entry between two git refs of the version stream
+type EntryChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Change string `json:"change"`
+}
+
+// DiffEntries returns the entries whose versions changed between the two git refs of the git repository
+// which contains the version stream directory. The changes are sorted by kind and name
+func DiffEntries(gitter gitclient.Interface, dir, fromRef, toRef string) ([]*EntryChange, error) {
+	text, err := gitter.Command(dir, "diff", "--name-status", "--no-renames", "--relative", fromRef, toRef, "--", ".")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find the changed files between %s and %s in dir %s", fromRef, toRef, dir)
+	}
+
+	var answer []*EntryChange
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		status, path, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if !found {
+			continue
+		}
+		kindName, name, ok := EntryPath(path)
+		if !ok {
+			continue
+		}
+		c := &EntryChange{
+			Kind: kindName,
+			Name: name,
+		}
+		switch status {
+		case "A":
+			c.Change = ChangeAdded
+		case "D":
+			c.Change = ChangeRemoved
+		default:
+			c.Change = ChangeUpdated
+		}
+		if c.Change != ChangeAdded {
+			c.From, err = versionAtRef(gitter, dir, fromRef, path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if c.Change != ChangeRemoved {
+			c.To, err = versionAtRef(gitter, dir, toRef, path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if c.Change == ChangeUpdated && c.From == c.To {
+			continue
+		}
+		answer = append(answer, c)
+	}
+	SortEntryChanges(answer)
+	return answer, nil
+}
+
+// SortEntryChanges sorts the changes by kind then name
+func SortEntryChanges(changes []*EntryChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+// versionAtRef returns the version of the version stream file at the given git ref
+func versionAtRef(gitter gitclient.Interface, dir, ref, path string) (string, error) {
+	text, err := gitter.Command(dir, "show", ref+":./"+path)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get file %s at %s in dir %s", path, ref, dir)
+	}
+	sv, err := versionstream.LoadStableVersionFromData([]byte(text))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse file %s at %s", path, ref)
+	}
+	return sv.Version, nil
+}
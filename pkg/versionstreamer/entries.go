@@ -0,0 +1,166 @@
+package versionstreamer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/versionstream"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KindNameChart the name used on the command line for charts
+	KindNameChart = "chart"
+	// KindNameImage the name used on the command line for container images
+	KindNameImage = "image"
+	// KindNameGit the name used on the command line for git repositories
+	KindNameGit = "git"
+	// KindNamePackage the name used on the command line for packages
+	KindNamePackage = "package"
+
+	defaultsFileName = "defaults.yaml"
+)
+
+var (
+	// KindNames the names of the kinds of version stream entries used on the command line
+	KindNames = []string{KindNameChart, KindNameImage, KindNameGit, KindNamePackage}
+
+	kinds = map[string]versionstream.VersionKind{
+		KindNameChart:   versionstream.KindChart,
+		KindNameImage:   versionstream.KindDocker,
+		KindNameGit:     versionstream.KindGit,
+		KindNamePackage: versionstream.KindPackage,
+	}
+)
+
+// Entry an entry in the version stream
+type Entry struct {
+	Kind                   string            `json:"kind"`
+	Name                   string            `json:"name"`
+	Version                string            `json:"version,omitempty"`
+	ReplacementChart       string            `json:"replacementChart,omitempty"`
+	ReplacementChartPrefix string            `json:"replacementChartPrefix,omitempty"`
+	Namespace              string            `json:"namespace,omitempty"`
+	Labels                 map[string]string `json:"labels,omitempty"`
+	GitURL                 string            `json:"gitUrl,omitempty"`
+}
+
+// NewEntry creates a new entry from the stable version
+func NewEntry(kindName, name string, sv *versionstream.StableVersion) *Entry {
+	return &Entry{
+		Kind:                   kindName,
+		Name:                   name,
+		Version:                sv.Version,
+		ReplacementChart:       sv.ReplacementChart,
+		ReplacementChartPrefix: sv.ReplacementChartPrefix,
+		Namespace:              sv.Namespace,
+		Labels:                 sv.Labels,
+		GitURL:                 sv.GitURL,
+	}
+}
+
+// ToVersionKind converts the kind name used on the command line such as 'chart' or 'image' to the version stream kind
+func ToVersionKind(kindName string) (versionstream.VersionKind, bool) {
+	kind, ok := kinds[kindName]
+	return kind, ok
+}
+
+// EntryName returns the name of the version stream entry for the path relative to the directory of its kind such as
+// 'jenkins-x/lighthouse/defaults.yaml' or 'github.com/jenkins-x/jx3-pipeline-catalog.yml' or false if the path is not an entry
+func EntryName(kind versionstream.VersionKind, rel string) (string, bool) {
+	rel = filepath.ToSlash(rel)
+	name := ""
+	switch {
+	case strings.HasSuffix(rel, "/"+defaultsFileName):
+		name = strings.TrimSuffix(rel, "/"+defaultsFileName)
+	case strings.HasSuffix(rel, ".yml"):
+		name = strings.TrimSuffix(rel, ".yml")
+	default:
+		return "", false
+	}
+	// the chart repositories are not an entry
+	if kind == versionstream.KindChart && name == "repositories" {
+		return "", false
+	}
+	return name, name != ""
+}
+
+// EntryPath returns the path relative to the version stream directory and the name of the entry for the path or
+// false if it is not an entry
+func EntryPath(path string) (string, string, bool) {
+	kindDir, rel, found := strings.Cut(filepath.ToSlash(path), "/")
+	if !found {
+		return "", "", false
+	}
+	for _, kindName := range KindNames {
+		if string(kinds[kindName]) == kindDir {
+			name, ok := EntryName(kinds[kindName], rel)
+			return kindName, name, ok
+		}
+	}
+	return "", "", false
+}
+
+// LoadEntries loads the version stream entries of the given kind names in the directory sorted by kind and name
+func LoadEntries(dir string, kindNames ...string) ([]*Entry, error) {
+	if len(kindNames) == 0 {
+		kindNames = KindNames
+	}
+	var answer []*Entry
+	for _, kindName := range kindNames {
+		kind, ok := kinds[kindName]
+		if !ok {
+			return nil, errors.Errorf("unknown version stream kind %s", kindName)
+		}
+		kindDir := filepath.Join(dir, string(kind))
+		exists, err := files.DirExists(kindDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check if dir exists %s", kindDir)
+		}
+		if !exists {
+			continue
+		}
+		err = filepath.WalkDir(kindDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(kindDir, path)
+			if err != nil {
+				return err
+			}
+			name, ok := EntryName(kind, rel)
+			if !ok {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read file %s", path)
+			}
+			sv, err := versionstream.LoadStableVersionFromData(data)
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse file %s", path)
+			}
+			answer = append(answer, NewEntry(kindName, name, sv))
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to walk dir %s", kindDir)
+		}
+	}
+	SortEntries(answer)
+	return answer, nil
+}
+
+// SortEntries sorts the entries by kind then name
+func SortEntries(entries []*Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
@@ -18,3 +18,13 @@ func MatchRepositoryPrefix(prefixes *versionstream.RepositoryPrefixes, prefix st
 	}
 	return repoURL[0], nil
 }
+
+// IndexRepositoryPrefixes eagerly builds the lazily created lookup indexes of the prefixes so that they can then be
+// safely read from multiple goroutines
+func IndexRepositoryPrefixes(prefixes *versionstream.RepositoryPrefixes) *versionstream.RepositoryPrefixes {
+	if prefixes != nil {
+		prefixes.URLsForPrefix("")
+		prefixes.PrefixForURL("")
+	}
+	return prefixes
+}
@@ -0,0 +1,107 @@
+package versionstreamer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-gitops/pkg/apis/gitops/v1alpha1"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kyamls"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+var (
+	// HistoryFile the location of the version stream history file relative to the cluster git repository
+	HistoryFile = filepath.Join(".jx", "gitops", v1alpha1.VersionStreamHistoryFileName)
+)
+
+// LoadHistory loads the version stream history file if it exists
+func LoadHistory(path string) (*v1alpha1.VersionStreamHistory, error) {
+	answer := &v1alpha1.VersionStreamHistory{}
+	exists, err := files.FileExists(path)
+	if err != nil {
+		return answer, errors.Wrapf(err, "failed to check if file exists %s", path)
+	}
+	if !exists {
+		return answer, nil
+	}
+	err = yamls.LoadFile(path, answer)
+	if err != nil {
+		return answer, errors.Wrapf(err, "failed to load file %s", path)
+	}
+	return answer, nil
+}
+
+// SaveHistory saves the version stream history file
+func SaveHistory(history *v1alpha1.VersionStreamHistory, path string) error {
+	if history.APIVersion == "" {
+		history.APIVersion = v1alpha1.APIVersion
+	}
+	if history.Kind == "" {
+		history.Kind = v1alpha1.KindVersionStreamHistory
+	}
+	err := os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make directory %s", filepath.Dir(path))
+	}
+	err = yamls.SaveFile(history, path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save %s", path)
+	}
+	return nil
+}
+
+// KptfileUpstream returns the upstream git repository of the given Kptfile along with the
+// commit SHA of the last update from either the upstreamLock or the older upstream commit field
+func KptfileUpstream(node *yaml.RNode, path string) v1alpha1.VersionStreamHistoryEntry {
+	sha := kyamls.GetStringField(node, path, "upstreamLock", "git", "commit")
+	if sha == "" {
+		sha = kyamls.GetStringField(node, path, "upstream", "git", "commit")
+	}
+	return v1alpha1.VersionStreamHistoryEntry{
+		URL:       kyamls.GetStringField(node, path, "upstream", "git", "repo"),
+		Ref:       kyamls.GetStringField(node, path, "upstream", "git", "ref"),
+		Directory: kyamls.GetStringField(node, path, "upstream", "git", "directory"),
+		SHA:       sha,
+	}
+}
+
+// SetKptfileUpstream sets the upstream git repository, ref and directory of the given Kptfile
+func SetKptfileUpstream(node *yaml.RNode, path, repo, ref, directory string) error {
+	err := node.PipeE(yaml.LookupCreate(yaml.ScalarNode, "upstream", "git", "repo"), yaml.FieldSetter{StringValue: repo})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set the git source repository to %s for %s", repo, path)
+	}
+	err = node.PipeE(yaml.LookupCreate(yaml.ScalarNode, "upstream", "git", "ref"), yaml.FieldSetter{StringValue: ref})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set the git source ref to %s for %s", ref, path)
+	}
+	err = node.PipeE(yaml.LookupCreate(yaml.ScalarNode, "upstream", "git", "directory"), yaml.FieldSetter{StringValue: directory})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set the git directory to %s for %s", directory, path)
+	}
+	return nil
+}
+
+// ResolveSHA returns the commit SHA of the given ref of the remote git repository
+func ResolveSHA(gitter gitclient.Interface, dir, gitURL, ref string) (string, error) {
+	text, err := gitter.Command(dir, "ls-remote", gitURL, ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list the refs of %s", gitURL)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			return fields[0], nil
+		}
+	}
+	// lets assume the ref is already a commit SHA
+	if len(ref) == 40 {
+		return ref, nil
+	}
+	return "", errors.Errorf("could not find ref %s in %s", ref, gitURL)
+}